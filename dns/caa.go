@@ -0,0 +1,205 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// CAA property tags, as specified in RFC 8659 section 4
+const (
+	CAATagIssue     = "issue"
+	CAATagIssueWild = "issuewild"
+	CAATagIODEF     = "iodef"
+)
+
+// CAAResolver looks up CAA records, which is satisfied by *Resolver
+//
+// Returns an empty result if the name has no CAA records or does not exist.
+type CAAResolver interface {
+	LookupCAA(ctx context.Context, name domain.Name) ([]CAA, error)
+}
+
+// LookupCAA queries the CAA records of the domain name, including those of the target if the name is an alias
+//
+// Returns an empty result if the name has no CAA records or does not exist.
+func (r *Resolver) LookupCAA(ctx context.Context, name domain.Name) ([]CAA, error) {
+	records, err := r.Lookup(ctx, name, TypeCAA)
+	if errors.Is(err, ErrNameNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []CAA
+	for _, record := range records {
+		if caa, ok := record.Data.(CAA); ok {
+			result = append(result, caa)
+		}
+	}
+	return result, nil
+}
+
+// CAAIssuer is the value of an issue or issuewild property
+type CAAIssuer struct {
+	// Issuer is the domain name identifying the certificate authority, or the root domain if no certificate authority
+	// is authorized, i.e. the value is ";"
+	Issuer domain.Name
+	// Parameters are the parameters specific to the certificate authority, such as "accounturi"
+	Parameters map[string]string
+}
+
+// ParseCAAIssuer parses the value of an issue or issuewild property, as specified in RFC 8659 section 4.2
+//
+// Returns an error if the issuer domain name or any parameter is invalid.
+func ParseCAAIssuer(value string) (CAAIssuer, error) {
+	issuer, params, _ := strings.Cut(value, ";")
+
+	var result CAAIssuer
+	if issuer = strings.TrimSpace(issuer); len(issuer) > 0 {
+		var err error
+		if result.Issuer, err = domain.Parse(issuer); err != nil || strings.HasPrefix(issuer, "*.") {
+			return CAAIssuer{}, fmt.Errorf("invalid CAA issuer %s", issuer)
+		}
+	}
+
+	for _, p := range strings.Split(params, ";") {
+		if p = strings.TrimSpace(p); len(p) == 0 {
+			continue
+		}
+		tag, v, ok := strings.Cut(p, "=")
+		if !ok || len(strings.TrimSpace(tag)) == 0 {
+			return CAAIssuer{}, fmt.Errorf("invalid CAA parameter %s", p)
+		}
+		if result.Parameters == nil {
+			result.Parameters = map[string]string{}
+		}
+		result.Parameters[strings.TrimSpace(tag)] = strings.TrimSpace(v)
+	}
+	return result, nil
+}
+
+// CAAPolicy is the certificate issuance policy of a domain name, specified by the relevant CAA records
+type CAAPolicy struct {
+	// Name is the name the relevant CAA records are found at, which is the root domain if none are found
+	Name domain.Name
+	// Records are the relevant CAA records
+	Records []CAA
+	// Issue and IssueWild hold the issue and issuewild properties
+	Issue     []CAAIssuer
+	IssueWild []CAAIssuer
+	// IODEF holds the URLs incidents are reported to
+	IODEF []string
+	// Invalid indicates an issue or issuewild property is malformed, which doesn't authorize any certificate authority
+	Invalid bool
+	// UnknownCritical indicates a property with an unknown tag and the critical flag, which forbids issuance
+	UnknownCritical bool
+}
+
+// IsRestricted returns whether the policy restricts certificate issuance, i.e. there are relevant CAA records
+func (p CAAPolicy) IsRestricted() bool {
+	return len(p.Records) > 0
+}
+
+// CanIssue returns whether the certificate authority is allowed to issue a certificate for the name, or a wildcard
+// certificate if specified, as specified in RFC 8659 sections 4.2 and 4.3
+//
+// The certificate authority is identified by its issuer domain name, such as "letsencrypt.org".
+func (p CAAPolicy) CanIssue(ca string, wildcard bool) bool {
+	if !p.IsRestricted() {
+		return true
+	}
+	if p.UnknownCritical {
+		return false
+	}
+
+	issuers := p.Issue
+	if wildcard && len(p.IssueWild) > 0 {
+		issuers = p.IssueWild
+	} else if !p.hasTag(CAATagIssue) {
+		// issuance is not restricted without issue properties
+		return true
+	}
+
+	ca = strings.TrimSuffix(strings.ToLower(ca), ".")
+	for _, issuer := range issuers {
+		if name := issuer.Issuer.String(); len(name) > 0 && name == ca {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CAAPolicy) hasTag(tag string) bool {
+	for _, r := range p.Records {
+		if strings.EqualFold(r.Tag, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCAAPolicy returns the policy specified by the CAA records found at the name
+func NewCAAPolicy(name domain.Name, records []CAA) CAAPolicy {
+	p := CAAPolicy{Name: name, Records: records}
+	for _, r := range records {
+		switch strings.ToLower(r.Tag) {
+		case CAATagIssue, CAATagIssueWild:
+			issuer, err := ParseCAAIssuer(r.Value)
+			if err != nil {
+				// a malformed property is treated as authorizing no certificate authority
+				p.Invalid = true
+				issuer = CAAIssuer{}
+			}
+			if strings.EqualFold(r.Tag, CAATagIssue) {
+				p.Issue = append(p.Issue, issuer)
+			} else {
+				p.IssueWild = append(p.IssueWild, issuer)
+			}
+		case CAATagIODEF:
+			p.IODEF = append(p.IODEF, r.Value)
+		default:
+			p.UnknownCritical = p.UnknownCritical || r.IsCritical()
+		}
+	}
+	return p
+}
+
+// EvaluateCAA finds the relevant CAA records of the domain name, and returns the issuance policy they specify
+//
+// The relevant records are found by climbing the domain tree label by label from the name towards the TLD, as specified
+// in RFC 8659 section 3, and are the first non-empty set of CAA records, e.g. of www.example.co.uk, example.co.uk,
+// co.uk or uk. The policy is unrestricted if no records are found. Returns an error if a lookup fails.
+func EvaluateCAA(ctx context.Context, r CAAResolver, name domain.Name) (CAAPolicy, error) {
+	for n := name; len(n.String()) > 0; n = parentLabel(n) {
+		records, err := r.LookupCAA(ctx, n)
+		if err != nil {
+			return CAAPolicy{}, fmt.Errorf("failed to lookup CAA for %s: %w", n.String(), err)
+		}
+		if len(records) > 0 {
+			return NewCAAPolicy(n, records), nil
+		}
+	}
+	return CAAPolicy{Name: domain.RootDomain}, nil
+}
+
+// parentLabel returns the name without its first label, which for an effective TLD of several labels such as co.uk is
+// the TLD uk, rather than the root as returned by Parent
+func parentLabel(n domain.Name) domain.Name {
+	if !n.IsEffectiveTLD() {
+		return n.Parent()
+	}
+	// the labels of effective TLDs are host name labels, hence not escaped
+	_, parent, ok := strings.Cut(n.String(), ".")
+	if !ok {
+		return domain.RootDomain
+	}
+	if p, err := domain.Parse(parent); err == nil {
+		return p
+	}
+	return domain.RootDomain
+}
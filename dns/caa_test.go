@@ -0,0 +1,134 @@
+package dns_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/detectify/n5/dns"
+	"github.com/detectify/n5/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// testCAAResolver resolves CAA records from a map
+type testCAAResolver map[string][]dns.CAA
+
+func (r testCAAResolver) LookupCAA(_ context.Context, name domain.Name) ([]dns.CAA, error) {
+	if name.String() == "fail.example.com" {
+		return nil, errors.New("timeout")
+	}
+	return r[name.String()], nil
+}
+
+// recordingCAAResolver records the names looked up with the resolver
+type recordingCAAResolver struct {
+	resolver dns.CAAResolver
+	queried  *[]string
+}
+
+func (r recordingCAAResolver) LookupCAA(ctx context.Context, name domain.Name) ([]dns.CAA, error) {
+	*r.queried = append(*r.queried, name.String())
+	return r.resolver.LookupCAA(ctx, name)
+}
+
+func TestParseCAAIssuer_WithParameters_ShouldReturnIssuer(t *testing.T) {
+	issuer, err := dns.ParseCAAIssuer("letsencrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1; " +
+		"validationmethods=dns-01")
+	require.NoError(t, err)
+	require.Equal(t, "letsencrypt.org", issuer.Issuer.String())
+	require.Equal(t, "dns-01", issuer.Parameters["validationmethods"])
+
+	issuer, err = dns.ParseCAAIssuer(";")
+	require.NoError(t, err)
+	require.Equal(t, domain.RootDomain, issuer.Issuer)
+
+	_, err = dns.ParseCAAIssuer("letsencrypt.org; invalid")
+	require.Error(t, err)
+	_, err = dns.ParseCAAIssuer("*.letsencrypt.org")
+	require.Error(t, err)
+}
+
+func TestEvaluateCAA_WithParentRecords_ShouldClimbTree(t *testing.T) {
+	r := testCAAResolver{
+		"example.com": {
+			{Tag: "issue", Value: "letsencrypt.org"},
+			{Tag: "issuewild", Value: ";"},
+			{Tag: "iodef", Value: "mailto:security@example.com"},
+		},
+		"shop.example.com": {{Tag: "ISSUE", Value: "digicert.com; cansignhttpexchanges=yes"}},
+		"wild.example.com": {{Tag: "issuewild", Value: "sectigo.com"}},
+		"tbs.example.com":  {{Flags: 128, Tag: "tbs", Value: "unknown"}, {Tag: "issue", Value: "letsencrypt.org"}},
+		"bad.example.com":  {{Tag: "issue", Value: "%%%"}},
+	}
+
+	p, err := dns.EvaluateCAA(context.Background(), r, domain.MustParse("www.dev.example.com"))
+	require.NoError(t, err)
+	require.Equal(t, "example.com", p.Name.String())
+	require.True(t, p.IsRestricted())
+	require.Equal(t, []string{"mailto:security@example.com"}, p.IODEF)
+	require.True(t, p.CanIssue("letsencrypt.org", false))
+	require.True(t, p.CanIssue("LetsEncrypt.org.", false))
+	require.False(t, p.CanIssue("letsencrypt.org", true))
+	require.False(t, p.CanIssue("digicert.com", false))
+
+	p, err = dns.EvaluateCAA(context.Background(), r, domain.MustParse("shop.example.com"))
+	require.NoError(t, err)
+	require.True(t, p.CanIssue("digicert.com", false))
+	require.True(t, p.CanIssue("digicert.com", true))
+	require.False(t, p.CanIssue("letsencrypt.org", false))
+
+	p, err = dns.EvaluateCAA(context.Background(), r, domain.MustParse("wild.example.com"))
+	require.NoError(t, err)
+	require.True(t, p.CanIssue("letsencrypt.org", false))
+	require.True(t, p.CanIssue("sectigo.com", true))
+	require.False(t, p.CanIssue("letsencrypt.org", true))
+
+	p, err = dns.EvaluateCAA(context.Background(), r, domain.MustParse("tbs.example.com"))
+	require.NoError(t, err)
+	require.True(t, p.UnknownCritical)
+	require.False(t, p.CanIssue("letsencrypt.org", false))
+
+	p, err = dns.EvaluateCAA(context.Background(), r, domain.MustParse("bad.example.com"))
+	require.NoError(t, err)
+	require.True(t, p.Invalid)
+	require.False(t, p.CanIssue("letsencrypt.org", false))
+}
+
+func TestEvaluateCAA_WithoutRecords_ShouldBeUnrestricted(t *testing.T) {
+	p, err := dns.EvaluateCAA(context.Background(), testCAAResolver{}, domain.MustParse("www.example.co.uk"))
+	require.NoError(t, err)
+	require.False(t, p.IsRestricted())
+	require.Equal(t, domain.RootDomain, p.Name)
+	require.True(t, p.CanIssue("letsencrypt.org", true))
+
+	_, err = dns.EvaluateCAA(context.Background(), testCAAResolver{}, domain.MustParse("www.fail.example.com"))
+	require.Error(t, err)
+}
+
+func TestEvaluateCAA_WithTLDRecords_ShouldClimbToTLD(t *testing.T) {
+	var queried []string
+	r := recordingCAAResolver{resolver: testCAAResolver{"uk": {{Tag: "issue", Value: "letsencrypt.org"}}},
+		queried: &queried}
+
+	p, err := dns.EvaluateCAA(context.Background(), r, domain.MustParse("www.example.co.uk"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"www.example.co.uk", "example.co.uk", "co.uk", "uk"}, queried)
+	require.Equal(t, "uk", p.Name.String())
+	require.False(t, p.CanIssue("digicert.com", false))
+}
+
+func TestResolver_LookupCAA_WithRecords_ShouldReturnRecords(t *testing.T) {
+	r := newTestResolver(t)
+
+	records, err := r.LookupCAA(context.Background(), domain.MustParse("example.com"))
+	require.NoError(t, err)
+	require.Equal(t, []dns.CAA{{Tag: "issue", Value: "letsencrypt.org"}}, records)
+
+	records, err = r.LookupCAA(context.Background(), domain.MustParse("missing.example.com"))
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	p, err := dns.EvaluateCAA(context.Background(), r, domain.MustParse("www.example.com"))
+	require.NoError(t, err)
+	require.Equal(t, "example.com", p.Name.String())
+}
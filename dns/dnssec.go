@@ -0,0 +1,719 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/detectify/n5/domain"
+	mdns "github.com/miekg/dns"
+)
+
+// maxCNAMEChain limits the number of CNAME records followed to validate an answer
+const maxCNAMEChain = 8
+
+// maxNSEC3Iterations is the highest number of NSEC3 hash iterations which are computed, as more iterations make
+// validation costly, see RFC 9276 section 3.2
+const maxNSEC3Iterations = 100
+
+// rootTrustAnchors holds the DS records of the root zone key signing keys published by IANA
+//
+// Source: https://data.iana.org/root-anchors/root-anchors.xml
+var rootTrustAnchors = []DS{
+	{
+		// KSK-2017
+		KeyTag:     20326,
+		Algorithm:  8,
+		DigestType: 2,
+		Digest:     "e06d44b80b8f1d39a95c0b0d7c65d08458e880409bbc683457104237c7f8ec8d",
+	},
+	{
+		// KSK-2024
+		KeyTag:     38696,
+		Algorithm:  8,
+		DigestType: 2,
+		Digest:     "683d2d0acb8c9b712a1948b27f741219298d0a450d612c483af444a4c0fb2b16",
+	},
+}
+
+// RootTrustAnchors returns the DS records of the root zone key signing keys published by IANA
+func RootTrustAnchors() []DS {
+	return append([]DS(nil), rootTrustAnchors...)
+}
+
+// Security is the DNSSEC security status of an answer, as specified in RFC 4035 section 4.3
+type Security int
+
+// Security statuses
+const (
+	// SecurityIndeterminate means the security could not be determined, e.g. because a query failed
+	SecurityIndeterminate Security = iota
+	// SecuritySecure means the answer is signed and validated through a chain of trust from the trust anchor
+	SecuritySecure
+	// SecurityInsecure means the answer is in a zone proven to be unsigned, i.e. the chain of trust ends securely
+	SecurityInsecure
+	// SecurityBogus means the answer should be signed, but the validation failed
+	SecurityBogus
+)
+
+// String returns the name of the security status
+func (s Security) String() string {
+	switch s {
+	case SecuritySecure:
+		return "secure"
+	case SecurityInsecure:
+		return "insecure"
+	case SecurityBogus:
+		return "bogus"
+	default:
+		return "indeterminate"
+	}
+}
+
+// Validation holds the result of a DNSSEC validation
+type Validation struct {
+	Security Security
+	// Records holds the records of the answer, including CNAME records leading to them, without signatures
+	Records []Record
+	// NXDomain indicates the name is proven not to exist, when secure
+	NXDomain bool
+	// NoData indicates the name is proven to exist without records of the type, when secure
+	NoData bool
+	// Zone is the zone of the failing link when bogus or indeterminate, the unsigned zone when insecure, and the zone
+	// of the answer when secure
+	Zone domain.Name
+	// Err is the reason of a bogus or indeterminate status, or of an insecure status
+	Err error
+}
+
+// Validate queries the records of the type for the domain name and validates them with DNSSEC
+//
+// Validation builds the chain of trust from the trust anchors of the root zone down to the zone of the name, by
+// validating the DNSKEY records of each zone with the DS records of the parent zone, and the absence of DS records
+// with NSEC or NSEC3 proofs. A non-existent name or type is validated with NSEC or NSEC3 proof of non-existence.
+func (r *Resolver) Validate(ctx context.Context, name domain.Name, t Type) Validation {
+	v := validator{r: r, ctx: ctx, now: time.Now()}
+	return v.validate(mdns.CanonicalName(fqdn(name)), uint16(t), 0)
+}
+
+// validator holds the state of a validation
+type validator struct {
+	r   *Resolver
+	ctx context.Context
+	now time.Time
+}
+
+func (v *validator) validate(qname string, qtype uint16, depth int) Validation {
+	zone := "."
+	keys, result := v.zoneKeys(zone, v.trustAnchors())
+	if result != nil {
+		return *result
+	}
+
+	insecure := ""
+	var reason error
+	labels := mdns.SplitDomainName(qname)
+	for i := len(labels) - 1; i >= 0 && len(insecure) == 0; i-- {
+		child := mdns.Fqdn(strings.Join(labels[i:], "."))
+		resp, err := v.query(child, mdns.TypeDS)
+		if err != nil {
+			return indeterminate(child, err)
+		}
+
+		ds, sigs := rrset(resp.Answer, child, mdns.TypeDS)
+		if len(ds) > 0 && resp.Rcode == mdns.RcodeSuccess {
+			if _, err = verifyRRset(ds, sigs, zone, keys, v.now); err != nil {
+				return bogus(child, fmt.Errorf("DS records of %s: %w", child, err))
+			}
+			anchors := make([]*mdns.DS, 0, len(ds))
+			for _, rr := range ds {
+				anchors = append(anchors, rr.(*mdns.DS))
+			}
+			if keys, result = v.zoneKeys(child, anchors); result != nil {
+				return *result
+			}
+			zone = child
+			continue
+		}
+
+		if cname, _ := rrset(resp.Answer, child, mdns.TypeCNAME); len(cname) > 0 {
+			// a name with a CNAME record is not a delegation, which is validated with the final answer
+			continue
+		}
+		denials := verifiedDenials(resp.Ns, zone, keys, v.now)
+		if resp.Rcode == mdns.RcodeNameError {
+			if err = proveNXDomain(denials, child); err != nil {
+				return denials.failure(zone, nil, fmt.Errorf("non-existence of %s: %w", child, err))
+			}
+			return Validation{Security: SecuritySecure, NXDomain: true, Zone: mustParseName(zone)}
+		}
+		isDelegation, err := proveNoDS(denials, child)
+		if err != nil {
+			result := denials.failure(zone, nil, fmt.Errorf("absence of DS records of %s: %w", child, err))
+			if result.Security != SecurityInsecure {
+				return result
+			}
+			// the zone is treated as unsigned, like an unsigned delegation
+			insecure, reason = zone, result.Err
+		} else if isDelegation {
+			insecure, reason = child, fmt.Errorf("delegation to %s is not signed", child)
+		}
+	}
+
+	resp, err := v.query(qname, qtype)
+	if err != nil {
+		return indeterminate(qname, err)
+	}
+	if len(insecure) > 0 {
+		records, err := recordsFromRRs(resp.Answer)
+		if err != nil {
+			return indeterminate(qname, err)
+		}
+		return Validation{Security: SecurityInsecure, Records: records, Zone: mustParseName(insecure), Err: reason}
+	}
+	return v.validateAnswer(resp, qname, qtype, zone, keys, depth)
+}
+
+// validateAnswer validates the answer, following CNAME records, or the proof of non-existence
+func (v *validator) validateAnswer(resp *mdns.Msg, qname string, qtype uint16, zone string, keys []*mdns.DNSKEY,
+	depth int) Validation {
+	denials := verifiedDenials(resp.Ns, zone, keys, v.now)
+
+	var validated []mdns.RR
+	name := qname
+	for {
+		t := qtype
+		set, sigs := rrset(resp.Answer, name, t)
+		if len(set) == 0 && qtype != mdns.TypeCNAME {
+			t = mdns.TypeCNAME
+			set, sigs = rrset(resp.Answer, name, t)
+		}
+		if len(set) == 0 {
+			break
+		}
+
+		sig, err := verifyRRset(set, sigs, zone, keys, v.now)
+		if err != nil && name != qname {
+			// the target of a CNAME record may be signed by a child zone
+			return v.follow(validated, name, qtype, depth)
+		}
+		if err != nil {
+			return bogus(zone, fmt.Errorf("%s records of %s: %w", Type(t), name, err))
+		}
+		if int(sig.Labels) < mdns.CountLabel(name) {
+			// the answer is expanded from a wildcard, which requires proof the name does not exist
+			if err = proveWildcard(denials, name, int(sig.Labels)); err != nil {
+				return denials.failure(zone, append(validated, set...),
+					fmt.Errorf("wildcard expansion of %s: %w", name, err))
+			}
+		}
+		validated = append(validated, set...)
+		if t != mdns.TypeCNAME || qtype == mdns.TypeCNAME {
+			records, err := recordsFromRRs(validated)
+			if err != nil {
+				return indeterminate(zone, err)
+			}
+			return Validation{Security: SecuritySecure, Records: records, Zone: mustParseName(zone)}
+		}
+
+		name = mdns.CanonicalName(set[0].(*mdns.CNAME).Target)
+		if !mdns.IsSubDomain(zone, name) {
+			return v.follow(validated, name, qtype, depth)
+		}
+	}
+
+	if name != qname {
+		// the records of the CNAME target are not in the answer, which is validated separately
+		return v.follow(validated, name, qtype, depth)
+	}
+	if resp.Rcode == mdns.RcodeNameError {
+		if err := proveNXDomain(denials, name); err != nil {
+			return denials.failure(zone, nil, fmt.Errorf("non-existence of %s: %w", name, err))
+		}
+		return Validation{Security: SecuritySecure, NXDomain: true, Zone: mustParseName(zone)}
+	}
+	if err := proveNoData(denials, name, qtype); err != nil {
+		return denials.failure(zone, nil, fmt.Errorf("absence of %s records of %s: %w", Type(qtype), name, err))
+	}
+	return Validation{Security: SecuritySecure, NoData: true, Zone: mustParseName(zone)}
+}
+
+// follow validates the target of the validated CNAME records, returning the result with the CNAME records included
+func (v *validator) follow(validated []mdns.RR, target string, qtype uint16, depth int) Validation {
+	if depth >= maxCNAMEChain {
+		return bogus(target, fmt.Errorf("CNAME chain longer than %d", maxCNAMEChain))
+	}
+
+	records, err := recordsFromRRs(validated)
+	if err != nil {
+		return indeterminate(target, err)
+	}
+	result := v.validate(target, qtype, depth+1)
+	result.Records = append(records, result.Records...)
+	return result
+}
+
+// zoneKeys returns the keys of the zone, after validating them with the DS records
+func (v *validator) zoneKeys(zone string, anchors []*mdns.DS) ([]*mdns.DNSKEY, *Validation) {
+	supported := false
+	for _, ds := range anchors {
+		supported = supported || (isSupportedAlgorithm(ds.Algorithm) && isSupportedDigest(ds.DigestType))
+	}
+	if !supported {
+		// RFC 4035 section 5.2: a zone with unsupported algorithms only is treated as unsigned
+		result := Validation{Security: SecurityInsecure, Zone: mustParseName(zone),
+			Err: fmt.Errorf("DS records of %s use unsupported algorithms", zone)}
+		return nil, &result
+	}
+
+	resp, err := v.query(zone, mdns.TypeDNSKEY)
+	if err != nil {
+		result := indeterminate(zone, err)
+		return nil, &result
+	}
+	set, sigs := rrset(resp.Answer, zone, mdns.TypeDNSKEY)
+	if len(set) == 0 {
+		result := bogus(zone, fmt.Errorf("no DNSKEY records for %s", zone))
+		return nil, &result
+	}
+
+	var keys, entryPoints []*mdns.DNSKEY
+	for _, rr := range set {
+		key := rr.(*mdns.DNSKEY)
+		if key.Flags&mdns.ZONE == 0 {
+			continue
+		}
+		keys = append(keys, key)
+		for _, ds := range anchors {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+				continue
+			}
+			if digest := key.ToDS(ds.DigestType); digest != nil && strings.EqualFold(digest.Digest, ds.Digest) {
+				entryPoints = append(entryPoints, key)
+				break
+			}
+		}
+	}
+	if len(entryPoints) == 0 {
+		result := bogus(zone, fmt.Errorf("no DNSKEY of %s matches the DS records", zone))
+		return nil, &result
+	}
+	if _, err = verifyRRset(set, sigs, zone, entryPoints, v.now); err != nil {
+		result := bogus(zone, fmt.Errorf("DNSKEY records of %s: %w", zone, err))
+		return nil, &result
+	}
+	return keys, nil
+}
+
+func (v *validator) query(name string, t uint16) (*mdns.Msg, error) {
+	return v.r.exchange(v.ctx, name, t, true, true)
+}
+
+func (v *validator) trustAnchors() []*mdns.DS {
+	anchors := v.r.TrustAnchors
+	if len(anchors) == 0 {
+		anchors = rootTrustAnchors
+	}
+
+	result := make([]*mdns.DS, 0, len(anchors))
+	for _, a := range anchors {
+		result = append(result, &mdns.DS{
+			Hdr:        mdns.RR_Header{Name: ".", Rrtype: mdns.TypeDS, Class: mdns.ClassINET},
+			KeyTag:     a.KeyTag,
+			Algorithm:  a.Algorithm,
+			DigestType: a.DigestType,
+			Digest:     a.Digest,
+		})
+	}
+	return result
+}
+
+// verifyRRset verifies the signatures of the records, returning the first valid signature by a key of the zone
+func verifyRRset(set []mdns.RR, sigs []*mdns.RRSIG, zone string, keys []*mdns.DNSKEY, now time.Time) (*mdns.RRSIG,
+	error) {
+	if len(sigs) == 0 {
+		return nil, errors.New("no signatures")
+	}
+
+	err := fmt.Errorf("no signature by a key of %s", zone)
+	for _, sig := range sigs {
+		if !strings.EqualFold(sig.SignerName, zone) {
+			continue
+		}
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+			if !sig.ValidityPeriod(now) {
+				err = fmt.Errorf("signature by key %d is expired or not yet valid", sig.KeyTag)
+				continue
+			}
+			if verifyErr := sig.Verify(key, set); verifyErr != nil {
+				err = fmt.Errorf("signature by key %d is invalid: %w", sig.KeyTag, verifyErr)
+				continue
+			}
+			return sig, nil
+		}
+	}
+	return nil, err
+}
+
+// denialSet holds the NSEC and NSEC3 records proving the non-existence of names or types, and the hashes of the names
+// computed for the NSEC3 records, as the records of a zone share the same parameters
+type denialSet struct {
+	records []mdns.RR
+	hashes  map[nsec3Hash]string
+	// ignored indicates NSEC3 records were ignored for exceeding maxNSEC3Iterations
+	ignored bool
+}
+
+// nsec3Hash is the name and hash parameters of a hash computed for NSEC3 records
+type nsec3Hash struct {
+	name       string
+	algorithm  uint8
+	iterations uint16
+	salt       string
+}
+
+// verifiedDenials returns the NSEC and NSEC3 records of the section with a valid signature by a key of the zone,
+// ignoring NSEC3 records with more than maxNSEC3Iterations iterations
+func verifiedDenials(section []mdns.RR, zone string, keys []*mdns.DNSKEY, now time.Time) *denialSet {
+	denials := &denialSet{hashes: map[nsec3Hash]string{}}
+	for _, rr := range section {
+		t := rr.Header().Rrtype
+		if t != mdns.TypeNSEC && t != mdns.TypeNSEC3 {
+			continue
+		}
+		if d, ok := rr.(*mdns.NSEC3); ok && d.Iterations > maxNSEC3Iterations {
+			denials.ignored = true
+			continue
+		}
+		set, sigs := rrset(section, rr.Header().Name, t)
+		if _, err := verifyRRset(set, sigs, zone, keys, now); err == nil {
+			denials.records = append(denials.records, rr)
+		}
+	}
+	return denials
+}
+
+// failure returns the result of a failed proof, which is insecure rather than bogus if NSEC3 records were ignored, as
+// recommended by RFC 9276 section 3.2
+func (d *denialSet) failure(zone string, validated []mdns.RR, err error) Validation {
+	if !d.ignored {
+		return bogus(zone, err)
+	}
+	records, _ := recordsFromRRs(validated)
+	return Validation{Security: SecurityInsecure, Records: records, Zone: mustParseName(zone),
+		Err: fmt.Errorf("%w, NSEC3 records with more than %d iterations are ignored", err, maxNSEC3Iterations)}
+}
+
+// hash returns the hash of the name with the parameters of the NSEC3 record, computing it once per parameters
+func (d *denialSet) hash(nsec3 *mdns.NSEC3, name string) string {
+	key := nsec3Hash{name: strings.ToLower(name), algorithm: nsec3.Hash, iterations: nsec3.Iterations,
+		salt: strings.ToUpper(nsec3.Salt)}
+	h, ok := d.hashes[key]
+	if !ok {
+		h = mdns.HashName(key.name, key.algorithm, key.iterations, key.salt)
+		d.hashes[key] = h
+	}
+	return h
+}
+
+// match returns whether the NSEC3 record is the record of the name, like mdns.NSEC3.Match
+func (d *denialSet) match(nsec3 *mdns.NSEC3, name string) bool {
+	owner, zone, ok := splitNSEC3Owner(nsec3)
+	return ok && mdns.IsSubDomain(zone, strings.ToUpper(name)) && d.hash(nsec3, name) == owner
+}
+
+// cover returns whether the hash of the name is between the owner and next hash of the NSEC3 record, like
+// mdns.NSEC3.Cover
+func (d *denialSet) cover(nsec3 *mdns.NSEC3, name string) bool {
+	owner, zone, ok := splitNSEC3Owner(nsec3)
+	if !ok || !mdns.IsSubDomain(zone, strings.ToUpper(name)) {
+		return false
+	}
+	hash, next := d.hash(nsec3, name), strings.ToUpper(nsec3.NextDomain)
+	switch {
+	case owner == next:
+		// the only record of the zone covers all other hashes
+		return hash != owner
+	case owner > next:
+		// the last record of the zone wraps around to the first
+		return hash > owner || hash < next
+	default:
+		return hash > owner && hash < next
+	}
+}
+
+// splitNSEC3Owner returns the hash and zone of the owner name of the NSEC3 record in upper case
+func splitNSEC3Owner(nsec3 *mdns.NSEC3) (string, string, bool) {
+	owner := strings.ToUpper(nsec3.Hdr.Name)
+	i := strings.IndexByte(owner, '.')
+	if i <= 0 || i == len(owner)-1 {
+		return "", "", false
+	}
+	return owner[:i], owner[i+1:], true
+}
+
+// rrset returns the records of the type for the name in the section, and the signatures covering them
+func rrset(section []mdns.RR, name string, t uint16) ([]mdns.RR, []*mdns.RRSIG) {
+	var set []mdns.RR
+	var sigs []*mdns.RRSIG
+	for _, rr := range section {
+		if !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		if sig, ok := rr.(*mdns.RRSIG); ok {
+			if sig.TypeCovered == t {
+				sigs = append(sigs, sig)
+			}
+		} else if rr.Header().Rrtype == t {
+			set = append(set, rr)
+		}
+	}
+	return set, sigs
+}
+
+// proveNXDomain checks the proof of non-existence of the name, as specified in RFC 4035 section 5.4 for NSEC and
+// RFC 5155 section 8.4 for NSEC3
+func proveNXDomain(denials *denialSet, name string) error {
+	if nsec := coveringNSEC(denials, name); nsec != nil {
+		encloser := closestEncloserNSEC(nsec, name)
+		if coveringNSEC(denials, "*."+encloser) == nil {
+			return fmt.Errorf("no NSEC record proves the non-existence of wildcard *.%s", encloser)
+		}
+		return nil
+	}
+
+	encloser, _, ok := closestEncloserNSEC3(denials, name)
+	if !ok {
+		return errors.New("no NSEC or NSEC3 record proves the non-existence of the name")
+	}
+	if coveringNSEC3(denials, "*."+encloser) == nil {
+		return fmt.Errorf("no NSEC3 record proves the non-existence of wildcard *.%s", encloser)
+	}
+	return nil
+}
+
+// proveNoData checks the proof of the name having no records of the type, as specified in RFC 4035 section 5.4 for
+// NSEC and RFC 5155 sections 8.5 and 8.7 for NSEC3, including the proof for a wildcard matching the name
+func proveNoData(denials *denialSet, name string, t uint16) error {
+	if found, err := typeAbsent(denials, name, t); found {
+		return err
+	}
+
+	wildcard := ""
+	if nsec := coveringNSEC(denials, name); nsec != nil {
+		wildcard = "*." + closestEncloserNSEC(nsec, name)
+	} else if encloser, _, ok := closestEncloserNSEC3(denials, name); ok {
+		wildcard = "*." + encloser
+	}
+	if len(wildcard) > 0 {
+		if found, err := typeAbsent(denials, wildcard, t); found {
+			return err
+		}
+	}
+	return errors.New("no NSEC or NSEC3 record proves the absence of the type")
+}
+
+// typeAbsent returns whether an NSEC or NSEC3 record matches the name, and an error if it lists the type
+func typeAbsent(denials *denialSet, name string, t uint16) (bool, error) {
+	for _, rr := range denials.records {
+		var bitmap []uint16
+		switch d := rr.(type) {
+		case *mdns.NSEC:
+			if !strings.EqualFold(d.Hdr.Name, name) {
+				if nsecCovers(d, name) && mdns.IsSubDomain(name, d.NextDomain) {
+					// empty non-terminal name
+					return true, nil
+				}
+				continue
+			}
+			bitmap = d.TypeBitMap
+		case *mdns.NSEC3:
+			if !denials.match(d, name) {
+				continue
+			}
+			bitmap = d.TypeBitMap
+		}
+		if hasType(bitmap, t) || hasType(bitmap, mdns.TypeCNAME) {
+			return true, fmt.Errorf("NSEC or NSEC3 record lists type %s", Type(t))
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// proveNoDS checks the proof of the name having no DS records, as specified in RFC 4035 section 5.2 for NSEC and
+// RFC 5155 section 8.6 for NSEC3, returning whether the name is an unsigned delegation
+func proveNoDS(denials *denialSet, name string) (bool, error) {
+	for _, rr := range denials.records {
+		var bitmap []uint16
+		switch d := rr.(type) {
+		case *mdns.NSEC:
+			if !strings.EqualFold(d.Hdr.Name, name) {
+				if nsecCovers(d, name) {
+					// the name does not exist or is an empty non-terminal, neither of which is a delegation
+					return false, nil
+				}
+				continue
+			}
+			bitmap = d.TypeBitMap
+		case *mdns.NSEC3:
+			if !denials.match(d, name) {
+				continue
+			}
+			bitmap = d.TypeBitMap
+		}
+		if hasType(bitmap, mdns.TypeDS) {
+			return false, errors.New("NSEC or NSEC3 record lists type DS")
+		}
+		if hasType(bitmap, mdns.TypeSOA) {
+			return false, errors.New("NSEC or NSEC3 record is from the child zone")
+		}
+		return hasType(bitmap, mdns.TypeNS), nil
+	}
+
+	// NSEC3 opt-out may cover unsigned delegations, which are not listed themselves
+	if _, cover, ok := closestEncloserNSEC3(denials, name); ok {
+		return cover.Flags&0x1 != 0, nil
+	}
+	return false, errors.New("no NSEC or NSEC3 record proves the absence of DS records")
+}
+
+// proveWildcard checks the proof of the name not existing, which is answered by expanding the wildcard
+func proveWildcard(denials *denialSet, name string, labels int) error {
+	if coveringNSEC(denials, name) != nil {
+		return nil
+	}
+
+	parts := mdns.SplitDomainName(name)
+	nextCloser := mdns.Fqdn(strings.Join(parts[len(parts)-labels-1:], "."))
+	if coveringNSEC3(denials, nextCloser) != nil {
+		return nil
+	}
+	return errors.New("no NSEC or NSEC3 record proves the non-existence of the name")
+}
+
+// closestEncloserNSEC returns the closest encloser of the name, based on the NSEC record covering it
+func closestEncloserNSEC(nsec *mdns.NSEC, name string) string {
+	common := mdns.CompareDomainName(name, nsec.Hdr.Name)
+	if n := mdns.CompareDomainName(name, nsec.NextDomain); n > common {
+		common = n
+	}
+	parts := mdns.SplitDomainName(name)
+	return mdns.Fqdn(strings.Join(parts[len(parts)-common:], "."))
+}
+
+// closestEncloserNSEC3 returns the closest encloser of the name and the NSEC3 record covering the next closer name,
+// as specified in RFC 5155 section 8.3
+func closestEncloserNSEC3(denials *denialSet, name string) (string, *mdns.NSEC3, bool) {
+	parts := mdns.SplitDomainName(name)
+	for i := 1; i <= len(parts); i++ {
+		encloser := mdns.Fqdn(strings.Join(parts[i:], "."))
+		for _, rr := range denials.records {
+			if d, ok := rr.(*mdns.NSEC3); ok && denials.match(d, encloser) {
+				nextCloser := mdns.Fqdn(strings.Join(parts[i-1:], "."))
+				if cover := coveringNSEC3(denials, nextCloser); cover != nil {
+					return encloser, cover, true
+				}
+				return "", nil, false
+			}
+		}
+	}
+	return "", nil, false
+}
+
+func coveringNSEC(denials *denialSet, name string) *mdns.NSEC {
+	for _, rr := range denials.records {
+		if d, ok := rr.(*mdns.NSEC); ok && nsecCovers(d, name) {
+			return d
+		}
+	}
+	return nil
+}
+
+func coveringNSEC3(denials *denialSet, name string) *mdns.NSEC3 {
+	for _, rr := range denials.records {
+		if d, ok := rr.(*mdns.NSEC3); ok && !denials.match(d, name) && denials.cover(d, name) {
+			return d
+		}
+	}
+	return nil
+}
+
+// nsecCovers returns whether the name is between the owner and next name of the NSEC record in canonical order
+func nsecCovers(nsec *mdns.NSEC, name string) bool {
+	owner, next := nsec.Hdr.Name, nsec.NextDomain
+	if canonicalCompare(owner, next) < 0 {
+		return canonicalCompare(owner, name) < 0 && canonicalCompare(name, next) < 0
+	}
+	// the last NSEC record of the zone points back to the apex
+	return canonicalCompare(owner, name) < 0 || canonicalCompare(name, next) < 0
+}
+
+// canonicalCompare compares the names in the canonical order specified in RFC 4034 section 6.1
+func canonicalCompare(a, b string) int {
+	la := mdns.SplitDomainName(strings.ToLower(a))
+	lb := mdns.SplitDomainName(strings.ToLower(b))
+	for i := 1; i <= len(la) && i <= len(lb); i++ {
+		if c := strings.Compare(la[len(la)-i], lb[len(lb)-i]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+func hasType(bitmap []uint16, t uint16) bool {
+	for _, b := range bitmap {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isSupportedAlgorithm(algorithm uint8) bool {
+	switch algorithm {
+	case mdns.RSASHA1, mdns.RSASHA1NSEC3SHA1, mdns.RSASHA256, mdns.RSASHA512, mdns.ECDSAP256SHA256,
+		mdns.ECDSAP384SHA384, mdns.ED25519:
+		return true
+	default:
+		return false
+	}
+}
+
+func isSupportedDigest(digestType uint8) bool {
+	return digestType == mdns.SHA1 || digestType == mdns.SHA256 || digestType == mdns.SHA384
+}
+
+func recordsFromRRs(rrs []mdns.RR) ([]Record, error) {
+	var records []Record
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == mdns.TypeRRSIG {
+			continue
+		}
+		record, err := fromRR(rr)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func bogus(zone string, err error) Validation {
+	return Validation{Security: SecurityBogus, Zone: mustParseName(zone), Err: err}
+}
+
+func indeterminate(zone string, err error) Validation {
+	return Validation{Security: SecurityIndeterminate, Zone: mustParseName(zone), Err: err}
+}
+
+// mustParseName parses a name of the wire format implementation, returning the root domain if invalid
+func mustParseName(s string) domain.Name {
+	name, _ := parseWireName(s)
+	return name
+}
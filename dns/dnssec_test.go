@@ -0,0 +1,499 @@
+package dns_test
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/detectify/n5/dns"
+	"github.com/detectify/n5/domain"
+	mdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// testZone is a zone served by the test name server, signed on the fly
+type testZone struct {
+	apex       string
+	key        *mdns.DNSKEY
+	signer     crypto.Signer
+	nsec3      bool
+	iterations uint16
+	rrs        []mdns.RR
+}
+
+func newTestZone(t *testing.T, apex string, signed, nsec3 bool, rrs ...string) *testZone {
+	t.Helper()
+	z := &testZone{apex: apex, nsec3: nsec3}
+	ns, mbox := mdns.Fqdn("ns1."+strings.TrimSuffix(apex, ".")), mdns.Fqdn("hostmaster."+strings.TrimSuffix(apex, "."))
+	z.rrs = append(z.rrs, mustRR(t, apex+" 3600 IN SOA "+ns+" "+mbox+" 1 7200 1800 604800 300"))
+	for _, s := range rrs {
+		z.rrs = append(z.rrs, mustRR(t, s))
+	}
+	if signed {
+		z.key, z.signer = generateKey(t, apex)
+		z.rrs = append(z.rrs, z.key)
+	}
+	return z
+}
+
+func generateKey(t *testing.T, apex string) (*mdns.DNSKEY, crypto.Signer) {
+	t.Helper()
+	key := &mdns.DNSKEY{
+		Hdr:       mdns.RR_Header{Name: apex, Rrtype: mdns.TypeDNSKEY, Class: mdns.ClassINET, Ttl: 3600},
+		Flags:     mdns.ZONE | mdns.SEP,
+		Protocol:  3,
+		Algorithm: mdns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	require.NoError(t, err)
+	return key, priv.(crypto.Signer)
+}
+
+// delegate adds the delegation to the child zone, with the DS record of the key if any
+func (z *testZone) delegate(t *testing.T, child string, key *mdns.DNSKEY) {
+	z.rrs = append(z.rrs, mustRR(t, child+" 3600 IN NS ns1."+child))
+	if key != nil {
+		z.rrs = append(z.rrs, key.ToDS(mdns.SHA256))
+	}
+}
+
+func (z *testZone) lookup(name string, t uint16) []mdns.RR {
+	var set []mdns.RR
+	for _, rr := range z.rrs {
+		if strings.EqualFold(rr.Header().Name, name) && rr.Header().Rrtype == t {
+			set = append(set, mdns.Copy(rr))
+		}
+	}
+	return set
+}
+
+func (z *testZone) exists(name string) bool {
+	for _, rr := range z.rrs {
+		if strings.EqualFold(rr.Header().Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (z *testZone) sign(t *testing.T, set []mdns.RR) []mdns.RR {
+	if z.signer == nil || len(set) == 0 {
+		return set
+	}
+	now := time.Now()
+	sig := &mdns.RRSIG{
+		Hdr:        mdns.RR_Header{Ttl: set[0].Header().Ttl},
+		Algorithm:  z.key.Algorithm,
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.apex,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+	require.NoError(t, sig.Sign(z.signer, set))
+	return append(set, sig)
+}
+
+// names returns the owner names of the zone in canonical order
+func (z *testZone) names() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, rr := range z.rrs {
+		if name := strings.ToLower(rr.Header().Name); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return canonicalLess(names[i], names[j])
+	})
+	return names
+}
+
+func canonicalLess(a, b string) bool {
+	la, lb := mdns.SplitDomainName(a), mdns.SplitDomainName(b)
+	for i := 1; i <= len(la) && i <= len(lb); i++ {
+		if x, y := la[len(la)-i], lb[len(lb)-i]; x != y {
+			return x < y
+		}
+	}
+	return len(la) < len(lb)
+}
+
+func (z *testZone) bitmap(name string) []uint16 {
+	types := []uint16{mdns.TypeRRSIG}
+	if !z.nsec3 {
+		types = append(types, mdns.TypeNSEC)
+	}
+	for _, rr := range z.rrs {
+		if strings.EqualFold(rr.Header().Name, name) {
+			types = append(types, rr.Header().Rrtype)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// denials returns the signed NSEC or NSEC3 records matching or covering the names
+func (z *testZone) denials(t *testing.T, names ...string) []mdns.RR {
+	if z.signer == nil {
+		return nil
+	}
+
+	var records []mdns.RR
+	seen := map[string]bool{}
+	for _, name := range names {
+		var rr mdns.RR
+		if z.nsec3 {
+			rr = z.nsec3Record(name)
+		} else {
+			rr = z.nsecRecord(name)
+		}
+		if !seen[rr.Header().Name] {
+			seen[rr.Header().Name] = true
+			records = append(records, z.sign(t, []mdns.RR{rr})...)
+		}
+	}
+	return records
+}
+
+func (z *testZone) nsecRecord(name string) mdns.RR {
+	names := z.names()
+	i := sort.Search(len(names), func(i int) bool { return !canonicalLess(names[i], name) })
+	if i == len(names) || names[i] != name {
+		i--
+	}
+	return &mdns.NSEC{
+		Hdr:        mdns.RR_Header{Name: names[i], Rrtype: mdns.TypeNSEC, Class: mdns.ClassINET, Ttl: 300},
+		NextDomain: names[(i+1)%len(names)],
+		TypeBitMap: z.bitmap(names[i]),
+	}
+}
+
+func (z *testZone) nsec3Record(name string) mdns.RR {
+	const salt = "AABBCCDD"
+	hashes := map[string]string{}
+	var sorted []string
+	for _, n := range z.names() {
+		h := mdns.HashName(n, mdns.SHA1, z.iterations, salt)
+		hashes[h] = n
+		sorted = append(sorted, h)
+	}
+	sort.Strings(sorted)
+
+	hash := mdns.HashName(name, mdns.SHA1, z.iterations, salt)
+	i := sort.SearchStrings(sorted, hash)
+	if i == len(sorted) || sorted[i] != hash {
+		i = (i + len(sorted) - 1) % len(sorted)
+	}
+	return &mdns.NSEC3{
+		Hdr:        mdns.RR_Header{Name: sorted[i] + "." + z.apex, Rrtype: mdns.TypeNSEC3, Class: mdns.ClassINET, Ttl: 300},
+		Hash:       mdns.SHA1,
+		Iterations: z.iterations,
+		SaltLength: 4,
+		Salt:       salt,
+		HashLength: 20,
+		NextDomain: sorted[(i+1)%len(sorted)],
+		TypeBitMap: z.bitmap(hashes[sorted[i]]),
+	}
+}
+
+// answer responds to the query like an authoritative name server of the zone
+func (z *testZone) answer(t *testing.T, m *mdns.Msg, qname string, qtype uint16) {
+	if z.exists(qname) {
+		if set := z.lookup(qname, qtype); len(set) > 0 {
+			m.Answer = append(m.Answer, z.sign(t, set)...)
+			return
+		}
+		if set := z.lookup(qname, mdns.TypeCNAME); len(set) > 0 {
+			m.Answer = append(m.Answer, z.sign(t, set)...)
+			target := set[0].(*mdns.CNAME).Target
+			if mdns.IsSubDomain(z.apex, target) {
+				z.answer(t, m, target, qtype)
+			}
+			return
+		}
+		m.Ns = append(m.Ns, z.denials(t, qname)...)
+		return
+	}
+
+	labels := mdns.SplitDomainName(qname)
+	i := 1
+	for ; i < len(labels) && !z.exists(mdns.Fqdn(strings.Join(labels[i:], "."))); i++ {
+	}
+	encloser := mdns.Fqdn(strings.Join(labels[i:], "."))
+	nextCloser := mdns.Fqdn(strings.Join(labels[i-1:], "."))
+	wildcard := "*." + encloser
+
+	if z.exists(wildcard) {
+		set := z.lookup(wildcard, qtype)
+		if len(set) == 0 {
+			m.Ns = append(m.Ns, z.denials(t, qname, wildcard)...)
+			return
+		}
+		for _, rr := range z.sign(t, set) {
+			rr.Header().Name = qname
+			m.Answer = append(m.Answer, rr)
+		}
+		m.Ns = append(m.Ns, z.denials(t, nextCloser)...)
+		return
+	}
+
+	m.Rcode = mdns.RcodeNameError
+	if z.nsec3 {
+		m.Ns = append(m.Ns, z.denials(t, encloser, nextCloser, wildcard)...)
+	} else {
+		m.Ns = append(m.Ns, z.denials(t, qname, wildcard)...)
+	}
+}
+
+// startDNSSECServer starts an in-process name server serving the signed zones over UDP, returning its address and
+// the DS record of the root zone key
+func startDNSSECServer(t *testing.T) (string, dns.DS) {
+	t.Helper()
+
+	root := newTestZone(t, ".", true, false, ". 3600 IN NS ns1.")
+	com := newTestZone(t, "com.", true, false, "com. 3600 IN NS ns1.com.")
+	example := newTestZone(t, "example.com.", true, false,
+		"example.com. 3600 IN NS ns1.example.com.",
+		"example.com. 3600 IN CAA 0 issue \"letsencrypt.org\"",
+		"www.example.com. 3600 IN A 192.0.2.1",
+		"alias.example.com. 3600 IN CNAME www.example.com.",
+		"wild.example.com. 3600 IN TXT \"wildcards below\"",
+		"*.wild.example.com. 3600 IN A 192.0.2.2",
+		"tampered.example.com. 3600 IN A 192.0.2.3",
+		"unsigned.example.com. 3600 IN CNAME www.insecure.com.",
+	)
+	nsec3 := newTestZone(t, "nsec3.com.", true, true,
+		"nsec3.com. 3600 IN NS ns1.nsec3.com.",
+		"www.nsec3.com. 3600 IN A 192.0.2.4",
+	)
+	costly := newTestZone(t, "costly.com.", true, true,
+		"costly.com. 3600 IN NS ns1.costly.com.",
+		"www.costly.com. 3600 IN A 192.0.2.7",
+	)
+	costly.iterations = 500
+	bogus := newTestZone(t, "bogus.com.", true, false,
+		"bogus.com. 3600 IN NS ns1.bogus.com.",
+		"www.bogus.com. 3600 IN A 192.0.2.5",
+	)
+	insecure := newTestZone(t, "insecure.com.", false, false, "www.insecure.com. 3600 IN A 192.0.2.6")
+
+	root.delegate(t, "com.", com.key)
+	com.delegate(t, "example.com.", example.key)
+	com.delegate(t, "nsec3.com.", nsec3.key)
+	com.delegate(t, "costly.com.", costly.key)
+	otherKey, _ := generateKey(t, "bogus.com.")
+	com.delegate(t, "bogus.com.", otherKey)
+	com.delegate(t, "insecure.com.", nil)
+	zones := []*testZone{root, com, example, nsec3, costly, bogus, insecure}
+
+	handler := mdns.HandlerFunc(func(w mdns.ResponseWriter, r *mdns.Msg) {
+		q := r.Question[0]
+		qname := strings.ToLower(q.Name)
+
+		var zone *testZone
+		for _, z := range zones {
+			if !mdns.IsSubDomain(z.apex, qname) || (q.Qtype == mdns.TypeDS && z.apex == qname) {
+				continue
+			}
+			if zone == nil || mdns.CountLabel(z.apex) > mdns.CountLabel(zone.apex) {
+				zone = z
+			}
+		}
+
+		m := new(mdns.Msg)
+		m.SetReply(r)
+		zone.answer(t, m, qname, q.Qtype)
+		if qname == "tampered.example.com." && len(m.Answer) > 0 {
+			m.Answer[0].(*mdns.A).A = net.ParseIP("198.51.100.1")
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &mdns.Server{PacketConn: conn, Handler: handler}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	ds := root.key.ToDS(mdns.SHA256)
+	return conn.LocalAddr().String(), dns.DS{KeyTag: ds.KeyTag, Algorithm: ds.Algorithm, DigestType: ds.DigestType,
+		Digest: ds.Digest}
+}
+
+func newTestResolver(t *testing.T) *dns.Resolver {
+	server, anchor := startDNSSECServer(t)
+	return &dns.Resolver{Server: server, DNSSEC: true, TrustAnchors: []dns.DS{anchor}}
+}
+
+func TestResolver_Validate_WithSignedAnswer_ShouldBeSecure(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("www.example.com"), dns.TypeA)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.Equal(t, "example.com", v.Zone.String())
+	require.Len(t, v.Records, 1)
+	require.Equal(t, "192.0.2.1", v.Records[0].Data.(dns.A).Addr.String())
+}
+
+func TestResolver_Validate_WithCNAME_ShouldBeSecure(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("alias.example.com"), dns.TypeA)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.Len(t, v.Records, 2)
+	require.Equal(t, dns.TypeCNAME, v.Records[0].Type())
+	require.Equal(t, dns.TypeA, v.Records[1].Type())
+}
+
+func TestResolver_Validate_WithWildcardAnswer_ShouldBeSecure(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("host.wild.example.com"), dns.TypeA)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.Len(t, v.Records, 1)
+	require.Equal(t, "host.wild.example.com", v.Records[0].Name.String())
+}
+
+func TestResolver_Validate_WithNonExistentName_ShouldBeSecureNXDomain(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("missing.example.com"), dns.TypeA)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.True(t, v.NXDomain)
+	require.Empty(t, v.Records)
+}
+
+func TestResolver_Validate_WithMissingType_ShouldBeSecureNoData(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("www.example.com"), dns.TypeAAAA)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.True(t, v.NoData)
+	require.False(t, v.NXDomain)
+}
+
+func TestResolver_Validate_WithNSEC3_ShouldProveNonExistence(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("missing.nsec3.com"), dns.TypeA)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.True(t, v.NXDomain)
+
+	v = r.Validate(context.Background(), domain.MustParse("www.nsec3.com"), dns.TypeTXT)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.True(t, v.NoData)
+
+	v = r.Validate(context.Background(), domain.MustParse("www.nsec3.com"), dns.TypeA)
+	require.NoError(t, v.Err)
+	require.Equal(t, dns.SecuritySecure, v.Security)
+	require.Len(t, v.Records, 1)
+}
+
+func TestResolver_Validate_WithTooManyNSEC3Iterations_ShouldBeInsecure(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("missing.costly.com"), dns.TypeA)
+	require.Error(t, v.Err)
+	require.Equal(t, dns.SecurityInsecure, v.Security)
+	require.Equal(t, "costly.com", v.Zone.String())
+	require.False(t, v.NXDomain)
+
+	v = r.Validate(context.Background(), domain.MustParse("www.costly.com"), dns.TypeA)
+	require.Error(t, v.Err)
+	require.Equal(t, dns.SecurityInsecure, v.Security)
+	require.Len(t, v.Records, 1)
+}
+
+func TestResolver_Validate_WithUnsignedDelegation_ShouldBeInsecure(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("www.insecure.com"), dns.TypeA)
+	require.Equal(t, dns.SecurityInsecure, v.Security)
+	require.Equal(t, "insecure.com", v.Zone.String())
+	require.Len(t, v.Records, 1)
+
+	v = r.Validate(context.Background(), domain.MustParse("unsigned.example.com"), dns.TypeA)
+	require.Equal(t, dns.SecurityInsecure, v.Security)
+	require.Len(t, v.Records, 2)
+}
+
+func TestResolver_Validate_WithMismatchingDS_ShouldBeBogus(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("www.bogus.com"), dns.TypeA)
+	require.Error(t, v.Err)
+	require.Equal(t, dns.SecurityBogus, v.Security)
+	require.Equal(t, "bogus.com", v.Zone.String())
+	require.Empty(t, v.Records)
+}
+
+func TestResolver_Validate_WithTamperedAnswer_ShouldBeBogus(t *testing.T) {
+	r := newTestResolver(t)
+
+	v := r.Validate(context.Background(), domain.MustParse("tampered.example.com"), dns.TypeA)
+	require.Error(t, v.Err)
+	require.Equal(t, dns.SecurityBogus, v.Security)
+	require.Equal(t, "example.com", v.Zone.String())
+}
+
+func TestResolver_Validate_WithWrongTrustAnchor_ShouldBeBogus(t *testing.T) {
+	r := newTestResolver(t)
+	r.TrustAnchors = dns.RootTrustAnchors()
+
+	v := r.Validate(context.Background(), domain.MustParse("www.example.com"), dns.TypeA)
+	require.Error(t, v.Err)
+	require.Equal(t, dns.SecurityBogus, v.Security)
+	require.Equal(t, domain.RootDomain, v.Zone)
+}
+
+func TestResolver_Validate_WithUnreachableServer_ShouldBeIndeterminate(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := conn.LocalAddr().String()
+	require.NoError(t, conn.Close())
+
+	r := &dns.Resolver{Server: server, Timeout: 200 * time.Millisecond}
+	v := r.Validate(context.Background(), domain.MustParse("www.example.com"), dns.TypeA)
+	require.Error(t, v.Err)
+	require.Equal(t, dns.SecurityIndeterminate, v.Security)
+}
+
+func TestResolver_Lookup_WithDNSSEC_ShouldReturnSignatures(t *testing.T) {
+	r := newTestResolver(t)
+
+	records, err := r.Lookup(context.Background(), domain.MustParse("www.example.com"), dns.TypeA)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, dns.TypeRRSIG, records[1].Type())
+	require.Equal(t, dns.TypeA, records[1].Data.(dns.RRSIG).TypeCovered)
+
+	_, err = r.Lookup(context.Background(), domain.MustParse("missing.example.com"), dns.TypeA)
+	require.ErrorIs(t, err, dns.ErrNameNotFound)
+}
+
+func TestRootTrustAnchors_WhenModified_ShouldNotChange(t *testing.T) {
+	anchors := dns.RootTrustAnchors()
+	require.NotEmpty(t, anchors)
+	anchors[0].KeyTag = 1
+
+	require.NotEqual(t, uint16(1), dns.RootTrustAnchors()[0].KeyTag)
+}
+
+func TestSecurity_String(t *testing.T) {
+	require.Equal(t, "secure", dns.SecuritySecure.String())
+	require.Equal(t, "insecure", dns.SecurityInsecure.String())
+	require.Equal(t, "bogus", dns.SecurityBogus.String())
+	require.Equal(t, "indeterminate", dns.SecurityIndeterminate.String())
+}
@@ -0,0 +1,2 @@
+// Package dns provides helper functions for DNS records and zones
+package dns
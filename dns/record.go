@@ -0,0 +1,522 @@
+package dns
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/detectify/n5/domain"
+)
+
+// Type is a DNS resource record type
+type Type uint16
+
+// Resource record types
+const (
+	TypeA          Type = 1
+	TypeNS         Type = 2
+	TypeCNAME      Type = 5
+	TypeSOA        Type = 6
+	TypePTR        Type = 12
+	TypeHINFO      Type = 13
+	TypeMX         Type = 15
+	TypeTXT        Type = 16
+	TypeAAAA       Type = 28
+	TypeSRV        Type = 33
+	TypeNAPTR      Type = 35
+	TypeDNAME      Type = 39
+	TypeOPT        Type = 41
+	TypeDS         Type = 43
+	TypeSSHFP      Type = 44
+	TypeRRSIG      Type = 46
+	TypeNSEC       Type = 47
+	TypeDNSKEY     Type = 48
+	TypeNSEC3      Type = 50
+	TypeNSEC3PARAM Type = 51
+	TypeTLSA       Type = 52
+	TypeCDS        Type = 59
+	TypeCDNSKEY    Type = 60
+	TypeSVCB       Type = 64
+	TypeHTTPS      Type = 65
+	TypeSPF        Type = 99
+	TypeTSIG       Type = 250
+	TypeIXFR       Type = 251
+	TypeAXFR       Type = 252
+	TypeANY        Type = 255
+	TypeCAA        Type = 257
+)
+
+var typeNames = map[Type]string{
+	TypeA:          "A",
+	TypeNS:         "NS",
+	TypeCNAME:      "CNAME",
+	TypeSOA:        "SOA",
+	TypePTR:        "PTR",
+	TypeHINFO:      "HINFO",
+	TypeMX:         "MX",
+	TypeTXT:        "TXT",
+	TypeAAAA:       "AAAA",
+	TypeSRV:        "SRV",
+	TypeNAPTR:      "NAPTR",
+	TypeDNAME:      "DNAME",
+	TypeOPT:        "OPT",
+	TypeDS:         "DS",
+	TypeSSHFP:      "SSHFP",
+	TypeRRSIG:      "RRSIG",
+	TypeNSEC:       "NSEC",
+	TypeDNSKEY:     "DNSKEY",
+	TypeNSEC3:      "NSEC3",
+	TypeNSEC3PARAM: "NSEC3PARAM",
+	TypeTLSA:       "TLSA",
+	TypeCDS:        "CDS",
+	TypeCDNSKEY:    "CDNSKEY",
+	TypeSVCB:       "SVCB",
+	TypeHTTPS:      "HTTPS",
+	TypeSPF:        "SPF",
+	TypeTSIG:       "TSIG",
+	TypeIXFR:       "IXFR",
+	TypeAXFR:       "AXFR",
+	TypeANY:        "ANY",
+	TypeCAA:        "CAA",
+}
+
+// String returns the mnemonic of the type, or the generic "TYPEnnn" format for types without mnemonic
+func (t Type) String() string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// ParseType parses a type mnemonic or the generic "TYPEnnn" format
+func ParseType(s string) (Type, error) {
+	upper := strings.ToUpper(s)
+	for t, name := range typeNames {
+		if name == upper {
+			return t, nil
+		}
+	}
+	if strings.HasPrefix(upper, "TYPE") {
+		v, err := strconv.ParseUint(upper[4:], 10, 16)
+		if err == nil {
+			return Type(v), nil
+		}
+	}
+	return 0, fmt.Errorf("%s is not a record type", s)
+}
+
+// Class is a DNS resource record class
+type Class uint16
+
+// Resource record classes
+const (
+	ClassIN   Class = 1
+	ClassCH   Class = 3
+	ClassHS   Class = 4
+	ClassNONE Class = 254
+	ClassANY  Class = 255
+)
+
+var classNames = map[Class]string{
+	ClassIN:   "IN",
+	ClassCH:   "CH",
+	ClassHS:   "HS",
+	ClassNONE: "NONE",
+	ClassANY:  "ANY",
+}
+
+// String returns the mnemonic of the class, or the generic "CLASSnnn" format for classes without mnemonic
+func (c Class) String() string {
+	if s, ok := classNames[c]; ok {
+		return s
+	}
+	return "CLASS" + strconv.Itoa(int(c))
+}
+
+// ParseClass parses a class mnemonic or the generic "CLASSnnn" format
+func ParseClass(s string) (Class, error) {
+	upper := strings.ToUpper(s)
+	for c, name := range classNames {
+		if name == upper {
+			return c, nil
+		}
+	}
+	if upper == "CS" {
+		// obsolete CSNET class
+		return 2, nil
+	}
+	if strings.HasPrefix(upper, "CLASS") {
+		v, err := strconv.ParseUint(upper[5:], 10, 16)
+		if err == nil {
+			return Class(v), nil
+		}
+	}
+	return 0, fmt.Errorf("%s is not a record class", s)
+}
+
+// Record holds a DNS resource record
+type Record struct {
+	// Name is the owner name of the record
+	Name domain.Name
+	// Wildcard indicates that the owner name is the wildcard ("*.") of Name
+	Wildcard bool
+	TTL      uint32
+	Class    Class
+	Data     RData
+}
+
+// Type returns the type of the record
+func (r Record) Type() Type {
+	if r.Data == nil {
+		return 0
+	}
+	return r.Data.Type()
+}
+
+// Owner returns the owner name in text format, including the wildcard label if any
+func (r Record) Owner() string {
+	if r.Wildcard {
+		return "*." + strings.TrimPrefix(fqdn(r.Name), ".")
+	}
+	return fqdn(r.Name)
+}
+
+// String returns the record in master file format
+func (r Record) String() string {
+	data := ""
+	if r.Data != nil {
+		data = r.Data.String()
+	}
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s", r.Owner(), r.TTL, r.Class, r.Type(), data)
+}
+
+// RData is the type specific data of a resource record
+type RData interface {
+	// Type returns the record type of the data
+	Type() Type
+	// String returns the data in master file format
+	String() string
+}
+
+// A holds the data of an A record
+type A struct {
+	Addr netip.Addr
+}
+
+// Type returns TypeA
+func (A) Type() Type { return TypeA }
+
+// String returns the data in master file format
+func (d A) String() string { return d.Addr.String() }
+
+// AAAA holds the data of an AAAA record
+type AAAA struct {
+	Addr netip.Addr
+}
+
+// Type returns TypeAAAA
+func (AAAA) Type() Type { return TypeAAAA }
+
+// String returns the data in master file format
+func (d AAAA) String() string { return d.Addr.String() }
+
+// NS holds the data of an NS record
+type NS struct {
+	Host domain.Name
+}
+
+// Type returns TypeNS
+func (NS) Type() Type { return TypeNS }
+
+// String returns the data in master file format
+func (d NS) String() string { return fqdn(d.Host) }
+
+// CNAME holds the data of a CNAME record
+type CNAME struct {
+	Target domain.Name
+}
+
+// Type returns TypeCNAME
+func (CNAME) Type() Type { return TypeCNAME }
+
+// String returns the data in master file format
+func (d CNAME) String() string { return fqdn(d.Target) }
+
+// DNAME holds the data of a DNAME record
+type DNAME struct {
+	Target domain.Name
+}
+
+// Type returns TypeDNAME
+func (DNAME) Type() Type { return TypeDNAME }
+
+// String returns the data in master file format
+func (d DNAME) String() string { return fqdn(d.Target) }
+
+// PTR holds the data of a PTR record
+type PTR struct {
+	Target domain.Name
+}
+
+// Type returns TypePTR
+func (PTR) Type() Type { return TypePTR }
+
+// String returns the data in master file format
+func (d PTR) String() string { return fqdn(d.Target) }
+
+// MX holds the data of an MX record
+type MX struct {
+	Preference uint16
+	Host       domain.Name
+}
+
+// Type returns TypeMX
+func (MX) Type() Type { return TypeMX }
+
+// String returns the data in master file format
+func (d MX) String() string { return fmt.Sprintf("%d %s", d.Preference, fqdn(d.Host)) }
+
+// TXT holds the data of a TXT record
+type TXT struct {
+	Text []string
+}
+
+// Type returns TypeTXT
+func (TXT) Type() Type { return TypeTXT }
+
+// String returns the data in master file format
+func (d TXT) String() string { return quoteStrings(d.Text) }
+
+// Joined returns the character strings of the record concatenated, as used by e.g. SPF and DKIM
+func (d TXT) Joined() string { return strings.Join(d.Text, "") }
+
+// SOA holds the data of an SOA record
+type SOA struct {
+	MName   domain.Name
+	RName   domain.Name
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+	Minimum uint32
+}
+
+// Type returns TypeSOA
+func (SOA) Type() Type { return TypeSOA }
+
+// String returns the data in master file format
+func (d SOA) String() string {
+	return fmt.Sprintf("%s %s %d %d %d %d %d", fqdn(d.MName), fqdn(d.RName), d.Serial, d.Refresh, d.Retry,
+		d.Expire, d.Minimum)
+}
+
+// SRV holds the data of an SRV record
+type SRV struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   domain.Name
+}
+
+// Type returns TypeSRV
+func (SRV) Type() Type { return TypeSRV }
+
+// String returns the data in master file format
+func (d SRV) String() string {
+	return fmt.Sprintf("%d %d %d %s", d.Priority, d.Weight, d.Port, fqdn(d.Target))
+}
+
+// CAA holds the data of a CAA record
+type CAA struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+// Type returns TypeCAA
+func (CAA) Type() Type { return TypeCAA }
+
+// String returns the data in master file format
+func (d CAA) String() string {
+	return fmt.Sprintf("%d %s %s", d.Flags, d.Tag, quoteStrings([]string{d.Value}))
+}
+
+// IsCritical returns whether the issuer critical flag is set
+func (d CAA) IsCritical() bool { return d.Flags&0x80 != 0 }
+
+// DS holds the data of a DS (delegation signer) record
+type DS struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	// Digest is the hex encoded digest of the DNSKEY record
+	Digest string
+}
+
+// Type returns TypeDS
+func (DS) Type() Type { return TypeDS }
+
+// String returns the data in master file format
+func (d DS) String() string {
+	return fmt.Sprintf("%d %d %d %s", d.KeyTag, d.Algorithm, d.DigestType, strings.ToUpper(d.Digest))
+}
+
+// DNSKEY holds the data of a DNSKEY record
+type DNSKEY struct {
+	Flags     uint16
+	Protocol  uint8
+	Algorithm uint8
+	// PublicKey is the base64 encoded public key
+	PublicKey string
+}
+
+// Type returns TypeDNSKEY
+func (DNSKEY) Type() Type { return TypeDNSKEY }
+
+// String returns the data in master file format
+func (d DNSKEY) String() string {
+	return fmt.Sprintf("%d %d %d %s", d.Flags, d.Protocol, d.Algorithm, d.PublicKey)
+}
+
+// IsKSK returns whether the key is a key signing key, i.e. has the secure entry point flag set
+func (d DNSKEY) IsKSK() bool { return d.Flags&0x1 != 0 }
+
+// RRSIG holds the data of an RRSIG (signature) record
+type RRSIG struct {
+	TypeCovered Type
+	Algorithm   uint8
+	Labels      uint8
+	OriginalTTL uint32
+	Expiration  time.Time
+	Inception   time.Time
+	KeyTag      uint16
+	SignerName  domain.Name
+	// Signature is the base64 encoded signature
+	Signature string
+}
+
+// Type returns TypeRRSIG
+func (RRSIG) Type() Type { return TypeRRSIG }
+
+// String returns the data in master file format
+func (d RRSIG) String() string {
+	const layout = "20060102150405"
+	return fmt.Sprintf("%s %d %d %d %s %s %d %s %s", d.TypeCovered, d.Algorithm, d.Labels, d.OriginalTTL,
+		d.Expiration.UTC().Format(layout), d.Inception.UTC().Format(layout), d.KeyTag, fqdn(d.SignerName),
+		d.Signature)
+}
+
+// NSEC holds the data of an NSEC record
+type NSEC struct {
+	NextName domain.Name
+	Types    []Type
+}
+
+// Type returns TypeNSEC
+func (NSEC) Type() Type { return TypeNSEC }
+
+// String returns the data in master file format
+func (d NSEC) String() string { return fqdn(d.NextName) + typeList(d.Types) }
+
+// NSEC3 holds the data of an NSEC3 record
+type NSEC3 struct {
+	HashAlgorithm uint8
+	Flags         uint8
+	Iterations    uint16
+	// Salt is the hex encoded salt, empty if none
+	Salt string
+	// NextHashedOwner is the base32hex encoded hash of the next owner name
+	NextHashedOwner string
+	Types           []Type
+}
+
+// Type returns TypeNSEC3
+func (NSEC3) Type() Type { return TypeNSEC3 }
+
+// String returns the data in master file format
+func (d NSEC3) String() string {
+	salt := d.Salt
+	if len(salt) == 0 {
+		salt = "-"
+	}
+	return fmt.Sprintf("%d %d %d %s %s", d.HashAlgorithm, d.Flags, d.Iterations, strings.ToUpper(salt),
+		strings.ToUpper(d.NextHashedOwner)) + typeList(d.Types)
+}
+
+// IsOptOut returns whether the opt-out flag is set, i.e. the record may cover unsigned delegations
+func (d NSEC3) IsOptOut() bool { return d.Flags&0x1 != 0 }
+
+// Raw holds the data of a record type without specific support, as the fields in master file format
+type Raw struct {
+	RRType Type
+	Fields []string
+}
+
+// Type returns the record type
+func (d Raw) Type() Type { return d.RRType }
+
+// String returns the data in master file format
+func (d Raw) String() string { return strings.Join(d.Fields, " ") }
+
+// Unknown holds the data of a record in wire format, as specified for unknown record types in RFC 3597
+type Unknown struct {
+	RRType Type
+	Data   []byte
+}
+
+// Type returns the record type
+func (d Unknown) Type() Type { return d.RRType }
+
+// String returns the data in the generic RFC 3597 format
+func (d Unknown) String() string {
+	if len(d.Data) == 0 {
+		return `\# 0`
+	}
+	return fmt.Sprintf(`\# %d %s`, len(d.Data), hex.EncodeToString(d.Data))
+}
+
+// fqdn returns the fully-qualified format of the name, which is "." for the root domain
+func fqdn(n domain.Name) string {
+	if n.String() == "" {
+		return "."
+	}
+	return n.FQDN()
+}
+
+// typeList returns the types separated and prefixed by space
+func typeList(types []Type) string {
+	var b strings.Builder
+	for _, t := range types {
+		b.WriteByte(' ')
+		b.WriteString(t.String())
+	}
+	return b.String()
+}
+
+// quoteStrings returns the character strings quoted and escaped in master file format
+func quoteStrings(ss []string) string {
+	var b strings.Builder
+	for i, s := range ss {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte('"')
+		for j := 0; j < len(s); j++ {
+			c := s[j]
+			switch {
+			case c == '"' || c == '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case c < ' ' || c > '~':
+				fmt.Fprintf(&b, "\\%03d", c)
+			default:
+				b.WriteByte(c)
+			}
+		}
+		b.WriteByte('"')
+	}
+	return b.String()
+}
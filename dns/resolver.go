@@ -0,0 +1,124 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/detectify/n5/domain"
+	mdns "github.com/miekg/dns"
+)
+
+// DefaultResolverTimeout is the default time limit of a single query
+const DefaultResolverTimeout = 5 * time.Second
+
+// ErrNameNotFound is returned when the queried name does not exist (NXDOMAIN)
+var ErrNameNotFound = errors.New("name does not exist")
+
+var (
+	defaultServer     string
+	defaultServerOnce sync.Once
+)
+
+// Resolver sends queries to a recursive name server, optionally requesting DNSSEC records
+//
+// The zero value is ready to use, querying the system name server without DNSSEC.
+type Resolver struct {
+	// Server is the address of the recursive name server in "host:port" format, defaults to the first name server of
+	// /etc/resolv.conf
+	Server string
+	// DNSSEC requests DNSSEC records, which are then included in the records returned by Lookup
+	DNSSEC bool
+	// TrustAnchors are the DS records of the root zone keys DNSSEC validation starts from, defaults to the records
+	// returned by RootTrustAnchors
+	TrustAnchors []DS
+	// Timeout is the time limit of a single query, defaults to DefaultResolverTimeout
+	Timeout time.Duration
+}
+
+// Lookup queries the records of the type for the domain name
+//
+// The answer is returned as is, which means it may also contain CNAME records leading to the records of the type, and
+// RRSIG records if DNSSEC is requested. Returns an empty result if the name exists without records of the type, and
+// ErrNameNotFound if the name does not exist.
+func (r *Resolver) Lookup(ctx context.Context, name domain.Name, t Type) ([]Record, error) {
+	resp, err := r.exchange(ctx, fqdn(name), uint16(t), r.DNSSEC, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Rcode == mdns.RcodeNameError {
+		return nil, fmt.Errorf("%s: %w", name.String(), ErrNameNotFound)
+	}
+
+	records := make([]Record, 0, len(resp.Answer))
+	for _, rr := range resp.Answer {
+		record, err := fromRR(rr)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// exchange sends the query to the name server, retrying over TCP if the answer is truncated
+//
+// Returns an error if the query fails, or the response code is other than success or NXDOMAIN.
+func (r *Resolver) exchange(ctx context.Context, name string, t uint16, dnssec, checkingDisabled bool) (*mdns.Msg,
+	error) {
+	m := new(mdns.Msg)
+	m.SetQuestion(name, t)
+	m.RecursionDesired = true
+	m.CheckingDisabled = checkingDisabled
+	if dnssec {
+		m.SetEdns0(4096, true)
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultResolverTimeout
+	}
+	server := r.server()
+
+	client := &mdns.Client{Timeout: timeout}
+	resp, _, err := client.ExchangeContext(ctx, m, server)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		resp, _, err = client.ExchangeContext(ctx, m, server)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s %s: %w", name, Type(t), err)
+	}
+	if resp.Rcode != mdns.RcodeSuccess && resp.Rcode != mdns.RcodeNameError {
+		return nil, fmt.Errorf("failed to query %s %s: %s", name, Type(t), mdns.RcodeToString[resp.Rcode])
+	}
+	return resp, nil
+}
+
+// server returns the name server address, which defaults to the system name server
+func (r *Resolver) server() string {
+	if len(r.Server) > 0 {
+		return withDefaultPort(r.Server)
+	}
+
+	defaultServerOnce.Do(func() {
+		defaultServer = "127.0.0.1:53"
+		config, err := mdns.ClientConfigFromFile("/etc/resolv.conf")
+		if err == nil && len(config.Servers) > 0 {
+			defaultServer = net.JoinHostPort(config.Servers[0], config.Port)
+		}
+	})
+	return defaultServer
+}
+
+// withDefaultPort returns the server address with the DNS port 53 added, if no port is specified
+func withDefaultPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return server
+}
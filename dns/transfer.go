@@ -0,0 +1,443 @@
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/detectify/n5/domain"
+	mdns "github.com/miekg/dns"
+)
+
+// Default limits of zone transfers
+const (
+	DefaultTransferTimeout    = time.Minute
+	DefaultTransferMaxRecords = 1000000
+	DefaultTransferMaxBytes   = 256 << 20
+)
+
+// TSIG algorithms, as specified in RFC 8945
+const (
+	TSIGHMACSHA1   = "hmac-sha1"
+	TSIGHMACSHA224 = "hmac-sha224"
+	TSIGHMACSHA256 = "hmac-sha256"
+	TSIGHMACSHA384 = "hmac-sha384"
+	TSIGHMACSHA512 = "hmac-sha512"
+)
+
+// maxUnsignedMessages is the maximum number of consecutive messages of a transfer signed with TSIG which may be
+// unsigned, as specified in RFC 8945 section 5.3.1
+const maxUnsignedMessages = 99
+
+// ErrTransferLimit is returned when a zone transfer exceeds the record or size limit
+var ErrTransferLimit = errors.New("zone transfer limit exceeded")
+
+// TSIGKey holds a key for signing messages with transaction signatures (TSIG)
+type TSIGKey struct {
+	// Name is the name of the key, as configured on the name server
+	Name domain.Name
+	// Algorithm is the HMAC algorithm of the key, defaults to TSIGHMACSHA256
+	Algorithm string
+	// Secret is the base64 encoded secret of the key
+	Secret string
+}
+
+// TransferOptions holds the options for a zone transfer
+type TransferOptions struct {
+	// Timeout is the time limit of the complete transfer, defaults to DefaultTransferTimeout
+	Timeout time.Duration
+	// MaxRecords is the maximum number of records transferred, defaults to DefaultTransferMaxRecords
+	MaxRecords int
+	// MaxBytes is the maximum size of the transferred records in wire format, defaults to DefaultTransferMaxBytes
+	MaxBytes int
+	// TSIG is the key the transfer is signed with, if any
+	TSIG *TSIGKey
+}
+
+// ZoneTransfer reads the records of a zone transfer one by one
+//
+// The transfer must be closed when not read until the end.
+type ZoneTransfer struct {
+	opts        TransferOptions
+	ctx         context.Context
+	deadline    time.Time
+	conn        *mdns.Conn
+	query       *mdns.Msg
+	tsig        *tsigProvider
+	requestMAC  string
+	messages    int
+	serial      uint32
+	soas        int
+	incremental bool
+	complete    bool
+	unsigned    int
+	held        []mdns.RR
+	digest      []byte
+	pending     []mdns.RR
+	records     int
+	bytes       int
+	record      Record
+	err         error
+	done        chan struct{}
+	close       sync.Once
+}
+
+// AXFR requests a full zone transfer (AXFR) of the zone from the name server over TCP
+//
+// The server address is in "host:port" format, port 53 is used if omitted. Returns an error if the connection to the
+// server fails, while errors during the transfer, such as the server refusing it, are returned by
+// ZoneTransfer.Err.
+func AXFR(ctx context.Context, zone domain.Name, server string, opts TransferOptions) (*ZoneTransfer, error) {
+	m := new(mdns.Msg)
+	m.SetAxfr(fqdn(zone))
+	return startTransfer(ctx, m, server, opts)
+}
+
+// IXFR requests an incremental zone transfer (IXFR) of the zone from the name server over TCP
+//
+// The serial is the version of the zone held by the client. The records are returned in the order sent by the
+// server, which is the sequence of differences specified in RFC 1995, or a full transfer if the server does not support
+// incremental transfers, or a single SOA record if the zone is unchanged.
+func IXFR(ctx context.Context, zone domain.Name, server string, serial uint32, opts TransferOptions) (*ZoneTransfer,
+	error) {
+	m := new(mdns.Msg)
+	m.SetIxfr(fqdn(zone), serial, ".", ".")
+	return startTransfer(ctx, m, server, opts)
+}
+
+func startTransfer(ctx context.Context, m *mdns.Msg, server string, opts TransferOptions) (*ZoneTransfer, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTransferTimeout
+	}
+	if opts.MaxRecords <= 0 {
+		opts.MaxRecords = DefaultTransferMaxRecords
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultTransferMaxBytes
+	}
+	server = withDefaultPort(server)
+
+	z := &ZoneTransfer{opts: opts, ctx: ctx, deadline: time.Now().Add(opts.Timeout), done: make(chan struct{})}
+	if d, ok := ctx.Deadline(); ok && d.Before(z.deadline) {
+		z.deadline = d
+	}
+
+	dialer := net.Dialer{Deadline: z.deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to name server: %w", err)
+	}
+
+	z.conn, z.query = &mdns.Conn{Conn: conn}, m
+	_ = conn.SetDeadline(z.deadline)
+	var out []byte
+	if opts.TSIG != nil {
+		if z.tsig, err = newTSIGProvider(*opts.TSIG); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		m.SetTsig(fqdn(opts.TSIG.Name), z.tsig.algorithm, 300, time.Now().Unix())
+		out, z.requestMAC, err = mdns.TsigGenerateWithProvider(m, z.tsig, "", false)
+	} else {
+		out, err = m.Pack()
+	}
+	if err == nil {
+		_, err = z.conn.Write(out)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to request zone transfer: %w", err)
+	}
+
+	// the transfer is aborted by closing the connection, when cancelled or timed out
+	go func() {
+		timer := time.NewTimer(time.Until(z.deadline))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		case <-z.done:
+		}
+		_ = conn.Close()
+	}()
+	return z, nil
+}
+
+// Scan advances to the next record, which is then available through Record
+//
+// Returns false when the transfer is complete or an error occurred, which is then available through Err. When the
+// transfer is signed with TSIG, the records of unsigned messages are only returned once the MAC of the next signed
+// message, which covers the unsigned messages preceding it, is verified, and the transfer fails if the last message or
+// more than 99 consecutive messages are unsigned.
+func (z *ZoneTransfer) Scan() bool {
+	for len(z.pending) == 0 {
+		if z.err != nil {
+			return false
+		}
+		if z.complete {
+			z.Close()
+			return false
+		}
+		in, signed, err := z.readMsg()
+		if err == nil {
+			err = z.checkMsg(in)
+		}
+		if err != nil {
+			z.fail(z.transferError(err))
+			return false
+		}
+
+		z.records += len(in.Answer)
+		for _, rr := range in.Answer {
+			z.bytes += mdns.Len(rr)
+		}
+		if z.records > z.opts.MaxRecords {
+			z.fail(fmt.Errorf("%w: more than %d records", ErrTransferLimit, z.opts.MaxRecords))
+			return false
+		}
+		if z.bytes > z.opts.MaxBytes {
+			z.fail(fmt.Errorf("%w: more than %d bytes", ErrTransferLimit, z.opts.MaxBytes))
+			return false
+		}
+
+		if z.tsig == nil {
+			z.pending = in.Answer
+			continue
+		}
+		if signed {
+			z.pending, z.held, z.unsigned = append(z.held, in.Answer...), nil, 0
+			continue
+		}
+		z.unsigned++
+		switch {
+		case z.messages == 1:
+			z.fail(fmt.Errorf("zone transfer response is not signed with TSIG key"))
+			return false
+		case z.complete:
+			z.fail(fmt.Errorf("last message of zone transfer is not signed with TSIG key"))
+			return false
+		case z.unsigned > maxUnsignedMessages:
+			z.fail(fmt.Errorf("more than %d consecutive messages of zone transfer are not signed with TSIG key",
+				maxUnsignedMessages))
+			return false
+		}
+		z.held = append(z.held, in.Answer...)
+	}
+
+	rr := z.pending[0]
+	z.pending = z.pending[1:]
+	record, err := fromRR(rr)
+	if err != nil {
+		z.fail(err)
+		return false
+	}
+	z.record = record
+	return true
+}
+
+// Record returns the most recent record read by Scan
+func (z *ZoneTransfer) Record() Record {
+	return z.record
+}
+
+// Err returns the first error encountered during the transfer
+func (z *ZoneTransfer) Err() error {
+	return z.err
+}
+
+// Close aborts the transfer, if not already complete
+func (z *ZoneTransfer) Close() {
+	z.close.Do(func() {
+		close(z.done)
+	})
+}
+
+func (z *ZoneTransfer) fail(err error) {
+	z.err = err
+	z.pending, z.held, z.digest = nil, nil, nil
+	z.Close()
+}
+
+// readMsg reads the next message of the transfer, returning whether it is signed with the TSIG key
+//
+// Returns an error if the TSIG record of the message is not valid.
+func (z *ZoneTransfer) readMsg() (*mdns.Msg, bool, error) {
+	p, err := z.conn.ReadMsgHeader(nil)
+	if err != nil {
+		return nil, false, err
+	}
+	in := new(mdns.Msg)
+	if err := in.Unpack(p); err != nil {
+		return nil, false, err
+	}
+	z.messages++
+	if z.tsig == nil {
+		return in, false, nil
+	}
+	t := in.IsTsig()
+	if t == nil {
+		z.digest = append(z.digest, p...)
+		return in, false, nil
+	}
+	// the MAC of the previous signed message and the unsigned messages since are part of the MAC, and after the first
+	// message only the timers of the TSIG record are
+	provider := &digestProvider{tsigProvider: z.tsig, macSize: len(z.requestMAC) / 2, unsigned: z.digest}
+	if err := mdns.TsigVerifyWithProvider(p, provider, z.requestMAC, z.messages > 1); err != nil {
+		return nil, false, err
+	}
+	z.requestMAC, z.digest = t.MAC, nil
+	return in, true, nil
+}
+
+// checkMsg checks the response of the server and whether it completes the transfer, i.e. an AXFR or an IXFR falling
+// back to a full transfer ends with the second SOA record of the current serial, and an incremental IXFR with the third
+func (z *ZoneTransfer) checkMsg(in *mdns.Msg) error {
+	if in.Id != z.query.Id {
+		return mdns.ErrId
+	}
+	if in.Rcode != mdns.RcodeSuccess {
+		return fmt.Errorf("server responded with %s", mdns.RcodeToString[in.Rcode])
+	}
+	if z.messages == 1 {
+		soa, ok := firstSOA(in)
+		if !ok {
+			return mdns.ErrSoa
+		}
+		z.serial = soa.Serial
+		// the zone is unchanged if the client holds the current version
+		if z.query.Question[0].Qtype == mdns.TypeIXFR &&
+			!serialBefore(z.query.Ns[0].(*mdns.SOA).Serial, z.serial) {
+			z.complete = true
+			return nil
+		}
+	}
+	for _, rr := range in.Answer {
+		soa, ok := rr.(*mdns.SOA)
+		if !ok {
+			continue
+		}
+		if soa.Serial != z.serial {
+			z.incremental = true
+			continue
+		}
+		z.soas++
+		if z.soas == 2 && !z.incremental || z.soas == 3 {
+			z.complete = true
+		}
+	}
+	return nil
+}
+
+// serialBefore returns whether the serial a precedes the serial b in serial number arithmetic, as specified in RFC 1982,
+// i.e. serials wrap around after 4294967295
+func serialBefore(a, b uint32) bool {
+	return a != b && int32(b-a) > 0
+}
+
+func firstSOA(in *mdns.Msg) (*mdns.SOA, bool) {
+	if len(in.Answer) == 0 {
+		return nil, false
+	}
+	soa, ok := in.Answer[0].(*mdns.SOA)
+	return soa, ok
+}
+
+// transferError returns the reason of a failed transfer, as closing the connection hides cancellation and timeout
+func (z *ZoneTransfer) transferError(err error) error {
+	if z.ctx.Err() != nil {
+		return fmt.Errorf("zone transfer aborted: %w", z.ctx.Err())
+	}
+	if !time.Now().Before(z.deadline) {
+		return fmt.Errorf("zone transfer time limit exceeded: %w", context.DeadlineExceeded)
+	}
+	if errors.Is(err, mdns.ErrSig) || errors.Is(err, mdns.ErrTime) || errors.Is(err, mdns.ErrSecret) {
+		return fmt.Errorf("zone transfer TSIG verification failed: %w", err)
+	}
+	return fmt.Errorf("zone transfer failed: %w", err)
+}
+
+// tsigProvider signs and verifies messages with an HMAC key
+type tsigProvider struct {
+	algorithm string
+	hash      func() hash.Hash
+	secret    []byte
+}
+
+func newTSIGProvider(key TSIGKey) (*tsigProvider, error) {
+	algorithm := strings.TrimSuffix(strings.ToLower(key.Algorithm), ".")
+	if len(algorithm) == 0 {
+		algorithm = TSIGHMACSHA256
+	}
+
+	p := &tsigProvider{algorithm: algorithm + "."}
+	switch algorithm {
+	case TSIGHMACSHA1:
+		p.hash = sha1.New
+	case TSIGHMACSHA224:
+		p.hash = sha256.New224
+	case TSIGHMACSHA256:
+		p.hash = sha256.New
+	case TSIGHMACSHA384:
+		p.hash = sha512.New384
+	case TSIGHMACSHA512:
+		p.hash = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported TSIG algorithm %s", key.Algorithm)
+	}
+
+	var err error
+	if p.secret, err = base64.StdEncoding.DecodeString(key.Secret); err != nil {
+		return nil, fmt.Errorf("invalid TSIG secret: %w", err)
+	}
+	return p, nil
+}
+
+// Generate returns the MAC of the message
+func (p *tsigProvider) Generate(msg []byte, _ *mdns.TSIG) ([]byte, error) {
+	h := hmac.New(p.hash, p.secret)
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+// Verify checks the MAC of the message
+func (p *tsigProvider) Verify(msg []byte, t *mdns.TSIG) error {
+	if strings.ToLower(mdns.CanonicalName(t.Algorithm)) != p.algorithm {
+		return mdns.ErrKeyAlg
+	}
+	expected, _ := p.Generate(msg, t)
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil || !hmac.Equal(expected, mac) {
+		return mdns.ErrSig
+	}
+	return nil
+}
+
+// digestProvider verifies the MAC of a signed message of a transfer, which digests the unsigned messages since the
+// previous signed message following the MAC of that message, as specified in RFC 8945 section 5.3.1
+type digestProvider struct {
+	*tsigProvider
+	macSize  int
+	unsigned []byte
+}
+
+// Verify checks the MAC of the message, inserting the unsigned messages after the MAC of the previous signed message
+func (p *digestProvider) Verify(msg []byte, t *mdns.TSIG) error {
+	if len(p.unsigned) == 0 || len(msg) < 2+p.macSize {
+		return p.tsigProvider.Verify(msg, t)
+	}
+	digest := make([]byte, 0, len(msg)+len(p.unsigned))
+	digest = append(digest, msg[:2+p.macSize]...)
+	digest = append(digest, p.unsigned...)
+	return p.tsigProvider.Verify(append(digest, msg[2+p.macSize:]...), t)
+}
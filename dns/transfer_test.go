@@ -0,0 +1,399 @@
+package dns_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/detectify/n5/dns"
+	"github.com/detectify/n5/domain"
+	mdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+const testTSIGSecret = "c2VjcmV0LWtleS1mb3ItdGVzdGluZy10cmFuc2ZlcnM="
+
+// startTransferServer starts an in-process name server serving transfers of example.com over TCP
+func startTransferServer(t *testing.T, delay time.Duration, requireTSIG bool) string {
+	t.Helper()
+
+	soa := mustRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 5 7200 1800 604800 300")
+	return serveTransfer(t, delay, requireTSIG, [][]mdns.RR{
+		{soa, mustRR(t, "example.com. 3600 IN NS ns1.example.com.")},
+		{
+			mustRR(t, "ns1.example.com. 3600 IN A 192.0.2.1"),
+			mustRR(t, "www.example.com. 3600 IN AAAA 2001:db8::1"),
+			mustRR(t, `example.com. 3600 IN TXT "v=spf1 -all" "with \"quotes\""`),
+			mustRR(t, "*.dev.example.com. 3600 IN CNAME www.example.com."),
+			mustRR(t, "example.com. 3600 IN SSHFP 1 1 dd465c09cfa51fb45020cc83316fff21b9ec74ac"),
+		},
+		{soa},
+	}, nil)
+}
+
+// signing of the messages of a transfer served by serveTransfer
+const (
+	// signed messages are signed with TSIG
+	signed = iota
+	// unsigned messages are not signed, and digested by the MAC of the next signed message
+	unsigned
+	// injected messages are not signed, nor digested by the MAC of the next signed message, as if injected on the path
+	injected
+)
+
+// serveTransfer starts an in-process name server sending the messages of the records, starting with the SOA record,
+// over TCP, where the messages of a transfer signed with TSIG are signed as returned by signing, if not nil
+func serveTransfer(t *testing.T, delay time.Duration, requireTSIG bool, messages [][]mdns.RR,
+	signing func(i int) int) string {
+	t.Helper()
+
+	mux := mdns.NewServeMux()
+	mux.HandleFunc("example.com.", func(w mdns.ResponseWriter, r *mdns.Msg) {
+		defer w.Close()
+		time.Sleep(delay)
+
+		q := r.Question[0]
+		if (requireTSIG && (r.IsTsig() == nil || w.TsigStatus() != nil)) ||
+			(q.Qtype != mdns.TypeAXFR && q.Qtype != mdns.TypeIXFR) {
+			m := new(mdns.Msg)
+			m.SetRcode(r, mdns.RcodeRefused)
+			_ = w.WriteMsg(m)
+			return
+		}
+
+		chunks := messages
+		if q.Qtype == mdns.TypeIXFR && int32(messages[0][0].(*mdns.SOA).Serial-r.Ns[0].(*mdns.SOA).Serial) <= 0 {
+			// zone is unchanged
+			chunks = [][]mdns.RR{{messages[0][0]}}
+		}
+		tsig := r.IsTsig()
+		var mac string
+		var digest []byte
+		for i, rrs := range chunks {
+			m := new(mdns.Msg)
+			m.SetReply(r)
+			m.Authoritative = true
+			m.Answer = rrs
+			s := signed
+			if signing != nil {
+				s = signing(i)
+			}
+
+			var out []byte
+			var err error
+			switch {
+			case tsig == nil || w.TsigStatus() != nil || s != signed:
+				if out, err = m.Pack(); s == unsigned {
+					digest = append(digest, out...)
+				}
+			case len(mac) == 0:
+				m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+				out, mac, err = mdns.TsigGenerateWithProvider(m, testDigestProvider{}, tsig.MAC, false)
+			default:
+				m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+				out, mac, err = mdns.TsigGenerateWithProvider(m, testDigestProvider{len(mac) / 2, digest}, mac, true)
+				digest = nil
+			}
+			if err != nil {
+				return
+			}
+			if _, err := w.Write(out); err != nil {
+				return
+			}
+		}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &mdns.Server{
+		Listener:   listener,
+		Handler:    mux,
+		TsigSecret: map[string]string{"transfer-key.": testTSIGSecret},
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return listener.Addr().String()
+}
+
+// testDigestProvider signs the messages of a transfer with the test secret, digesting the unsigned messages following
+// the MAC of the previous signed message, as specified in RFC 8945 section 5.3.1
+type testDigestProvider struct {
+	macSize  int
+	unsigned []byte
+}
+
+func (p testDigestProvider) Generate(msg []byte, _ *mdns.TSIG) ([]byte, error) {
+	secret, err := base64.StdEncoding.DecodeString(testTSIGSecret)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write(msg[:2+p.macSize])
+	h.Write(p.unsigned)
+	h.Write(msg[2+p.macSize:])
+	return h.Sum(nil), nil
+}
+
+func (p testDigestProvider) Verify([]byte, *mdns.TSIG) error {
+	return mdns.ErrSig
+}
+
+func mustRR(t *testing.T, s string) mdns.RR {
+	rr, err := mdns.NewRR(s)
+	require.NoError(t, err)
+	return rr
+}
+
+func transferAll(t *testing.T, z *dns.ZoneTransfer) ([]dns.Record, error) {
+	t.Helper()
+	var records []dns.Record
+	for z.Scan() {
+		records = append(records, z.Record())
+	}
+	return records, z.Err()
+}
+
+func TestAXFR_WithOpenServer_ShouldReturnRecords(t *testing.T) {
+	server := startTransferServer(t, 0, false)
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.NoError(t, err)
+	require.Len(t, records, 8)
+
+	require.Equal(t, dns.TypeSOA, records[0].Type())
+	require.Equal(t, uint32(5), records[0].Data.(dns.SOA).Serial)
+	require.Equal(t, dns.NS{Host: domain.MustParse("ns1.example.com")}, records[1].Data)
+	require.Equal(t, dns.A{Addr: netip.MustParseAddr("192.0.2.1")}, records[2].Data)
+	require.Equal(t, dns.AAAA{Addr: netip.MustParseAddr("2001:db8::1")}, records[3].Data)
+	require.Equal(t, dns.TXT{Text: []string{"v=spf1 -all", `with "quotes"`}}, records[4].Data)
+	require.True(t, records[5].Wildcard)
+	require.Equal(t, "dev.example.com", records[5].Name.String())
+	require.Equal(t, dns.TypeSSHFP, records[6].Type())
+	require.Equal(t, dns.Unknown{RRType: dns.TypeSSHFP, Data: []byte{1, 1, 0xdd, 0x46, 0x5c, 0x09, 0xcf, 0xa5, 0x1f,
+		0xb4, 0x50, 0x20, 0xcc, 0x83, 0x31, 0x6f, 0xff, 0x21, 0xb9, 0xec, 0x74, 0xac}}, records[6].Data)
+	require.Equal(t, dns.TypeSOA, records[7].Type())
+}
+
+func TestAXFR_WithEscapedNames_ShouldReturnRecords(t *testing.T) {
+	soa := mustRR(t, `example.com. 3600 IN SOA ns1.example.com. john\.doe.example.com. 5 7200 1800 604800 300`)
+	server := serveTransfer(t, 0, false, [][]mdns.RR{{
+		soa,
+		mustRR(t, "1.0/25.example.com. 3600 IN CNAME 1.0/25.2.0.192.in-addr.arpa."),
+		mustRR(t, `example.com. 3600 IN NSEC \000.example.com. A NS SOA RRSIG NSEC`),
+		soa,
+	}}, nil)
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	require.Equal(t, `john\.doe.example.com`, records[0].Data.(dns.SOA).RName.String())
+	require.Equal(t, "1.0/25.example.com", records[1].Name.String())
+	require.Equal(t, "1.0/25.2.0.192.in-addr.arpa", records[1].Data.(dns.CNAME).Target.String())
+	require.Equal(t, `\000.example.com`, records[2].Data.(dns.NSEC).NextName.String())
+}
+
+func TestAXFR_WithTSIG_ShouldReturnRecords(t *testing.T) {
+	server := startTransferServer(t, 0, true)
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		TSIG: &dns.TSIGKey{Name: domain.MustParse("transfer-key"), Secret: testTSIGSecret},
+	})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.NoError(t, err)
+	require.Len(t, records, 8)
+}
+
+func TestAXFR_WithWrongTSIGKey_ShouldReturnError(t *testing.T) {
+	server := startTransferServer(t, 0, true)
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		TSIG: &dns.TSIGKey{Name: domain.MustParse("transfer-key"), Secret: "d3Jvbmcta2V5"},
+	})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.Error(t, err)
+	require.Empty(t, records)
+}
+
+func TestAXFR_WithTSIGAndUnsignedResponse_ShouldReturnError(t *testing.T) {
+	server := startTransferServer(t, 0, false)
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		TSIG: &dns.TSIGKey{Name: domain.MustParse("other-key"), Secret: testTSIGSecret},
+	})
+	require.NoError(t, err)
+	_, err = transferAll(t, z)
+	require.Error(t, err)
+}
+
+func TestAXFR_WithTSIGAndUnsignedMessage_ShouldReturnRecords(t *testing.T) {
+	soa := mustRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 5 7200 1800 604800 300")
+	server := serveTransfer(t, 0, true, [][]mdns.RR{
+		{soa},
+		{mustRR(t, "example.com. 3600 IN NS ns1.example.com.")},
+		{mustRR(t, "ns1.example.com. 3600 IN A 192.0.2.1")},
+		{soa},
+	}, func(i int) int {
+		if i == 1 {
+			return unsigned
+		}
+		return signed
+	})
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		TSIG: &dns.TSIGKey{Name: domain.MustParse("transfer-key"), Secret: testTSIGSecret},
+	})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+}
+
+func TestAXFR_WithTSIGAndInjectedMessage_ShouldReturnError(t *testing.T) {
+	soa := mustRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 5 7200 1800 604800 300")
+	server := serveTransfer(t, 0, true, [][]mdns.RR{
+		{soa},
+		{mustRR(t, "evil.example.com. 3600 IN A 192.0.2.66")},
+		{mustRR(t, "ns1.example.com. 3600 IN A 192.0.2.1")},
+		{soa},
+	}, func(i int) int {
+		if i == 1 {
+			return injected
+		}
+		return signed
+	})
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		TSIG: &dns.TSIGKey{Name: domain.MustParse("transfer-key"), Secret: testTSIGSecret},
+	})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.Error(t, err)
+	require.ErrorIs(t, err, mdns.ErrSig)
+	require.Len(t, records, 1)
+}
+
+func TestAXFR_WithTSIGAndUnsignedLastMessage_ShouldReturnError(t *testing.T) {
+	soa := mustRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 5 7200 1800 604800 300")
+	server := serveTransfer(t, 0, true, [][]mdns.RR{
+		{soa, mustRR(t, "example.com. 3600 IN NS ns1.example.com.")},
+		{mustRR(t, "evil.example.com. 3600 IN A 192.0.2.66"), soa},
+	}, func(i int) int {
+		if i == 1 {
+			return unsigned
+		}
+		return signed
+	})
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		TSIG: &dns.TSIGKey{Name: domain.MustParse("transfer-key"), Secret: testTSIGSecret},
+	})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "last message")
+	require.Len(t, records, 2)
+}
+
+func TestAXFR_WithTSIGAndTooManyUnsignedMessages_ShouldReturnError(t *testing.T) {
+	soa := mustRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 5 7200 1800 604800 300")
+	messages := [][]mdns.RR{{soa}}
+	for i := 0; i < 100; i++ {
+		messages = append(messages, []mdns.RR{mustRR(t, "ns1.example.com. 3600 IN A 192.0.2.1")})
+	}
+	messages = append(messages, []mdns.RR{soa})
+	server := serveTransfer(t, 0, true, messages, func(i int) int {
+		if i > 0 && i < len(messages)-1 {
+			return unsigned
+		}
+		return signed
+	})
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		TSIG: &dns.TSIGKey{Name: domain.MustParse("transfer-key"), Secret: testTSIGSecret},
+	})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "consecutive messages")
+	require.Len(t, records, 1)
+}
+
+func TestAXFR_WithRefusingServer_ShouldReturnError(t *testing.T) {
+	server := startTransferServer(t, 0, true)
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.Error(t, err)
+	require.Empty(t, records)
+}
+
+func TestAXFR_WithRecordLimit_ShouldReturnError(t *testing.T) {
+	server := startTransferServer(t, 0, false)
+
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		MaxRecords: 4,
+	})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.True(t, errors.Is(err, dns.ErrTransferLimit))
+	require.Len(t, records, 2)
+}
+
+func TestAXFR_WithTimeout_ShouldReturnError(t *testing.T) {
+	server := startTransferServer(t, time.Second, false)
+
+	start := time.Now()
+	z, err := dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{
+		Timeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	_, err = transferAll(t, z)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestAXFR_WithUnreachableServer_ShouldReturnError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	_, err = dns.AXFR(context.Background(), domain.MustParse("example.com"), server, dns.TransferOptions{})
+	require.Error(t, err)
+}
+
+func TestIXFR_WithUnchangedZone_ShouldReturnSOA(t *testing.T) {
+	server := startTransferServer(t, 0, false)
+
+	z, err := dns.IXFR(context.Background(), domain.MustParse("example.com"), server, 5, dns.TransferOptions{})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, dns.TypeSOA, records[0].Type())
+}
+
+func TestIXFR_WithWrappedSerial_ShouldReturnRecords(t *testing.T) {
+	server := startTransferServer(t, 0, false)
+
+	z, err := dns.IXFR(context.Background(), domain.MustParse("example.com"), server, 4294967295,
+		dns.TransferOptions{})
+	require.NoError(t, err)
+	records, err := transferAll(t, z)
+	require.NoError(t, err)
+	require.Len(t, records, 8)
+}
@@ -0,0 +1,157 @@
+package dns
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/detectify/n5/domain"
+	mdns "github.com/miekg/dns"
+)
+
+// fromRR converts a record of the wire format implementation to a typed record
+func fromRR(rr mdns.RR) (Record, error) {
+	h := rr.Header()
+	record := Record{TTL: h.Ttl, Class: Class(h.Class)}
+
+	owner := h.Name
+	if owner == "*." || strings.HasPrefix(owner, "*.") {
+		record.Wildcard = true
+		owner = strings.TrimPrefix(owner, "*.")
+	}
+	var err error
+	if record.Name, err = parseWireName(owner); err != nil {
+		return Record{}, err
+	}
+
+	switch v := rr.(type) {
+	case *mdns.A:
+		// A records are held as 16 byte IPv4-mapped addresses
+		addr, _ := netip.AddrFromSlice(v.A)
+		record.Data = A{Addr: addr.Unmap()}
+	case *mdns.AAAA:
+		addr, _ := netip.AddrFromSlice(v.AAAA)
+		record.Data = AAAA{Addr: addr}
+	case *mdns.NS:
+		var d NS
+		d.Host, err = parseWireName(v.Ns)
+		record.Data = d
+	case *mdns.CNAME:
+		var d CNAME
+		d.Target, err = parseWireName(v.Target)
+		record.Data = d
+	case *mdns.DNAME:
+		var d DNAME
+		d.Target, err = parseWireName(v.Target)
+		record.Data = d
+	case *mdns.PTR:
+		var d PTR
+		d.Target, err = parseWireName(v.Ptr)
+		record.Data = d
+	case *mdns.MX:
+		d := MX{Preference: v.Preference}
+		d.Host, err = parseWireName(v.Mx)
+		record.Data = d
+	case *mdns.TXT:
+		d := TXT{Text: make([]string, 0, len(v.Txt))}
+		for _, t := range v.Txt {
+			d.Text = append(d.Text, unescapeWireText(t))
+		}
+		record.Data = d
+	case *mdns.SOA:
+		d := SOA{Serial: v.Serial, Refresh: v.Refresh, Retry: v.Retry, Expire: v.Expire, Minimum: v.Minttl}
+		if d.MName, err = parseWireName(v.Ns); err == nil {
+			d.RName, err = parseWireName(v.Mbox)
+		}
+		record.Data = d
+	case *mdns.SRV:
+		d := SRV{Priority: v.Priority, Weight: v.Weight, Port: v.Port}
+		d.Target, err = parseWireName(v.Target)
+		record.Data = d
+	case *mdns.CAA:
+		record.Data = CAA{Flags: v.Flag, Tag: v.Tag, Value: v.Value}
+	case *mdns.DS:
+		record.Data = DS{KeyTag: v.KeyTag, Algorithm: v.Algorithm, DigestType: v.DigestType,
+			Digest: strings.ToLower(v.Digest)}
+	case *mdns.DNSKEY:
+		record.Data = DNSKEY{Flags: v.Flags, Protocol: v.Protocol, Algorithm: v.Algorithm, PublicKey: v.PublicKey}
+	case *mdns.RRSIG:
+		d := RRSIG{TypeCovered: Type(v.TypeCovered), Algorithm: v.Algorithm, Labels: v.Labels,
+			OriginalTTL: v.OrigTtl, Expiration: time.Unix(int64(v.Expiration), 0).UTC(),
+			Inception: time.Unix(int64(v.Inception), 0).UTC(), KeyTag: v.KeyTag, Signature: v.Signature}
+		d.SignerName, err = parseWireName(v.SignerName)
+		record.Data = d
+	case *mdns.NSEC:
+		d := NSEC{Types: typesFromWire(v.TypeBitMap)}
+		d.NextName, err = parseWireName(v.NextDomain)
+		record.Data = d
+	case *mdns.NSEC3:
+		salt := v.Salt
+		if salt == "-" {
+			salt = ""
+		}
+		record.Data = NSEC3{HashAlgorithm: v.Hash, Flags: v.Flags, Iterations: v.Iterations,
+			Salt: strings.ToLower(salt), NextHashedOwner: strings.ToLower(v.NextDomain),
+			Types: typesFromWire(v.TypeBitMap)}
+	default:
+		record.Data, err = unknownFromRR(rr)
+	}
+	if err != nil {
+		// the data is kept in wire format if a name it holds is invalid, rather than failing for the whole record
+		if record.Data, err = unknownFromRR(rr); err != nil {
+			return Record{}, fmt.Errorf("invalid %s record %s: %w", Type(h.Rrtype), h.Name, err)
+		}
+	}
+	return record, nil
+}
+
+// typesFromWire converts a type bitmap of the wire format implementation
+func typesFromWire(bitmap []uint16) []Type {
+	types := make([]Type, 0, len(bitmap))
+	for _, t := range bitmap {
+		types = append(types, Type(t))
+	}
+	return types
+}
+
+// unknownFromRR returns the data of the record in wire format
+func unknownFromRR(rr mdns.RR) (RData, error) {
+	buf := make([]byte, mdns.Len(rr))
+	off, err := mdns.PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	length := int(rr.Header().Rdlength)
+	return Unknown{RRType: Type(rr.Header().Rrtype), Data: buf[off-length : off]}, nil
+}
+
+// parseWireName parses a name of the wire format implementation, which are fully-qualified and in presentation
+// format, such as john\.doe.example.com. or 0/25.2.0.192.in-addr.arpa.
+func parseWireName(s string) (domain.Name, error) {
+	if s == "." || len(s) == 0 {
+		return domain.RootDomain, nil
+	}
+	return domain.ParseEscaped(s)
+}
+
+// unescapeWireText removes the presentation format escapes of a character string
+func unescapeWireText(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		n, err := unescapeZone(s[i:], &b)
+		if err != nil {
+			b.WriteString(s[i:])
+			break
+		}
+		i += n - 1
+	}
+	return b.String()
+}
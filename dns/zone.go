@@ -0,0 +1,671 @@
+package dns
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// maxIncludeDepth limits the nesting of $INCLUDE directives, which also prevents include loops
+const maxIncludeDepth = 8
+
+// ZoneOptions holds the options for parsing a zone file
+type ZoneOptions struct {
+	// Origin is the initial origin used for relative names, until changed by $ORIGIN; the zero value means no
+	// origin, use $ORIGIN . for the root
+	Origin domain.Name
+	// TTL is the initial default TTL, until changed by $TTL; zero means no default
+	TTL uint32
+	// Filename is the name of the zone file, used in error messages
+	Filename string
+	// Include is the file system $INCLUDE directives are opened from; nil disallows $INCLUDE
+	//
+	// Paths are resolved by the file system, which for the fs.FS implementations of the standard library means
+	// absolute paths and paths escaping the root (e.g. "../secret") are rejected.
+	Include fs.FS
+}
+
+// ZoneError is returned for errors in a zone file, indicating the location of the error
+type ZoneError struct {
+	Filename string
+	Line     int
+	Err      error
+}
+
+// Error returns the error message, prefixed with the file name and line number
+func (e *ZoneError) Error() string {
+	if len(e.Filename) == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Filename, e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ZoneError) Unwrap() error {
+	return e.Err
+}
+
+// ParseZone parses a zone file in the master file format specified in RFC 1035 and returns all records
+//
+// Returns a *ZoneError if the zone file contains an invalid entry.
+func ParseZone(r io.Reader, opts ZoneOptions) ([]Record, error) {
+	var records []Record
+	s := NewZoneScanner(r, opts)
+	for s.Scan() {
+		records = append(records, s.Record())
+	}
+	return records, s.Err()
+}
+
+// ZoneScanner reads the records of a zone file one by one
+//
+// Supports the $ORIGIN, $TTL and $INCLUDE directives, "@" and relative names, parentheses continuation, quoted
+// strings and comments. Owner and target names are validated as domain names, which may contain escape sequences and
+// characters not valid in host names (see domain.ParseEscaped), "*." wildcard owner names are reported
+// with the Record.Wildcard flag. Common record types are parsed into typed data (e.g. A, MX, SOA), other types into
+// Raw data, or Unknown data when the generic RFC 3597 format is used.
+type ZoneScanner struct {
+	opts    ZoneOptions
+	files   []*zoneFile
+	ttl     uint32
+	hasTTL  bool
+	last    Record
+	hasLast bool
+	record  Record
+	err     error
+}
+
+// zoneFile holds the state of a zone file being read
+type zoneFile struct {
+	name    string
+	scanner *bufio.Scanner
+	closer  io.Closer
+	line    int
+	origin  zoneOrigin
+}
+
+// zoneOrigin holds the origin of relative names, which is set by $ORIGIN or the options, and may be the root
+type zoneOrigin struct {
+	name domain.Name
+	set  bool
+}
+
+// zoneToken holds a field of a zone file entry
+type zoneToken struct {
+	value string
+	// raw is the value as written, with escape sequences
+	raw     string
+	quoted  bool
+	escaped bool
+}
+
+// zoneEntry holds the fields of a zone file entry, which may span several lines with parentheses
+type zoneEntry struct {
+	line       int
+	blankOwner bool
+	tokens     []zoneToken
+}
+
+// NewZoneScanner returns a scanner reading records from the zone file
+func NewZoneScanner(r io.Reader, opts ZoneOptions) *ZoneScanner {
+	s := &ZoneScanner{opts: opts}
+	if opts.TTL > 0 {
+		s.ttl, s.hasTTL = opts.TTL, true
+	}
+	s.push(opts.Filename, r, nil, zoneOrigin{name: opts.Origin, set: len(opts.Origin.String()) > 0})
+	return s
+}
+
+// Scan advances to the next record, which is then available through Record
+//
+// Returns false when there are no more records or an error occurred, which is then available through Err.
+func (s *ZoneScanner) Scan() bool {
+	for s.err == nil && len(s.files) > 0 {
+		f := s.files[len(s.files)-1]
+		entry, err := f.next()
+		if err == io.EOF {
+			s.pop()
+			continue
+		}
+		if err != nil {
+			s.fail(f, f.line, err)
+			break
+		}
+
+		record, ok, err := s.parseEntry(f, entry)
+		if err != nil {
+			s.fail(f, entry.line, err)
+			break
+		}
+		if ok {
+			s.record = record
+			return true
+		}
+	}
+	return false
+}
+
+// Record returns the most recent record read by Scan
+func (s *ZoneScanner) Record() Record {
+	return s.record
+}
+
+// Err returns the first error encountered by the scanner
+func (s *ZoneScanner) Err() error {
+	return s.err
+}
+
+func (s *ZoneScanner) fail(f *zoneFile, line int, err error) {
+	s.err = &ZoneError{Filename: f.name, Line: line, Err: err}
+	for len(s.files) > 0 {
+		s.pop()
+	}
+}
+
+func (s *ZoneScanner) push(name string, r io.Reader, closer io.Closer, origin zoneOrigin) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	s.files = append(s.files, &zoneFile{name: name, scanner: scanner, closer: closer, origin: origin})
+}
+
+func (s *ZoneScanner) pop() {
+	f := s.files[len(s.files)-1]
+	if f.closer != nil {
+		_ = f.closer.Close()
+	}
+	s.files = s.files[:len(s.files)-1]
+}
+
+// parseEntry parses a zone file entry, returning whether it was a record or a directive
+func (s *ZoneScanner) parseEntry(f *zoneFile, e zoneEntry) (Record, bool, error) {
+	tokens := e.tokens
+	if !e.blankOwner && !tokens[0].quoted && strings.HasPrefix(tokens[0].value, "$") {
+		return Record{}, false, s.parseDirective(f, tokens)
+	}
+
+	record := Record{Class: ClassIN}
+	if s.hasLast {
+		record.Class = s.last.Class
+	}
+	if e.blankOwner {
+		if !s.hasLast {
+			return Record{}, false, fmt.Errorf("no previous owner name for entry")
+		}
+		record.Name, record.Wildcard = s.last.Name, s.last.Wildcard
+	} else {
+		if tokens[0].raw == "*" || strings.HasPrefix(tokens[0].raw, "*.") {
+			record.Wildcard = true
+			tokens[0].raw = strings.TrimPrefix(strings.TrimPrefix(tokens[0].raw, "*"), ".")
+			if len(tokens[0].raw) == 0 {
+				tokens[0].raw = "@"
+			}
+		}
+		name, err := parseZoneName(tokens[0], f.origin)
+		if err != nil {
+			return Record{}, false, err
+		}
+		record.Name = name
+		tokens = tokens[1:]
+	}
+
+	var ttl uint32
+	var hasTTL, hasClass bool
+	for i := 0; i < 2 && len(tokens) > 0; i++ {
+		t := tokens[0]
+		if !hasTTL && len(t.value) > 0 && t.value[0] >= '0' && t.value[0] <= '9' {
+			v, err := parseTTL(t.value)
+			if err != nil {
+				return Record{}, false, err
+			}
+			ttl, hasTTL = v, true
+		} else if class, err := ParseClass(t.value); !hasClass && err == nil && !t.quoted {
+			record.Class, hasClass = class, true
+		} else {
+			break
+		}
+		tokens = tokens[1:]
+	}
+
+	switch {
+	case hasTTL:
+		record.TTL = ttl
+	case s.hasTTL:
+		record.TTL = s.ttl
+	case s.hasLast:
+		record.TTL = s.last.TTL
+	default:
+		return Record{}, false, fmt.Errorf("no TTL specified and no default TTL set with $TTL")
+	}
+
+	if len(tokens) == 0 {
+		return Record{}, false, fmt.Errorf("missing record type")
+	}
+	rrType, err := ParseType(tokens[0].value)
+	if err != nil {
+		return Record{}, false, err
+	}
+	record.Data, err = parseRData(rrType, tokens[1:], f.origin)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("invalid %s record: %w", rrType, err)
+	}
+
+	s.last, s.hasLast = record, true
+	return record, true, nil
+}
+
+func (s *ZoneScanner) parseDirective(f *zoneFile, tokens []zoneToken) error {
+	directive := strings.ToUpper(tokens[0].value)
+	args := tokens[1:]
+	switch directive {
+	case "$ORIGIN":
+		if len(args) != 1 {
+			return fmt.Errorf("$ORIGIN requires one argument")
+		}
+		origin, err := parseZoneName(args[0], f.origin)
+		if err != nil {
+			return err
+		}
+		f.origin = zoneOrigin{name: origin, set: true}
+		return nil
+	case "$TTL":
+		if len(args) != 1 {
+			return fmt.Errorf("$TTL requires one argument")
+		}
+		ttl, err := parseTTL(args[0].value)
+		if err != nil {
+			return err
+		}
+		s.ttl, s.hasTTL = ttl, true
+		return nil
+	case "$INCLUDE":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("$INCLUDE requires a file name and optional origin")
+		}
+		if s.opts.Include == nil {
+			return fmt.Errorf("$INCLUDE is not allowed")
+		}
+		if len(s.files) >= maxIncludeDepth {
+			return fmt.Errorf("$INCLUDE nested deeper than %d files", maxIncludeDepth)
+		}
+		origin := f.origin
+		if len(args) == 2 {
+			var err error
+			name, err := parseZoneName(args[1], f.origin)
+			if err != nil {
+				return err
+			}
+			origin = zoneOrigin{name: name, set: true}
+		}
+		file, err := s.opts.Include.Open(args[0].value)
+		if err != nil {
+			return fmt.Errorf("failed to open $INCLUDE file: %w", err)
+		}
+		s.push(args[0].value, file, file, origin)
+		return nil
+	default:
+		return fmt.Errorf("unsupported directive %s", tokens[0].value)
+	}
+}
+
+// next returns the next entry of the zone file, or io.EOF if there are no more entries
+func (f *zoneFile) next() (zoneEntry, error) {
+	var entry zoneEntry
+	depth := 0
+	for f.scanner.Scan() {
+		f.line++
+		line := f.scanner.Text()
+		if len(entry.tokens) == 0 && depth == 0 {
+			entry.line = f.line
+			entry.blankOwner = len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+		}
+
+		var err error
+		entry.tokens, depth, err = tokenizeZoneLine(line, entry.tokens, depth)
+		if err != nil {
+			return entry, err
+		}
+		if depth == 0 && len(entry.tokens) > 0 {
+			return entry, nil
+		}
+	}
+	if err := f.scanner.Err(); err != nil {
+		return entry, err
+	}
+	if depth > 0 {
+		return entry, fmt.Errorf("unbalanced parentheses at end of file")
+	}
+	return entry, io.EOF
+}
+
+// tokenizeZoneLine splits a line into fields, tracking the parentheses depth across lines
+func tokenizeZoneLine(line string, tokens []zoneToken, depth int) ([]zoneToken, int, error) {
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			return tokens, depth, nil
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			if depth == 0 {
+				return nil, 0, fmt.Errorf("unbalanced parentheses")
+			}
+			depth--
+			i++
+		case c == '"':
+			var b strings.Builder
+			escaped := false
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					n, err := unescapeZone(line[j:], &b)
+					if err != nil {
+						return nil, 0, err
+					}
+					escaped = true
+					j += n - 1
+					continue
+				}
+				b.WriteByte(line[j])
+			}
+			if j == len(line) {
+				return nil, 0, fmt.Errorf("unterminated quoted string")
+			}
+			tokens = append(tokens, zoneToken{value: b.String(), raw: line[i+1 : j], quoted: true, escaped: escaped})
+			i = j + 1
+		default:
+			var b strings.Builder
+			escaped := false
+			j := i
+			for ; j < len(line) && !strings.ContainsRune(" \t\r;()\"", rune(line[j])); j++ {
+				if line[j] == '\\' {
+					n, err := unescapeZone(line[j:], &b)
+					if err != nil {
+						return nil, 0, err
+					}
+					escaped = true
+					j += n - 1
+					continue
+				}
+				b.WriteByte(line[j])
+			}
+			tokens = append(tokens, zoneToken{value: b.String(), raw: line[i:j], escaped: escaped})
+			i = j
+		}
+	}
+	return tokens, depth, nil
+}
+
+// unescapeZone writes the character escaped with "\X" or "\DDD" at the beginning of s and returns its length
+func unescapeZone(s string, b *strings.Builder) (int, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("incomplete escape sequence")
+	}
+	if s[1] < '0' || s[1] > '9' {
+		b.WriteByte(s[1])
+		return 2, nil
+	}
+	if len(s) < 4 {
+		return 0, fmt.Errorf("incomplete escape sequence %s", s)
+	}
+	v, err := strconv.ParseUint(s[1:4], 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape sequence %s", s[:4])
+	}
+	b.WriteByte(byte(v))
+	return 4, nil
+}
+
+// parseZoneName parses an absolute, relative or "@" name, relative names being completed with the origin
+//
+// Names may contain escape sequences and characters which are not valid in host names, such as the mailbox
+// john\.doe.example.com. or the RFC 2317 name 0/25.2.0.192.in-addr.arpa., see domain.ParseEscaped.
+func parseZoneName(t zoneToken, origin zoneOrigin) (domain.Name, error) {
+	s := t.raw
+	switch {
+	case s == "@" && !t.quoted:
+		return origin.name, nil
+	case s == ".":
+		return domain.RootDomain, nil
+	case isAbsoluteZoneName(s):
+	case !origin.set:
+		return domain.Name{}, fmt.Errorf("relative name %s without $ORIGIN", s)
+	default:
+		// the root origin is the final period of the name
+		s = s + "." + origin.name.String()
+	}
+	if s == "*" || strings.HasPrefix(s, "*.") || strings.HasPrefix(s, "@.") {
+		return domain.Name{}, fmt.Errorf("name %s is not a valid domain name", t.raw)
+	}
+	return domain.ParseEscaped(s)
+}
+
+// isAbsoluteZoneName returns whether the name ends with a period which is not escaped
+func isAbsoluteZoneName(s string) bool {
+	trimmed := strings.TrimSuffix(s, ".")
+	return len(trimmed) < len(s) && (len(trimmed)-len(strings.TrimRight(trimmed, `\`)))%2 == 0
+}
+
+// parseTTL parses a TTL in seconds or with the BIND unit suffixes, e.g. "3600" or "1h30m"
+func parseTTL(s string) (uint32, error) {
+	var total, value uint64
+	digits := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= '0' && c <= '9' {
+			value = value*10 + uint64(c-'0')
+			digits = true
+			if value > 1<<32 {
+				return 0, fmt.Errorf("TTL %s is out of range", s)
+			}
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid TTL %s", s)
+		}
+		switch c {
+		case 's', 'S':
+		case 'm', 'M':
+			value *= 60
+		case 'h', 'H':
+			value *= 60 * 60
+		case 'd', 'D':
+			value *= 24 * 60 * 60
+		case 'w', 'W':
+			value *= 7 * 24 * 60 * 60
+		default:
+			return 0, fmt.Errorf("invalid TTL %s", s)
+		}
+		total += value
+		value, digits = 0, false
+	}
+	total += value
+	if total > 1<<31-1 {
+		return 0, fmt.Errorf("TTL %s is out of range", s)
+	}
+	return uint32(total), nil
+}
+
+// parseRData parses the fields of a record into the type specific data
+func parseRData(t Type, fields []zoneToken, origin zoneOrigin) (RData, error) {
+	if len(fields) > 0 && fields[0].value == "#" && fields[0].escaped && !fields[0].quoted {
+		return parseUnknown(t, fields[1:])
+	}
+
+	p := rdataParser{fields: fields, origin: origin}
+	var data RData
+	switch t {
+	case TypeA:
+		addr := p.addr()
+		if p.err == nil && !addr.Is4() {
+			p.err = fmt.Errorf("%s is not an IPv4 address", addr)
+		}
+		data = A{Addr: addr}
+	case TypeAAAA:
+		addr := p.addr()
+		if p.err == nil && !addr.Is6() {
+			p.err = fmt.Errorf("%s is not an IPv6 address", addr)
+		}
+		data = AAAA{Addr: addr}
+	case TypeNS:
+		data = NS{Host: p.name()}
+	case TypeCNAME:
+		data = CNAME{Target: p.name()}
+	case TypeDNAME:
+		data = DNAME{Target: p.name()}
+	case TypePTR:
+		data = PTR{Target: p.name()}
+	case TypeMX:
+		data = MX{Preference: uint16(p.uint(16)), Host: p.name()}
+	case TypeTXT:
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("missing text")
+		}
+		text := make([]string, 0, len(fields))
+		for _, f := range fields {
+			if len(f.value) > 255 {
+				return nil, fmt.Errorf("character string longer than 255 bytes")
+			}
+			text = append(text, f.value)
+		}
+		return TXT{Text: text}, nil
+	case TypeSOA:
+		data = SOA{MName: p.name(), RName: p.name(), Serial: uint32(p.uint(32)), Refresh: p.ttl(), Retry: p.ttl(),
+			Expire: p.ttl(), Minimum: p.ttl()}
+	case TypeSRV:
+		data = SRV{Priority: uint16(p.uint(16)), Weight: uint16(p.uint(16)), Port: uint16(p.uint(16)),
+			Target: p.name()}
+	case TypeCAA:
+		flags := uint8(p.uint(8))
+		tag := p.string()
+		data = CAA{Flags: flags, Tag: tag, Value: p.string()}
+	default:
+		raw := Raw{RRType: t}
+		for _, f := range fields {
+			if f.quoted {
+				raw.Fields = append(raw.Fields, quoteStrings([]string{f.value}))
+			} else {
+				raw.Fields = append(raw.Fields, f.value)
+			}
+		}
+		return raw, nil
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(p.fields) > 0 {
+		return nil, fmt.Errorf("unexpected field %s", p.fields[0].value)
+	}
+	return data, nil
+}
+
+// parseUnknown parses the generic RFC 3597 format, e.g. "\# 4 0a000001"
+func parseUnknown(t Type, fields []zoneToken) (RData, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf(`missing data length after \#`)
+	}
+	length, err := strconv.ParseUint(fields[0].value, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data length %s", fields[0].value)
+	}
+	var hexData strings.Builder
+	for _, f := range fields[1:] {
+		hexData.WriteString(f.value)
+	}
+	data, err := hex.DecodeString(hexData.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data: %w", err)
+	}
+	if len(data) != int(length) {
+		return nil, fmt.Errorf("data length is %d, expected %d", len(data), length)
+	}
+	return Unknown{RRType: t, Data: data}, nil
+}
+
+// rdataParser consumes record data fields, keeping the first error
+type rdataParser struct {
+	fields []zoneToken
+	origin zoneOrigin
+	err    error
+}
+
+func (p *rdataParser) next() (zoneToken, bool) {
+	if p.err != nil {
+		return zoneToken{}, false
+	}
+	if len(p.fields) == 0 {
+		p.err = errors.New("missing field")
+		return zoneToken{}, false
+	}
+	t := p.fields[0]
+	p.fields = p.fields[1:]
+	return t, true
+}
+
+func (p *rdataParser) name() domain.Name {
+	t, ok := p.next()
+	if !ok {
+		return domain.Name{}
+	}
+	n, err := parseZoneName(t, p.origin)
+	if err != nil {
+		p.err = err
+	}
+	return n
+}
+
+func (p *rdataParser) addr() netip.Addr {
+	t, ok := p.next()
+	if !ok {
+		return netip.Addr{}
+	}
+	addr, err := netip.ParseAddr(t.value)
+	if err != nil || addr.Zone() != "" {
+		p.err = fmt.Errorf("%s is not an IP address", t.value)
+	}
+	return addr
+}
+
+func (p *rdataParser) uint(bits int) uint64 {
+	t, ok := p.next()
+	if !ok {
+		return 0
+	}
+	v, err := strconv.ParseUint(t.value, 10, bits)
+	if err != nil {
+		p.err = fmt.Errorf("%s is not a %d-bit number", t.value, bits)
+	}
+	return v
+}
+
+func (p *rdataParser) ttl() uint32 {
+	t, ok := p.next()
+	if !ok {
+		return 0
+	}
+	v, err := parseTTL(t.value)
+	if err != nil {
+		p.err = err
+	}
+	return v
+}
+
+func (p *rdataParser) string() string {
+	t, ok := p.next()
+	if !ok {
+		return ""
+	}
+	return t.value
+}
@@ -0,0 +1,229 @@
+package dns_test
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/detectify/n5/dns"
+	"github.com/detectify/n5/domain"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleZone = `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1 hostmaster (
+		2023010101 ; serial
+		2h         ; refresh
+		30m        ; retry
+		1w         ; expire
+		300 )      ; minimum
+	IN	NS	ns1
+	IN	NS	ns2.example.net.
+	IN	MX	10 mail
+	IN	TXT	"v=spf1 include:_spf.example.net -all"
+ns1	300	IN	A	192.0.2.1
+www	IN	300	AAAA	2001:db8::1
+*.dev		CNAME	www
+_sip._tcp	SRV	10 20 5060 sip
+@	CAA	0 issue "letsencrypt.org"
+null	MX	0 .
+other	TYPE731	\# 4 0a000001
+txt	TXT	"a \"quoted\" string; not a comment" unquoted
+`
+
+func TestParseZone_WithZone_ShouldReturnRecords(t *testing.T) {
+	records, err := dns.ParseZone(strings.NewReader(exampleZone), dns.ZoneOptions{})
+	require.NoError(t, err)
+	require.Len(t, records, 13)
+
+	require.Equal(t, "example.com", records[0].Name.String())
+	require.Equal(t, dns.TypeSOA, records[0].Type())
+	require.Equal(t, uint32(3600), records[0].TTL)
+	require.Equal(t, dns.SOA{
+		MName:   domain.MustParse("ns1.example.com"),
+		RName:   domain.MustParse("hostmaster.example.com"),
+		Serial:  2023010101,
+		Refresh: 7200,
+		Retry:   1800,
+		Expire:  604800,
+		Minimum: 300,
+	}, records[0].Data)
+
+	require.Equal(t, "example.com", records[1].Name.String())
+	require.Equal(t, dns.NS{Host: domain.MustParse("ns1.example.com")}, records[1].Data)
+	require.Equal(t, dns.NS{Host: domain.MustParse("ns2.example.net")}, records[2].Data)
+	require.Equal(t, dns.MX{Preference: 10, Host: domain.MustParse("mail.example.com")}, records[3].Data)
+	require.Equal(t, dns.TXT{Text: []string{"v=spf1 include:_spf.example.net -all"}}, records[4].Data)
+
+	require.Equal(t, "ns1.example.com", records[5].Name.String())
+	require.Equal(t, uint32(300), records[5].TTL)
+	require.Equal(t, dns.A{Addr: netip.MustParseAddr("192.0.2.1")}, records[5].Data)
+
+	require.Equal(t, "www.example.com", records[6].Name.String())
+	require.Equal(t, dns.ClassIN, records[6].Class)
+	require.Equal(t, dns.AAAA{Addr: netip.MustParseAddr("2001:db8::1")}, records[6].Data)
+
+	require.Equal(t, "dev.example.com", records[7].Name.String())
+	require.True(t, records[7].Wildcard)
+	require.Equal(t, "*.dev.example.com.", records[7].Owner())
+	require.Equal(t, dns.CNAME{Target: domain.MustParse("www.example.com")}, records[7].Data)
+
+	require.Equal(t, "_sip._tcp.example.com", records[8].Name.String())
+	require.Equal(t, dns.SRV{Priority: 10, Weight: 20, Port: 5060, Target: domain.MustParse("sip.example.com")},
+		records[8].Data)
+
+	require.Equal(t, dns.CAA{Flags: 0, Tag: "issue", Value: "letsencrypt.org"}, records[9].Data)
+	require.Equal(t, dns.MX{Preference: 0, Host: domain.RootDomain}, records[10].Data)
+	require.Equal(t, dns.Unknown{RRType: 731, Data: []byte{10, 0, 0, 1}}, records[11].Data)
+	require.Equal(t, dns.TXT{Text: []string{`a "quoted" string; not a comment`, "unquoted"}}, records[12].Data)
+}
+
+func TestParseZone_WithRecord_ShouldFormatInMasterFileFormat(t *testing.T) {
+	records, err := dns.ParseZone(strings.NewReader(exampleZone), dns.ZoneOptions{})
+	require.NoError(t, err)
+
+	require.Equal(t, "example.com.\t3600\tIN\tSOA\tns1.example.com. hostmaster.example.com. 2023010101 7200 1800 "+
+		"604800 300", records[0].String())
+	require.Equal(t, "*.dev.example.com.\t3600\tIN\tCNAME\twww.example.com.", records[7].String())
+	require.Equal(t, "null.example.com.\t3600\tIN\tMX\t0 .", records[10].String())
+	require.Equal(t, `other.example.com.`+"\t3600\tIN\tTYPE731\t"+`\# 4 0a000001`, records[11].String())
+	require.Equal(t, `"a \"quoted\" string; not a comment" "unquoted"`, records[12].Data.String())
+}
+
+func TestParseZone_WithOptions_ShouldUseOriginAndTTL(t *testing.T) {
+	records, err := dns.ParseZone(strings.NewReader("www A 192.0.2.1\n"), dns.ZoneOptions{
+		Origin: domain.MustParse("example.org"),
+		TTL:    60,
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "www.example.org", records[0].Name.String())
+	require.Equal(t, uint32(60), records[0].TTL)
+}
+
+func TestParseZone_WithoutTTL_ShouldUsePreviousTTL(t *testing.T) {
+	records, err := dns.ParseZone(strings.NewReader("a.example.com. 120 A 192.0.2.1\nb.example.com. A 192.0.2.2\n"),
+		dns.ZoneOptions{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, uint32(120), records[1].TTL)
+}
+
+func TestParseZone_WithInclude_ShouldReadIncludedFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hosts.db": {Data: []byte("www A 192.0.2.10\n")},
+	}
+	zone := "$ORIGIN example.com.\n$TTL 300\n$INCLUDE hosts.db sub.example.com.\nmail A 192.0.2.20\n"
+	records, err := dns.ParseZone(strings.NewReader(zone), dns.ZoneOptions{Include: fsys})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "www.sub.example.com", records[0].Name.String())
+	require.Equal(t, "mail.example.com", records[1].Name.String())
+}
+
+func TestParseZone_WithDisallowedInclude_ShouldReturnError(t *testing.T) {
+	zone := "$ORIGIN example.com.\n$INCLUDE /etc/passwd\n"
+	_, err := dns.ParseZone(strings.NewReader(zone), dns.ZoneOptions{})
+	require.Error(t, err)
+
+	_, err = dns.ParseZone(strings.NewReader(zone), dns.ZoneOptions{Include: fstest.MapFS{}})
+	require.Error(t, err)
+
+	_, err = dns.ParseZone(strings.NewReader("$INCLUDE ../secret.db\n"), dns.ZoneOptions{Include: fstest.MapFS{}})
+	require.Error(t, err)
+}
+
+func TestParseZone_WithIncludeLoop_ShouldReturnError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"loop.db": {Data: []byte("$INCLUDE loop.db\n")},
+	}
+	_, err := dns.ParseZone(strings.NewReader("$INCLUDE loop.db\n"), dns.ZoneOptions{Include: fsys})
+	require.Error(t, err)
+}
+
+func TestParseZone_WithInvalidEntry_ShouldReturnLineNumber(t *testing.T) {
+	zone := "$ORIGIN example.com.\n$TTL 300\n\n; comment\nwww A 192.0.2.300\n"
+	_, err := dns.ParseZone(strings.NewReader(zone), dns.ZoneOptions{Filename: "example.com.db"})
+	require.Error(t, err)
+
+	var zoneErr *dns.ZoneError
+	require.True(t, errors.As(err, &zoneErr))
+	require.Equal(t, 5, zoneErr.Line)
+	require.Equal(t, "example.com.db", zoneErr.Filename)
+	require.True(t, strings.HasPrefix(err.Error(), "example.com.db:5: "))
+}
+
+func TestParseZone_WithEscapedAndClasslessNames_ShouldKeepLabels(t *testing.T) {
+	zone := `$ORIGIN 2.0.192.in-addr.arpa.
+$TTL 300
+@	SOA	ns1.example.com. john\.doe.example.com. 1 2 3 4 5
+1	CNAME	1.0/25
+0/25	NS	ns1.example.com.
+1.0/25	PTR	host\032one.example.com.
+`
+	records, err := dns.ParseZone(strings.NewReader(zone), dns.ZoneOptions{})
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	require.Equal(t, `john\.doe.example.com`, records[0].Data.(dns.SOA).RName.String())
+	require.Equal(t, "example.com", records[0].Data.(dns.SOA).RName.Apex().String())
+	require.Equal(t, "1.0/25.2.0.192.in-addr.arpa", records[1].Data.(dns.CNAME).Target.String())
+	require.Equal(t, "0/25.2.0.192.in-addr.arpa", records[2].Name.String())
+	require.Equal(t, "1.0/25.2.0.192.in-addr.arpa.\t300\tIN\tPTR\thost\\032one.example.com.", records[3].String())
+}
+
+func TestParseZone_WithRootOrigin_ShouldCompleteRelativeNames(t *testing.T) {
+	zone := `$ORIGIN .
+$TTL 300
+example.com	NS	ns1.example.com
+www.example.com	CNAME	example.com
+$ORIGIN example.org
+www	A	192.0.2.1
+`
+	records, err := dns.ParseZone(strings.NewReader(zone), dns.ZoneOptions{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	require.Equal(t, "example.com", records[0].Name.String())
+	require.Equal(t, "ns1.example.com", records[0].Data.(dns.NS).Host.String())
+	require.Equal(t, "example.com", records[1].Data.(dns.CNAME).Target.String())
+	require.Equal(t, "www.example.org", records[2].Name.String())
+}
+
+func TestParseZone_WithInvalidZone_ShouldReturnError(t *testing.T) {
+	for _, zone := range []string{
+		"www.example.com. A 192.0.2.1\n",                   // no TTL
+		"$TTL 300\nwww A 192.0.2.1\n",                      // relative name without origin
+		"$TTL 300\nwww.example.com. A 2001:db8::1\n",       // IPv6 in A record
+		"$TTL 300\nwww.example.com. FOO bar\n",             // unknown type
+		"$TTL 300\nwww.example.com. MX mail.example.com.",  // missing preference
+		"$TTL 300\nwww.example.com. A 192.0.2.1 extra\n",   // extra field
+		"$TTL 300\nwww.example.com. TXT \"unterminated\n",  // unterminated string
+		"$TTL 300\nwww.example.com. SOA ( a. b. 1 2 3 4\n", // unbalanced parentheses
+		"$TTL 300\nwww..example.com. A 192.0.2.1\n",        // empty label
+		"$TTL 300\n  A 192.0.2.1\n",                        // no previous owner
+		"$TTL 300\nwww.example.com. \\# 1 00\n",            // missing type
+		"$TTL 300\nwww.example.com. TYPE99 \\# 2 00\n",     // wrong length
+		"$GENERATE 1-10 host$ A 192.0.2.$\n",               // unsupported directive
+	} {
+		_, err := dns.ParseZone(strings.NewReader(zone), dns.ZoneOptions{})
+		require.Error(t, err, zone)
+	}
+}
+
+func TestParseType_WithMnemonic_ShouldReturnType(t *testing.T) {
+	rrType, err := dns.ParseType("aaaa")
+	require.NoError(t, err)
+	require.Equal(t, dns.TypeAAAA, rrType)
+
+	rrType, err = dns.ParseType("TYPE65280")
+	require.NoError(t, err)
+	require.Equal(t, dns.Type(65280), rrType)
+	require.Equal(t, "TYPE65280", rrType.String())
+
+	_, err = dns.ParseType("TYPE65536")
+	require.Error(t, err)
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseEscaped parses the domain name in the presentation format of DNS master files (RFC 1035 section 5.1), where a
+// label may contain any octet, such as the mailbox john\.doe.example.com, the RFC 2317 name 0/25.2.0.192.in-addr.arpa
+// or \000.example.com
+//
+// Names accepted by Parse, other than wildcards, are parsed by Parse. Other names are not converted to IDN format, and
+// their labels are held in presentation format, with letters in lower case and escape sequences in canonical form,
+// i.e. "\X" for the special characters .\"();@$ and "\DDD" for spaces and non-printable octets. The public suffix is
+// determined by the trailing labels which are valid host name labels. Returns an error if the name is empty, has an
+// empty label, invalid escape sequence or exceeds the length limits.
+func ParseEscaped(s string) (Name, error) {
+	if !strings.Contains(s, `\`) && !strings.HasPrefix(s, "*.") && !strings.HasPrefix(s, "@.") {
+		if n, err := Parse(s); err == nil {
+			return n, nil
+		}
+	}
+	labels, err := splitEscaped(s)
+	if err != nil {
+		return Name{}, fmt.Errorf("domain name %s is invalid: %w", s, err)
+	}
+
+	var n Name
+	for i := range labels {
+		if !isHostLabels(labels[i:]) {
+			continue
+		}
+		if suffix, err := Parse(strings.Join(labels[i:], ".")); err == nil {
+			n.labels, n.category = suffix.labels, suffix.category
+			labels = labels[:i]
+			break
+		}
+	}
+	escaped := make([]string, 0, len(labels)+len(n.labels))
+	for _, l := range labels {
+		escaped = append(escaped, escapeLabel(l))
+	}
+	n.labels = append(escaped, n.labels...)
+	return n, nil
+}
+
+// splitEscaped splits the name in presentation format into its decoded labels in lower case
+func splitEscaped(s string) ([]string, error) {
+	name := s
+	if trimmed := strings.TrimSuffix(name, "."); len(trimmed) < len(name) {
+		// the final period ends the name, unless it is escaped by an odd number of backslashes
+		if (len(trimmed)-len(strings.TrimRight(trimmed, `\`)))%2 == 0 {
+			name = trimmed
+		}
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf("domain name is empty")
+	}
+	var labels []string
+	var label []byte
+	length := 1
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			switch {
+			case len(label) == 0:
+				return nil, fmt.Errorf("empty label at offset %d", i)
+			case len(label) > 63:
+				return nil, fmt.Errorf("byte length of label is %d, can't exceed 63", len(label))
+			}
+			length += len(label) + 1
+			labels = append(labels, string(label))
+			label = label[:0]
+			continue
+		}
+		c := name[i]
+		if c == '\\' {
+			switch {
+			case i+1 == len(name):
+				return nil, fmt.Errorf("incomplete escape sequence at offset %d", i)
+			case name[i+1] < '0' || name[i+1] > '9':
+				c = name[i+1]
+				i++
+			case i+4 <= len(name):
+				v, err := strconv.ParseUint(name[i+1:i+4], 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("invalid escape sequence at offset %d", i)
+				}
+				c = byte(v)
+				i += 3
+			default:
+				return nil, fmt.Errorf("incomplete escape sequence at offset %d", i)
+			}
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		label = append(label, c)
+	}
+	if length > 255 {
+		return nil, fmt.Errorf("wire length is %d, can't exceed 255", length)
+	}
+	return labels, nil
+}
+
+// isHostLabels returns whether the decoded labels are valid host name labels
+func isHostLabels(labels []string) bool {
+	for _, l := range labels {
+		if l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(l); i++ {
+			if c := l[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// escapeLabel returns the decoded label in canonical presentation format
+func escapeLabel(l string) string {
+	var b strings.Builder
+	for i := 0; i < len(l); i++ {
+		switch c := l[i]; {
+		case strings.IndexByte(`.\"();@$`, c) >= 0:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c <= ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/detectify/n5/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEscaped_WithEscapedNames_ShouldKeepLabels(t *testing.T) {
+	for s, expected := range map[string]string{
+		`john\.doe.example.com.`:     `john\.doe.example.com`,
+		`0/25.2.0.192.in-addr.arpa.`: `0/25.2.0.192.in-addr.arpa`,
+		`\000.example.com.`:          `\000.example.com`,
+		`\065b\099.Example.com`:      `abc.example.com`,
+		`a\ b.example.com`:           `a\032b.example.com`,
+		`a\\.example.com`:            `a\\.example.com`,
+		`example.com\.`:              `example.com\.`,
+		`www.example.com.`:           `www.example.com`,
+		`пример.мкд`:                 `xn--e1afmkfd.xn--d1alf`,
+		`*.example.com`:              `*.example.com`,
+	} {
+		n, err := domain.ParseEscaped(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, n.String(), s)
+	}
+}
+
+func TestParseEscaped_WithEscapedSubdomain_ShouldKeepPublicSuffix(t *testing.T) {
+	n, err := domain.ParseEscaped(`john\.doe.example.co.uk.`)
+	require.NoError(t, err)
+	require.Equal(t, "example.co.uk", n.Apex().String())
+	require.Equal(t, "co.uk", n.EffectiveTLD())
+	require.Equal(t, `john\.doe`, n.Subdomain())
+	require.True(t, n.IsICANN())
+	require.True(t, domain.MustParse("example.co.uk").IsParentOf(n))
+}
+
+func TestParseEscaped_WithInvalidNames_ShouldReturnError(t *testing.T) {
+	for _, s := range []string{"", ".", "a..example.com", `a\`, `a\25`, `a\256.example.com`,
+		"a" + string(make([]byte, 64)) + ".com"} {
+		_, err := domain.ParseEscaped(s)
+		require.Error(t, err, s)
+	}
+}
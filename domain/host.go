@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/detectify/n5/ip"
+)
+
+// Host holds a host, which is either a domain name or an IP address, with an optional port
+//
+// The zero value is an invalid host without name or address.
+type Host struct {
+	name    Name
+	addr    netip.Addr
+	port    uint16
+	hasPort bool
+}
+
+// ParseHost parses the specified host, which is a domain name (possibly internationalized), an IPv4 address or an
+// IPv6 address (possibly in brackets), optionally followed by a port, such as "www.example.com:8080",
+// "192.0.2.1" or "[2001:db8::1]:443"
+//
+// Domain names are parsed as by Parse. A port requires brackets around an IPv6 address, whose zone may be introduced by
+// %25 as in URLs, such as "[fe80::1%25eth0]:80".
+// Returns an error if the host is empty or invalid, or the port is invalid.
+func ParseHost(s string) (Host, error) {
+	host, port := strings.TrimSpace(s), ""
+	switch {
+	case strings.HasPrefix(host, "["):
+		end := strings.Index(host, "]")
+		if end < 0 {
+			return Host{}, fmt.Errorf("host %s is invalid: missing closing bracket", s)
+		}
+		rest := host[end+1:]
+		if len(rest) > 0 {
+			if rest[0] != ':' {
+				return Host{}, fmt.Errorf("host %s is invalid: unexpected %s after address", s, rest)
+			}
+			port = rest[1:]
+		}
+		host = host[1:end]
+		if before, zone, ok := strings.Cut(host, "%25"); ok && len(zone) > 0 {
+			// the zone of an IPv6 address in the authority of a URL is introduced by an encoded percent sign, as
+			// specified in RFC 6874
+			if unescaped, err := url.PathUnescape(zone); err == nil {
+				host = before + "%" + unescaped
+			}
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !addr.Is6() {
+			return Host{}, fmt.Errorf("host %s is invalid: %s is not an IPv6 address", s, host)
+		}
+		return hostWithPort(s, Host{addr: addr}, port, len(rest) > 0)
+	case strings.Count(host, ":") > 1:
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return Host{}, fmt.Errorf("host %s is invalid: %w", s, err)
+		}
+		return Host{addr: addr}, nil
+	}
+
+	host, port, hasPort := strings.Cut(host, ":")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return hostWithPort(s, Host{addr: addr}, port, hasPort)
+	}
+	name, err := Parse(host)
+	if err != nil {
+		return Host{}, fmt.Errorf("host %s is invalid: %w", s, err)
+	}
+	return hostWithPort(s, Host{name: name}, port, hasPort)
+}
+
+func hostWithPort(s string, h Host, port string, hasPort bool) (Host, error) {
+	if !hasPort {
+		return h, nil
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return Host{}, fmt.Errorf("host %s is invalid: invalid port %s", s, port)
+	}
+	h.port, h.hasPort = uint16(p), true
+	return h, nil
+}
+
+// MustParseHost parses the specified host
+//
+// Panics if the host is empty or invalid, or the port is invalid.
+func MustParseHost(s string) Host {
+	result, err := ParseHost(s)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// HostFromName returns the host of the domain name, without port
+func HostFromName(name Name) Host {
+	return Host{name: name}
+}
+
+// HostFromAddr returns the host of the IP address, without port
+func HostFromAddr(addr netip.Addr) Host {
+	return Host{addr: addr}
+}
+
+// WithPort returns the host with the port
+func (h Host) WithPort(port uint16) Host {
+	h.port, h.hasPort = port, true
+	return h
+}
+
+// WithoutPort returns the host without port
+func (h Host) WithoutPort() Host {
+	h.port, h.hasPort = 0, false
+	return h
+}
+
+// IsName returns whether the host is a domain name
+func (h Host) IsName() bool {
+	return len(h.name.labels) > 0
+}
+
+// IsAddr returns whether the host is an IP address
+func (h Host) IsAddr() bool {
+	return h.addr.IsValid()
+}
+
+// Name returns the domain name of the host, or the root domain if the host is an IP address
+func (h Host) Name() Name {
+	return h.name
+}
+
+// Addr returns the IP address of the host, or an invalid address if the host is a domain name
+func (h Host) Addr() netip.Addr {
+	return h.addr
+}
+
+// Port returns the port of the host, and whether the host has a port
+func (h Host) Port() (uint16, bool) {
+	return h.port, h.hasPort
+}
+
+// Hostname returns the domain name or IP address of the host, without brackets and port
+func (h Host) Hostname() string {
+	if h.IsAddr() {
+		return h.addr.String()
+	}
+	return h.name.String()
+}
+
+// String returns the host in the form of the authority of a URL, i.e. with brackets around an IPv6 address and the
+// port, if any, such as "[2001:db8::1]:443"
+//
+// The zone of an IPv6 address is percent-encoded and introduced by %25, as specified in RFC 6874, such as
+// "[fe80::1%25eth0]:80".
+func (h Host) String() string {
+	s := h.Hostname()
+	if h.addr.Is6() {
+		s = h.addr.WithZone("").String()
+		if zone := h.addr.Zone(); len(zone) > 0 {
+			s += "%25" + escapeZone(zone)
+		}
+		s = "[" + s + "]"
+	}
+	if h.hasPort {
+		s += ":" + strconv.Itoa(int(h.port))
+	}
+	return s
+}
+
+// escapeZone percent-encodes the characters of the zone which are not unreserved, as specified in RFC 6874
+func escapeZone(zone string) string {
+	var sb strings.Builder
+	for i := 0; i < len(zone); i++ {
+		if c := zone[i]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("-._~", c) >= 0 {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// IsPublic returns whether the host is reachable on the Internet by name or address, i.e. a domain name under a public
+// suffix or a non-reserved IP address
+//
+// See Name.HasPublicSuffix and ip.IsReservedAddr.
+func (h Host) IsPublic() bool {
+	if h.IsAddr() {
+		return !ip.IsReservedAddr(h.addr)
+	}
+	return h.name.HasPublicSuffix()
+}
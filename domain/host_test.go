@@ -0,0 +1,110 @@
+package domain_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHost_WithName_ShouldReturnName(t *testing.T) {
+	h, err := domain.ParseHost("WWW.Example.com")
+	require.NoError(t, err)
+	require.True(t, h.IsName())
+	require.False(t, h.IsAddr())
+	require.Equal(t, "www.example.com", h.Name().String())
+	require.Equal(t, "www.example.com", h.String())
+	_, hasPort := h.Port()
+	require.False(t, hasPort)
+
+	h, err = domain.ParseHost("пример.мкд:8443")
+	require.NoError(t, err)
+	require.Equal(t, "xn--e1afmkfd.xn--d1alf", h.Hostname())
+	require.Equal(t, "xn--e1afmkfd.xn--d1alf:8443", h.String())
+	port, hasPort := h.Port()
+	require.True(t, hasPort)
+	require.Equal(t, uint16(8443), port)
+}
+
+func TestParseHost_WithAddr_ShouldReturnAddr(t *testing.T) {
+	h, err := domain.ParseHost("192.0.2.1:80")
+	require.NoError(t, err)
+	require.True(t, h.IsAddr())
+	require.False(t, h.IsName())
+	require.Equal(t, netip.MustParseAddr("192.0.2.1"), h.Addr())
+	require.Equal(t, "192.0.2.1:80", h.String())
+
+	for s, expected := range map[string]string{
+		"2001:DB8::1":        "[2001:db8::1]",
+		"[2001:db8::1]":      "[2001:db8::1]",
+		"[2001:db8::1]:443":  "[2001:db8::1]:443",
+		" [::ffff:1.2.3.4] ": "[::ffff:1.2.3.4]",
+	} {
+		h, err := domain.ParseHost(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, h.String(), s)
+	}
+	require.Equal(t, "2001:db8::1", domain.MustParseHost("[2001:db8::1]:443").Hostname())
+}
+
+func TestParseHost_WithZone_ShouldEscapeIt(t *testing.T) {
+	for s, expected := range map[string]string{
+		"fe80::1%eth0":             "[fe80::1%25eth0]",
+		"[fe80::1%eth0]:80":        "[fe80::1%25eth0]:80",
+		"[fe80::1%25eth0]:80":      "[fe80::1%25eth0]:80",
+		"[fe80::1%25en%2F1]":       "[fe80::1%25en%2F1]",
+		"[fe80::1%25a:b]:8080":     "[fe80::1%25a%3Ab]:8080",
+		"[fe80::1%25%5Bx%5D]:8080": "[fe80::1%25%5Bx%5D]:8080",
+	} {
+		h, err := domain.ParseHost(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, h.String(), s)
+		require.Equal(t, h, domain.MustParseHost(h.String()), s)
+	}
+	require.Equal(t, "fe80::1%eth0", domain.MustParseHost("[fe80::1%25eth0]:80").Hostname())
+	require.Equal(t, "[fe80::1%25eth0]", domain.HostFromAddr(netip.MustParseAddr("fe80::1%eth0")).String())
+}
+
+func TestParseHost_WithInvalidHost_ShouldReturnError(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"[2001:db8::1",
+		"[192.0.2.1]",
+		"[2001:db8::1]443",
+		"[2001:db8::1]:",
+		"example.com:65536",
+		"example.com:http",
+		"2001:db8::g",
+		"exa mple.com",
+		"127.1",
+	} {
+		_, err := domain.ParseHost(s)
+		require.Error(t, err, s)
+	}
+	require.Panics(t, func() { domain.MustParseHost("") })
+}
+
+func TestHost_WithPort_ShouldChangePort(t *testing.T) {
+	h := domain.HostFromAddr(netip.MustParseAddr("2001:db8::1")).WithPort(8080)
+	require.Equal(t, "[2001:db8::1]:8080", h.String())
+	require.Equal(t, "[2001:db8::1]", h.WithoutPort().String())
+	require.Equal(t, "example.com:443", domain.HostFromName(domain.MustParse("example.com")).WithPort(443).String())
+}
+
+func TestHost_IsPublic_ShouldCheckReservedAndPublicSuffix(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"www.example.com":    true,
+		"example.co.uk:443":  true,
+		"localhost":          false,
+		"intranet.corp":      false,
+		"8.8.8.8":            true,
+		"10.0.0.1:8080":      false,
+		"[::1]":              false,
+		"2606:4700::1111":    true,
+		"[::ffff:127.0.0.1]": false,
+	} {
+		require.Equal(t, expected, domain.MustParseHost(s).IsPublic(), s)
+	}
+	require.False(t, domain.Host{}.IsPublic())
+}
@@ -41,3 +41,59 @@ func Resolves(domain string) bool {
 	}
 	return len(ips) > 0
 }
+
+// ResolvePTR performs a reverse DNS (PTR) lookup of the IP address, and returns the forward-confirmed names
+//
+// Forward-confirmed reverse DNS (FCrDNS) requires each name to resolve back to the IP address, names failing the
+// check are dropped. Returns an error if the lookup fails, or none of the names could be forward-confirmed.
+func ResolvePTR(addr net.IP) ([]Name, error) {
+	names, err := LookupPTR(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var confirmed []Name
+	for _, n := range names {
+		ips, err := net.LookupIP(n.String())
+		if err != nil {
+			continue
+		}
+		for _, i := range ips {
+			if i.Equal(addr) {
+				confirmed = append(confirmed, n)
+				break
+			}
+		}
+	}
+	if len(confirmed) == 0 {
+		return nil, fmt.Errorf("no forward-confirmed name for IP: %s", addr.String())
+	}
+	return confirmed, nil
+}
+
+// LookupPTR performs a reverse DNS (PTR) lookup of the IP address, and returns the names without confirming them
+//
+// Returns an error if the IP address is invalid, the lookup fails or no valid names are returned.
+func LookupPTR(addr net.IP) ([]Name, error) {
+	if ip.ReverseName(addr) == "" {
+		return nil, fmt.Errorf("%s is not an IP address", addr.String())
+	}
+
+	ptrs, err := net.LookupAddr(addr.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup PTR for IP: %w", err)
+	}
+
+	var names []Name
+	for _, p := range ptrs {
+		n, err := Parse(p)
+		if err != nil {
+			continue
+		}
+		names = append(names, n)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no valid PTR names for IP: %s", addr.String())
+	}
+	return names, nil
+}
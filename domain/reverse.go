@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/detectify/n5/ip"
+)
+
+// ReverseName returns the domain name used for reverse DNS (PTR) lookup of the specified IP address
+//
+// IPv4 addresses map to the in-addr.arpa domain, IPv6 addresses to the ip6.arpa domain.
+// Returns an error if the IP address is invalid.
+func ReverseName(addr net.IP) (Name, error) {
+	name := ip.ReverseName(addr)
+	if len(name) == 0 {
+		return Name{}, fmt.Errorf("%s is not an IP address", addr.String())
+	}
+	return Parse(name)
+}
+
+// ReverseNameAddr returns the domain name used for reverse DNS (PTR) lookup of the specified IP address
+//
+// Returns an error if the IP address is invalid.
+func ReverseNameAddr(addr netip.Addr) (Name, error) {
+	name := ip.ReverseNameAddr(addr)
+	if len(name) == 0 {
+		return Name{}, fmt.Errorf("%s is not an IP address", addr.String())
+	}
+	return Parse(name)
+}
+
+// ReverseZones returns the reverse DNS zone names covering the specified prefix
+//
+// Returns an error if the prefix is invalid.
+func ReverseZones(prefix netip.Prefix) ([]Name, error) {
+	zones := ip.ReverseZones(prefix)
+	if zones == nil {
+		return nil, fmt.Errorf("%s is not a valid prefix", prefix.String())
+	}
+
+	names := make([]Name, 0, len(zones))
+	for _, z := range zones {
+		name, err := Parse(z)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// ReversePrefix returns the IP prefix represented by the in-addr.arpa or ip6.arpa domain name
+//
+// A complete reverse DNS name results in a single address prefix (/32 or /128), a reverse zone in a shorter prefix.
+// Returns an error if the domain name is not a reverse DNS name.
+func (n Name) ReversePrefix() (netip.Prefix, error) {
+	return ip.ParseReverseName(n.String())
+}
+
+// IsReverse returns whether the domain name is in the in-addr.arpa or ip6.arpa reverse DNS domain
+func (n Name) IsReverse() bool {
+	_, err := n.ReversePrefix()
+	return err == nil
+}
@@ -0,0 +1,47 @@
+package domain_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseName_WithIP_ShouldReturnName(t *testing.T) {
+	name, err := domain.ReverseName(net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	require.Equal(t, "1.2.0.192.in-addr.arpa", name.String())
+	require.Equal(t, "in-addr.arpa", name.EffectiveTLD())
+	require.True(t, name.IsReverse())
+
+	name, err = domain.ReverseNameAddr(netip.MustParseAddr("2001:db8::1"))
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa", name.String())
+	require.Equal(t, "ip6.arpa", name.EffectiveTLD())
+
+	_, err = domain.ReverseName(nil)
+	require.Error(t, err)
+}
+
+func TestReverseZones_WithPrefix_ShouldReturnNames(t *testing.T) {
+	names, err := domain.ReverseZones(netip.MustParsePrefix("198.51.100.0/23"))
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+	require.Equal(t, "100.51.198.in-addr.arpa", names[0].String())
+	require.Equal(t, "101.51.198.in-addr.arpa", names[1].String())
+
+	_, err = domain.ReverseZones(netip.Prefix{})
+	require.Error(t, err)
+}
+
+func TestReversePrefix_WithReverseName_ShouldReturnPrefix(t *testing.T) {
+	prefix, err := domain.MustParse("2.0.192.in-addr.arpa").ReversePrefix()
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), prefix)
+
+	_, err = domain.MustParse("www.example.com").ReversePrefix()
+	require.Error(t, err)
+	require.False(t, domain.MustParse("www.example.com").IsReverse())
+}
@@ -0,0 +1,152 @@
+package email
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// DKIM key types, as specified in RFC 6376 and RFC 8463
+const (
+	DKIMKeyRSA     = "rsa"
+	DKIMKeyEd25519 = "ed25519"
+)
+
+// DKIM holds a DomainKeys Identified Mail public key record, as specified in RFC 6376 section 3.6.1
+type DKIM struct {
+	// KeyType is the type of the key (k), defaults to DKIMKeyRSA
+	KeyType string
+	// HashAlgorithms are the acceptable hash algorithms (h), empty if all are allowed
+	HashAlgorithms []string
+	// PublicKey is the decoded public key (p), which is empty when the key is revoked
+	PublicKey []byte
+	// ServiceTypes are the service types the key applies to (s), defaults to "*"
+	ServiceTypes []string
+	// Flags are the flags of the key (t), such as "y" for testing
+	Flags []string
+	// Notes are notes for humans (n)
+	Notes string
+}
+
+// IsRevoked returns whether the key is revoked, i.e. the public key is empty
+func (k DKIM) IsRevoked() bool {
+	return len(k.PublicKey) == 0
+}
+
+// IsTesting returns whether the domain is testing DKIM, i.e. has the "y" flag
+func (k DKIM) IsTesting() bool {
+	for _, f := range k.Flags {
+		if f == "y" {
+			return true
+		}
+	}
+	return false
+}
+
+// KeySize returns the size of the public key in bits
+//
+// Returns an error if the key is revoked, or can't be decoded.
+func (k DKIM) KeySize() (int, error) {
+	if k.IsRevoked() {
+		return 0, errors.New("DKIM key is revoked")
+	}
+
+	switch k.KeyType {
+	case DKIMKeyRSA:
+		key, err := x509.ParsePKIXPublicKey(k.PublicKey)
+		if err != nil {
+			// some keys are published in the RSAPublicKey format instead of SubjectPublicKeyInfo
+			if key, err = x509.ParsePKCS1PublicKey(k.PublicKey); err != nil {
+				return 0, fmt.Errorf("invalid RSA key: %w", err)
+			}
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return 0, errors.New("invalid RSA key: not an RSA key")
+		}
+		return rsaKey.N.BitLen(), nil
+	case DKIMKeyEd25519:
+		if len(k.PublicKey) != ed25519.PublicKeySize {
+			return 0, errors.New("invalid Ed25519 key: wrong size")
+		}
+		return ed25519.PublicKeySize * 8, nil
+	default:
+		return 0, fmt.Errorf("unsupported key type %s", k.KeyType)
+	}
+}
+
+// ParseDKIM parses a DKIM public key record
+//
+// Unknown tags are ignored, as specified. Returns an error if the version is not "DKIM1", the public key is missing or
+// any tag value is invalid.
+func ParseDKIM(record string) (DKIM, error) {
+	tags, err := parseTags(record)
+	if err != nil {
+		return DKIM{}, fmt.Errorf("invalid DKIM record: %w", err)
+	}
+
+	k := DKIM{KeyType: DKIMKeyRSA, ServiceTypes: []string{"*"}}
+	hasKey := false
+	for i, t := range tags {
+		switch t.name {
+		case "v":
+			if i != 0 || t.value != "DKIM1" {
+				return DKIM{}, fmt.Errorf("invalid DKIM version %s", t.value)
+			}
+		case "h":
+			k.HashAlgorithms = splitList(t.value, ":")
+		case "k":
+			k.KeyType = strings.ToLower(t.value)
+		case "n":
+			k.Notes = t.value
+		case "p":
+			hasKey = true
+			// base64 may be split by whitespace
+			key := strings.Join(strings.Fields(t.value), "")
+			if k.PublicKey, err = base64.StdEncoding.DecodeString(key); err != nil {
+				return DKIM{}, fmt.Errorf("invalid DKIM public key: %w", err)
+			}
+		case "s":
+			k.ServiceTypes = splitList(t.value, ":")
+		case "t":
+			k.Flags = splitList(t.value, ":")
+		}
+	}
+	if !hasKey {
+		return DKIM{}, errors.New("invalid DKIM record: missing public key")
+	}
+	return k, nil
+}
+
+// LookupDKIM looks up and parses the DKIM public key record of the selector for the domain name, i.e. the record at
+// "<selector>._domainkey.<name>"
+//
+// The resolver defaults to DefaultResolver. Returns ErrNoRecord if there is no record, and ErrMultipleRecords if
+// there is more than one.
+func LookupDKIM(ctx context.Context, r Resolver, selector string, name domain.Name) (DKIM, error) {
+	recordName, err := prefixName(selector+"._domainkey", name)
+	if err != nil {
+		return DKIM{}, err
+	}
+	record, err := lookupRecord(ctx, r, recordName.String(), isDKIM)
+	if err != nil {
+		return DKIM{}, err
+	}
+	return ParseDKIM(record)
+}
+
+// isDKIM returns whether the TXT record may be a DKIM record, which does not require the version tag
+func isDKIM(record string) bool {
+	name, _, ok := strings.Cut(record, "=")
+	if !ok {
+		return false
+	}
+	return strings.TrimSpace(name) != "v" || hasVersionTag(record, "DKIM1")
+}
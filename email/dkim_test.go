@@ -0,0 +1,65 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/detectify/n5/domain"
+	"github.com/detectify/n5/email"
+	"github.com/stretchr/testify/require"
+)
+
+const testDKIMKey = "MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQCi1sc9wV0s4MPOFPO4uQWl/Hp+LYwug+gLPtVRENDEzUAPVb5GuQFRGki4W" +
+	"Vuw4ufq14n7CEwu/Oy4kPcRx4xX+AvSECFHOsiQOX6u5xaztemHVCH2+Gv/0B957YbGdA10yCStTSxilHragNfySvgdCG1DBCCRv0AD3abmkKw" +
+	"PfwIDAQAB"
+
+func TestParseDKIM_WithValidRecord_ShouldReturnKey(t *testing.T) {
+	k, err := email.ParseDKIM("v=DKIM1; k=rsa; h=sha256; t=y:s; p=" + testDKIMKey[:40] + " " + testDKIMKey[40:])
+	require.NoError(t, err)
+	require.Equal(t, email.DKIMKeyRSA, k.KeyType)
+	require.Equal(t, []string{"sha256"}, k.HashAlgorithms)
+	require.Equal(t, []string{"*"}, k.ServiceTypes)
+	require.True(t, k.IsTesting())
+	require.False(t, k.IsRevoked())
+	size, err := k.KeySize()
+	require.NoError(t, err)
+	require.Equal(t, 1024, size)
+
+	k, err = email.ParseDKIM("k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=")
+	require.NoError(t, err)
+	size, err = k.KeySize()
+	require.NoError(t, err)
+	require.Equal(t, 256, size)
+
+	k, err = email.ParseDKIM("v=DKIM1; p=")
+	require.NoError(t, err)
+	require.True(t, k.IsRevoked())
+	_, err = k.KeySize()
+	require.Error(t, err)
+}
+
+func TestParseDKIM_WithInvalidRecord_ShouldReturnError(t *testing.T) {
+	for _, record := range []string{
+		"",
+		"v=DKIM1; k=rsa",
+		"k=rsa; v=DKIM1; p=",
+		"v=DKIM2; p=",
+		"v=DKIM1; p=not base64!",
+		"v=DKIM1; p=; p=",
+	} {
+		_, err := email.ParseDKIM(record)
+		require.Error(t, err, record)
+	}
+}
+
+func TestLookupDKIM_WithSelector_ShouldReturnKey(t *testing.T) {
+	r := testResolver{"selector1._domainkey.example.com": {"v=DKIM1; p=" + testDKIMKey}}
+
+	k, err := email.LookupDKIM(context.Background(), r, "selector1", domain.MustParse("example.com"))
+	require.NoError(t, err)
+	require.False(t, k.IsRevoked())
+
+	_, err = email.LookupDKIM(context.Background(), r, "selector2", domain.MustParse("example.com"))
+	require.True(t, errors.Is(err, email.ErrNoRecord))
+}
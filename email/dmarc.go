@@ -0,0 +1,295 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/detectify/n5/domain"
+)
+
+// DMARCPolicy is the policy requested for messages failing DMARC authentication
+type DMARCPolicy string
+
+// DMARC policies, as specified in RFC 7489 section 6.3
+const (
+	DMARCPolicyNone       DMARCPolicy = "none"
+	DMARCPolicyQuarantine DMARCPolicy = "quarantine"
+	DMARCPolicyReject     DMARCPolicy = "reject"
+)
+
+// Alignment is the identifier alignment mode of DMARC
+type Alignment string
+
+// DMARC alignment modes, as specified in RFC 7489 section 3.1
+const (
+	AlignmentRelaxed Alignment = "r"
+	AlignmentStrict  Alignment = "s"
+)
+
+// ReportURI is a destination for DMARC reports
+type ReportURI struct {
+	// URI is the destination, usually a "mailto:" URI
+	URI *url.URL
+	// MaxSize is the maximum size of reports sent to the destination in bytes, 0 if not limited
+	MaxSize uint64
+}
+
+// Domain returns the domain of the email address of a "mailto:" destination
+//
+// Returns an error if the destination is not an email address, or the domain is invalid.
+func (u ReportURI) Domain() (domain.Name, error) {
+	if u.URI == nil || u.URI.Scheme != "mailto" {
+		return domain.Name{}, errors.New("report URI is not a mailto URI")
+	}
+	_, host, ok := strings.Cut(u.URI.Opaque, "@")
+	if !ok {
+		return domain.Name{}, fmt.Errorf("invalid email address %s", u.URI.Opaque)
+	}
+	return domain.Parse(host)
+}
+
+// String returns the destination as in the record
+func (u ReportURI) String() string {
+	s := u.URI.String()
+	if u.MaxSize > 0 {
+		s += "!" + strconv.FormatUint(u.MaxSize, 10)
+	}
+	return s
+}
+
+// DMARC holds a Domain-based Message Authentication, Reporting and Conformance record, as specified in RFC 7489
+type DMARC struct {
+	// Policy is the policy for the domain (p)
+	Policy DMARCPolicy
+	// SubdomainPolicy is the policy for subdomains (sp), defaults to Policy
+	SubdomainPolicy DMARCPolicy
+	// Percent is the percentage of messages the policy is applied to (pct), defaults to 100
+	Percent int
+	// DKIMAlignment is the DKIM alignment mode (adkim), defaults to relaxed
+	DKIMAlignment Alignment
+	// SPFAlignment is the SPF alignment mode (aspf), defaults to relaxed
+	SPFAlignment Alignment
+	// AggregateReports are the destinations of aggregate reports (rua)
+	AggregateReports []ReportURI
+	// FailureReports are the destinations of failure reports (ruf)
+	FailureReports []ReportURI
+	// FailureOptions are the failure reporting options (fo), defaults to "0"
+	FailureOptions []string
+	// ReportFormats are the formats of failure reports (rf), defaults to "afrf"
+	ReportFormats []string
+	// ReportInterval is the interval between aggregate reports (ri), defaults to a day
+	ReportInterval time.Duration
+}
+
+// IsDMARC returns whether the TXT record is a DMARC record, i.e. starts with "v=DMARC1"
+func IsDMARC(record string) bool {
+	return hasVersionTag(record, "DMARC1")
+}
+
+// ParseDMARC parses a DMARC record
+//
+// Unknown tags are ignored, as specified. Returns an error if the record is not a DMARC record, the policy is
+// missing or any tag value is invalid.
+func ParseDMARC(record string) (DMARC, error) {
+	tags, err := parseTags(record)
+	if err != nil {
+		return DMARC{}, fmt.Errorf("invalid DMARC record: %w", err)
+	}
+	if len(tags) == 0 || tags[0].name != "v" || tags[0].value != "DMARC1" {
+		return DMARC{}, errors.New("not a DMARC record")
+	}
+
+	d := DMARC{
+		Percent:        100,
+		DKIMAlignment:  AlignmentRelaxed,
+		SPFAlignment:   AlignmentRelaxed,
+		FailureOptions: []string{"0"},
+		ReportFormats:  []string{"afrf"},
+		ReportInterval: 24 * time.Hour,
+	}
+	for _, t := range tags[1:] {
+		if err = d.parseTag(t); err != nil {
+			return DMARC{}, fmt.Errorf("invalid DMARC tag %s: %w", t.name, err)
+		}
+	}
+	if len(d.Policy) == 0 {
+		return DMARC{}, errors.New("invalid DMARC record: missing policy")
+	}
+	if len(d.SubdomainPolicy) == 0 {
+		d.SubdomainPolicy = d.Policy
+	}
+	return d, nil
+}
+
+func (d *DMARC) parseTag(t tag) error {
+	var err error
+	switch t.name {
+	case "p":
+		d.Policy, err = parseDMARCPolicy(t.value)
+	case "sp":
+		d.SubdomainPolicy, err = parseDMARCPolicy(t.value)
+	case "pct":
+		d.Percent, err = strconv.Atoi(t.value)
+		if err != nil || d.Percent < 0 || d.Percent > 100 {
+			err = fmt.Errorf("invalid percentage %s", t.value)
+		}
+	case "adkim":
+		d.DKIMAlignment, err = parseAlignment(t.value)
+	case "aspf":
+		d.SPFAlignment, err = parseAlignment(t.value)
+	case "rua":
+		d.AggregateReports, err = parseReportURIs(t.value)
+	case "ruf":
+		d.FailureReports, err = parseReportURIs(t.value)
+	case "fo":
+		d.FailureOptions = splitList(t.value, ":")
+		for _, o := range d.FailureOptions {
+			if o != "0" && o != "1" && o != "d" && o != "s" {
+				return fmt.Errorf("invalid failure option %s", o)
+			}
+		}
+	case "rf":
+		d.ReportFormats = splitList(t.value, ":")
+	case "ri":
+		var seconds uint32
+		seconds, err = parseUint32(t.value)
+		d.ReportInterval = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+func parseDMARCPolicy(s string) (DMARCPolicy, error) {
+	switch p := DMARCPolicy(strings.ToLower(s)); p {
+	case DMARCPolicyNone, DMARCPolicyQuarantine, DMARCPolicyReject:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid policy %s", s)
+	}
+}
+
+func parseAlignment(s string) (Alignment, error) {
+	switch a := Alignment(strings.ToLower(s)); a {
+	case AlignmentRelaxed, AlignmentStrict:
+		return a, nil
+	default:
+		return "", fmt.Errorf("invalid alignment %s", s)
+	}
+}
+
+func parseUint32(s string) (uint32, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %s", s)
+	}
+	return uint32(n), nil
+}
+
+// parseReportURIs parses a comma separated list of URIs, each with an optional size limit such as "!10m"
+func parseReportURIs(s string) ([]ReportURI, error) {
+	var uris []ReportURI
+	for _, v := range splitList(s, ",") {
+		var u ReportURI
+		if i := strings.LastIndexByte(v, '!'); i >= 0 {
+			var err error
+			if u.MaxSize, err = parseSize(v[i+1:]); err != nil {
+				return nil, err
+			}
+			v = v[:i]
+		}
+
+		var err error
+		if u.URI, err = url.Parse(v); err != nil || len(u.URI.Scheme) == 0 {
+			return nil, fmt.Errorf("invalid URI %s", v)
+		}
+		uris = append(uris, u)
+	}
+	return uris, nil
+}
+
+// parseSize parses a size with an optional unit, such as "10m"
+func parseSize(s string) (uint64, error) {
+	multiplier := uint64(1)
+	if len(s) > 0 {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			multiplier = 1 << 10
+		case 'm', 'M':
+			multiplier = 1 << 20
+		case 'g', 'G':
+			multiplier = 1 << 30
+		case 't', 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n > (1<<64-1)/multiplier {
+		return 0, fmt.Errorf("invalid size %s", s)
+	}
+	return n * multiplier, nil
+}
+
+// LookupDMARC looks up and parses the DMARC record of the domain name
+//
+// Falls back to the record of the organizational domain, i.e. the apex domain, if the name has no record, as
+// specified in RFC 7489 section 6.6.3. Returns the record and the name it was found for. The resolver defaults to
+// DefaultResolver. Returns ErrNoRecord if no DMARC record is found, and ErrMultipleRecords if there is more than one.
+func LookupDMARC(ctx context.Context, r Resolver, name domain.Name) (DMARC, domain.Name, error) {
+	d, err := lookupDMARC(ctx, r, name)
+	if errors.Is(err, ErrNoRecord) && name.IsApexOrSubdomain() && !name.IsApex() {
+		name = name.Apex()
+		d, err = lookupDMARC(ctx, r, name)
+	}
+	if err != nil {
+		return DMARC{}, domain.Name{}, err
+	}
+	return d, name, nil
+}
+
+func lookupDMARC(ctx context.Context, r Resolver, name domain.Name) (DMARC, error) {
+	recordName, err := prefixName("_dmarc", name)
+	if err != nil {
+		return DMARC{}, err
+	}
+	record, err := lookupRecord(ctx, r, recordName.String(), IsDMARC)
+	if err != nil {
+		return DMARC{}, err
+	}
+	return ParseDMARC(record)
+}
+
+// IsReportAuthorized checks whether the destination domain accepts DMARC reports for the domain name, as specified in
+// RFC 7489 section 7.1
+//
+// Reports to a destination outside the organizational domain of the name require a record at
+// "<name>._report._dmarc.<destination>". The resolver defaults to DefaultResolver.
+func IsReportAuthorized(ctx context.Context, r Resolver, name domain.Name, uri ReportURI) (bool, error) {
+	destination, err := uri.Domain()
+	if err != nil {
+		return false, err
+	}
+	if name.Apex().String() == destination.Apex().String() {
+		return true, nil
+	}
+
+	recordName, err := domain.Parse(name.String() + "._report._dmarc." + destination.String())
+	if err != nil {
+		return false, err
+	}
+	_, err = lookupRecord(ctx, r, recordName.String(), IsDMARC)
+	if errors.Is(err, ErrNoRecord) {
+		return false, nil
+	}
+	if errors.Is(err, ErrMultipleRecords) {
+		// any DMARC record authorizes the reports
+		return true, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,79 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/detectify/n5/domain"
+	"github.com/detectify/n5/email"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDMARC_WithValidRecord_ShouldReturnPolicy(t *testing.T) {
+	d, err := email.ParseDMARC("v=DMARC1; p=quarantine; pct=50; adkim=s; " +
+		"rua=mailto:dmarc@example.com!10m,mailto:reports@example.net; ruf=mailto:forensic@example.com; fo=1:d; " +
+		"ri=3600; unknown=tag;")
+	require.NoError(t, err)
+	require.Equal(t, email.DMARCPolicyQuarantine, d.Policy)
+	require.Equal(t, email.DMARCPolicyQuarantine, d.SubdomainPolicy)
+	require.Equal(t, 50, d.Percent)
+	require.Equal(t, email.AlignmentStrict, d.DKIMAlignment)
+	require.Equal(t, email.AlignmentRelaxed, d.SPFAlignment)
+	require.Equal(t, []string{"1", "d"}, d.FailureOptions)
+	require.Equal(t, []string{"afrf"}, d.ReportFormats)
+	require.Equal(t, time.Hour, d.ReportInterval)
+
+	require.Len(t, d.AggregateReports, 2)
+	require.Equal(t, uint64(10<<20), d.AggregateReports[0].MaxSize)
+	require.Equal(t, "mailto:dmarc@example.com!10485760", d.AggregateReports[0].String())
+	name, err := d.AggregateReports[1].Domain()
+	require.NoError(t, err)
+	require.Equal(t, "example.net", name.String())
+	require.Len(t, d.FailureReports, 1)
+}
+
+func TestParseDMARC_WithInvalidRecord_ShouldReturnError(t *testing.T) {
+	for _, record := range []string{
+		"",
+		"v=spf1 -all",
+		"p=reject; v=DMARC1",
+		"v=DMARC1",
+		"v=DMARC1; p=block",
+		"v=DMARC1; p=none; pct=101",
+		"v=DMARC1; p=none; adkim=x",
+		"v=DMARC1; p=none; rua=dmarc@example.com",
+		"v=DMARC1; p=none; rua=mailto:dmarc@example.com!10x",
+		"v=DMARC1; p=none; p=reject",
+	} {
+		_, err := email.ParseDMARC(record)
+		require.Error(t, err, record)
+	}
+}
+
+func TestLookupDMARC_WithSubdomain_ShouldFallBackToOrganizationalDomain(t *testing.T) {
+	r := testResolver{
+		"_dmarc.example.com":                             {"v=DMARC1; p=reject; rua=mailto:dmarc@reports.example.net"},
+		"_dmarc.example.org":                             {"v=DMARC1; p=none", "v=DMARC1; p=reject"},
+		"example.com._report._dmarc.reports.example.net": {"v=DMARC1"},
+	}
+
+	d, name, err := email.LookupDMARC(context.Background(), r, domain.MustParse("mail.example.com"))
+	require.NoError(t, err)
+	require.Equal(t, "example.com", name.String())
+	require.Equal(t, email.DMARCPolicyReject, d.Policy)
+
+	authorized, err := email.IsReportAuthorized(context.Background(), r, name, d.AggregateReports[0])
+	require.NoError(t, err)
+	require.True(t, authorized)
+	authorized, err = email.IsReportAuthorized(context.Background(), r, domain.MustParse("example.co.uk"),
+		d.AggregateReports[0])
+	require.NoError(t, err)
+	require.False(t, authorized)
+
+	_, _, err = email.LookupDMARC(context.Background(), r, domain.MustParse("example.org"))
+	require.True(t, errors.Is(err, email.ErrMultipleRecords))
+	_, _, err = email.LookupDMARC(context.Background(), r, domain.MustParse("www.example.net"))
+	require.True(t, errors.Is(err, email.ErrNoRecord))
+}
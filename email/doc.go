@@ -0,0 +1,2 @@
+// Package email provides helper functions for email security records, such as SPF, DMARC, DKIM and MTA-STS
+package email
@@ -0,0 +1,199 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/detectify/n5/domain"
+)
+
+// MTASTSMaxAge is the maximum lifetime of an MTA-STS policy, as specified in RFC 8461 section 3.2
+const MTASTSMaxAge = 31557600 * time.Second
+
+// MTASTSMode is the mode of an MTA-STS policy
+type MTASTSMode string
+
+// MTA-STS modes, as specified in RFC 8461 section 5
+const (
+	MTASTSModeEnforce MTASTSMode = "enforce"
+	MTASTSModeTesting MTASTSMode = "testing"
+	MTASTSModeNone    MTASTSMode = "none"
+)
+
+// MTASTS holds an MTA Strict Transport Security record, as specified in RFC 8461 section 3.1
+type MTASTS struct {
+	// ID identifies the version of the policy (id)
+	ID string
+}
+
+// IsMTASTS returns whether the TXT record is an MTA-STS record, i.e. starts with "v=STSv1"
+func IsMTASTS(record string) bool {
+	return hasVersionTag(record, "STSv1")
+}
+
+// ParseMTASTS parses an MTA-STS record
+//
+// Returns an error if the record is not an MTA-STS record, or the policy id is missing or invalid.
+func ParseMTASTS(record string) (MTASTS, error) {
+	tags, err := parseTags(record)
+	if err != nil {
+		return MTASTS{}, fmt.Errorf("invalid MTA-STS record: %w", err)
+	}
+	if len(tags) == 0 || tags[0].name != "v" || tags[0].value != "STSv1" {
+		return MTASTS{}, errors.New("not an MTA-STS record")
+	}
+
+	var sts MTASTS
+	for _, t := range tags[1:] {
+		if t.name == "id" {
+			sts.ID = t.value
+		}
+	}
+	if len(sts.ID) == 0 || len(sts.ID) > 32 || strings.IndexFunc(sts.ID, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) >= 0 {
+		return MTASTS{}, fmt.Errorf("invalid MTA-STS policy id %s", sts.ID)
+	}
+	return sts, nil
+}
+
+// LookupMTASTS looks up and parses the MTA-STS record of the domain name, i.e. the record at "_mta-sts.<name>"
+//
+// The resolver defaults to DefaultResolver. Returns ErrNoRecord if there is no record, and ErrMultipleRecords if
+// there is more than one.
+func LookupMTASTS(ctx context.Context, r Resolver, name domain.Name) (MTASTS, error) {
+	recordName, err := prefixName("_mta-sts", name)
+	if err != nil {
+		return MTASTS{}, err
+	}
+	record, err := lookupRecord(ctx, r, recordName.String(), IsMTASTS)
+	if err != nil {
+		return MTASTS{}, err
+	}
+	return ParseMTASTS(record)
+}
+
+// MTASTSPolicyURL returns the URL the MTA-STS policy of the domain name is published at
+func MTASTSPolicyURL(name domain.Name) string {
+	return "https://mta-sts." + name.String() + "/.well-known/mta-sts.txt"
+}
+
+// MXPattern is an MX host pattern of an MTA-STS policy, which may match subdomains with a wildcard
+type MXPattern struct {
+	Name domain.Name
+	// Wildcard indicates the pattern matches the immediate subdomains of the name, e.g. "*.example.com"
+	Wildcard bool
+}
+
+// Matches returns whether the MX host matches the pattern
+func (p MXPattern) Matches(host domain.Name) bool {
+	if p.Wildcard {
+		return host.IsImmediateSubdomainOf(p.Name)
+	}
+	return host.String() == p.Name.String()
+}
+
+// String returns the pattern as in the policy
+func (p MXPattern) String() string {
+	if p.Wildcard {
+		return "*." + p.Name.String()
+	}
+	return p.Name.String()
+}
+
+// MTASTSPolicy holds an MTA-STS policy, as specified in RFC 8461 section 3.2
+type MTASTSPolicy struct {
+	Mode MTASTSMode
+	// MX holds the patterns of the MX hosts allowed to receive email
+	MX []MXPattern
+	// MaxAge is the lifetime of the policy
+	MaxAge time.Duration
+}
+
+// Matches returns whether the MX host is allowed by the policy
+func (p MTASTSPolicy) Matches(host domain.Name) bool {
+	for _, mx := range p.MX {
+		if mx.Matches(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMTASTSPolicy parses an MTA-STS policy, which is fetched from MTASTSPolicyURL
+//
+// Unknown fields are ignored, as specified. Returns an error if the version is not "STSv1", the mode or max age is
+// missing or invalid, or the MX patterns are missing or invalid unless the mode is none.
+func ParseMTASTSPolicy(policy string) (MTASTSPolicy, error) {
+	var p MTASTSPolicy
+	version, maxAge := "", ""
+	scanner := bufio.NewScanner(strings.NewReader(policy))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return MTASTSPolicy{}, fmt.Errorf("invalid MTA-STS policy line %s", line)
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "version":
+			version = value
+		case "mode":
+			p.Mode = MTASTSMode(value)
+		case "max_age":
+			maxAge = value
+		case "mx":
+			mx, err := parseMXPattern(value)
+			if err != nil {
+				return MTASTSPolicy{}, err
+			}
+			p.MX = append(p.MX, mx)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return MTASTSPolicy{}, fmt.Errorf("invalid MTA-STS policy: %w", err)
+	}
+
+	if version != "STSv1" {
+		return MTASTSPolicy{}, fmt.Errorf("invalid MTA-STS policy version %s", version)
+	}
+	switch p.Mode {
+	case MTASTSModeEnforce, MTASTSModeTesting:
+		if len(p.MX) == 0 {
+			return MTASTSPolicy{}, errors.New("invalid MTA-STS policy: missing mx")
+		}
+	case MTASTSModeNone:
+	default:
+		return MTASTSPolicy{}, fmt.Errorf("invalid MTA-STS policy mode %s", p.Mode)
+	}
+
+	seconds, err := strconv.ParseUint(maxAge, 10, 32)
+	if err != nil || time.Duration(seconds)*time.Second > MTASTSMaxAge {
+		return MTASTSPolicy{}, fmt.Errorf("invalid MTA-STS policy max age %s", maxAge)
+	}
+	p.MaxAge = time.Duration(seconds) * time.Second
+	return p, nil
+}
+
+func parseMXPattern(s string) (MXPattern, error) {
+	var p MXPattern
+	if strings.HasPrefix(s, "*.") {
+		p.Wildcard = true
+		s = s[2:]
+	}
+
+	var err error
+	if p.Name, err = domain.Parse(s); err != nil || strings.ContainsRune(s, '*') {
+		return MXPattern{}, fmt.Errorf("invalid MTA-STS policy mx %s", s)
+	}
+	return p, nil
+}
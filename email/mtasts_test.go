@@ -0,0 +1,60 @@
+package email_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/detectify/n5/domain"
+	"github.com/detectify/n5/email"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMTASTS_WithValidRecord_ShouldReturnID(t *testing.T) {
+	sts, err := email.ParseMTASTS("v=STSv1; id=20160831085700Z;")
+	require.NoError(t, err)
+	require.Equal(t, "20160831085700Z", sts.ID)
+
+	for _, record := range []string{"", "v=STSv1;", "v=STSv2; id=1", "v=STSv1; id=invalid-id"} {
+		_, err = email.ParseMTASTS(record)
+		require.Error(t, err, record)
+	}
+}
+
+func TestParseMTASTSPolicy_WithValidPolicy_ShouldReturnPolicy(t *testing.T) {
+	p, err := email.ParseMTASTSPolicy("version: STSv1\r\nmode: enforce\r\nmx: mail.example.com\r\n" +
+		"mx: *.example.net\r\nmax_age: 604800\r\n")
+	require.NoError(t, err)
+	require.Equal(t, email.MTASTSModeEnforce, p.Mode)
+	require.Equal(t, 7*24*time.Hour, p.MaxAge)
+	require.Len(t, p.MX, 2)
+	require.Equal(t, "*.example.net", p.MX[1].String())
+
+	require.True(t, p.Matches(domain.MustParse("mail.example.com")))
+	require.True(t, p.Matches(domain.MustParse("mx1.example.net")))
+	require.False(t, p.Matches(domain.MustParse("example.net")))
+	require.False(t, p.Matches(domain.MustParse("a.mx1.example.net")))
+}
+
+func TestParseMTASTSPolicy_WithInvalidPolicy_ShouldReturnError(t *testing.T) {
+	for _, policy := range []string{
+		"",
+		"version: STSv1\nmode: enforce\nmax_age: 86400\n",
+		"version: STSv1\nmode: strict\nmx: mail.example.com\nmax_age: 86400\n",
+		"version: STSv1\nmode: none\nmax_age: 99999999999\n",
+		"version: STSv1\nmode: testing\nmx: mail.*.example.com\nmax_age: 86400\n",
+	} {
+		_, err := email.ParseMTASTSPolicy(policy)
+		require.Error(t, err, policy)
+	}
+}
+
+func TestLookupMTASTS_WithRecord_ShouldReturnRecord(t *testing.T) {
+	r := testResolver{"_mta-sts.example.com": {"v=STSv1; id=abc123"}}
+
+	sts, err := email.LookupMTASTS(context.Background(), r, domain.MustParse("example.com"))
+	require.NoError(t, err)
+	require.Equal(t, "abc123", sts.ID)
+	require.Equal(t, "https://mta-sts.example.com/.well-known/mta-sts.txt",
+		email.MTASTSPolicyURL(domain.MustParse("example.com")))
+}
@@ -0,0 +1,151 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// ErrNoRecord is returned when no record of the kind is published for the name
+var ErrNoRecord = errors.New("no record found")
+
+// ErrMultipleRecords is returned when more than one record of the kind is published for the name
+var ErrMultipleRecords = errors.New("multiple records found")
+
+// Resolver looks up TXT records, which is satisfied by *net.Resolver
+//
+// The result holds one string per record, with the character strings of a record concatenated.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DefaultResolver is the resolver used when none is specified
+var DefaultResolver Resolver = net.DefaultResolver
+
+// lookupRecord returns the single TXT record of the name matching the kind, e.g. starting with "v=spf1"
+//
+// Returns ErrNoRecord if the name does not exist or has no such record, and ErrMultipleRecords if there is more than
+// one such record.
+func lookupRecord(ctx context.Context, r Resolver, name string, match func(string) bool) (string, error) {
+	if r == nil {
+		r = DefaultResolver
+	}
+
+	txts, err := r.LookupTXT(ctx, name)
+	if isNotFound(err) {
+		return "", fmt.Errorf("%s: %w", name, ErrNoRecord)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to lookup TXT for %s: %w", name, err)
+	}
+
+	var records []string
+	for _, txt := range txts {
+		if match(txt) {
+			records = append(records, txt)
+		}
+	}
+	switch len(records) {
+	case 0:
+		return "", fmt.Errorf("%s: %w", name, ErrNoRecord)
+	case 1:
+		return records[0], nil
+	default:
+		return "", fmt.Errorf("%s: %w", name, ErrMultipleRecords)
+	}
+}
+
+// isNotFound returns whether the error indicates the name or record does not exist
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// hasVersionTag returns whether the tag-value list starts with the version tag, e.g. "v=DMARC1"
+func hasVersionTag(record, version string) bool {
+	name, value, ok := strings.Cut(record, "=")
+	if !ok || strings.TrimSpace(name) != "v" {
+		return false
+	}
+	value, _, _ = strings.Cut(value, ";")
+	return strings.TrimSpace(value) == version
+}
+
+// prefixName returns the name with the label prefixed, such as "_dmarc"
+func prefixName(prefix string, name domain.Name) (domain.Name, error) {
+	return domain.Parse(prefix + "." + name.String())
+}
+
+// tag is a tag of a tag-value list
+type tag struct {
+	name  string
+	value string
+}
+
+// parseTags parses a tag-value list, as specified in RFC 6376 section 3.2 and used by DKIM, DMARC and MTA-STS
+//
+// Tag names are case-sensitive, and whitespace around names and values is removed. Returns an error if a tag is
+// malformed or duplicated.
+func parseTags(s string) ([]tag, error) {
+	var tags []tag
+	seen := map[string]bool{}
+	for i, spec := range strings.Split(s, ";") {
+		spec = strings.TrimSpace(spec)
+		if len(spec) == 0 {
+			if i == 0 {
+				return nil, errors.New("empty tag list")
+			}
+			continue
+		}
+
+		name, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("tag %s has no value", spec)
+		}
+		name = strings.TrimSpace(name)
+		if !isTagName(name) {
+			return nil, fmt.Errorf("invalid tag name %s", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate tag %s", name)
+		}
+		seen[name] = true
+		tags = append(tags, tag{name: name, value: strings.TrimSpace(value)})
+	}
+	return tags, nil
+}
+
+func isTagName(s string) bool {
+	if len(s) == 0 || !isAlpha(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isAlpha(s[i]) && !isDigit(s[i]) && s[i] != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// splitList splits a list separated by the separator, removing whitespace and empty elements
+func splitList(s, sep string) []string {
+	var result []string
+	for _, v := range strings.Split(s, sep) {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			result = append(result, v)
+		}
+	}
+	return result
+}
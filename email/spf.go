@@ -0,0 +1,549 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// SPFLookupLimit is the maximum number of terms causing DNS lookups during SPF evaluation, as specified in RFC 7208
+// section 4.6.4
+const SPFLookupLimit = 10
+
+// ErrSPFLookupLimit is returned when the evaluation of an SPF record exceeds the DNS lookup limits
+var ErrSPFLookupLimit = errors.New("SPF lookup limit exceeded")
+
+// ErrSPFLoop is returned when an SPF record includes or redirects to itself
+var ErrSPFLoop = errors.New("SPF include loop")
+
+// Qualifier is the result of an SPF mechanism matching
+type Qualifier byte
+
+// SPF qualifiers, as specified in RFC 7208 section 4.6.2
+const (
+	QualifierPass     Qualifier = '+'
+	QualifierFail     Qualifier = '-'
+	QualifierSoftFail Qualifier = '~'
+	QualifierNeutral  Qualifier = '?'
+)
+
+// String returns the name of the result
+func (q Qualifier) String() string {
+	switch q {
+	case QualifierFail:
+		return "fail"
+	case QualifierSoftFail:
+		return "softfail"
+	case QualifierNeutral:
+		return "neutral"
+	default:
+		return "pass"
+	}
+}
+
+// MechanismType is the type of an SPF mechanism
+type MechanismType string
+
+// SPF mechanism types, as specified in RFC 7208 section 5
+const (
+	MechanismAll     MechanismType = "all"
+	MechanismInclude MechanismType = "include"
+	MechanismA       MechanismType = "a"
+	MechanismMX      MechanismType = "mx"
+	MechanismPTR     MechanismType = "ptr"
+	MechanismIP4     MechanismType = "ip4"
+	MechanismIP6     MechanismType = "ip6"
+	MechanismExists  MechanismType = "exists"
+)
+
+// DomainSpec is a domain referenced by an SPF record, which is either a domain name or a macro expanded during
+// evaluation
+type DomainSpec struct {
+	// Name is the referenced domain name, unless the domain is a macro
+	Name domain.Name
+	// Macro is the macro string, such as "%{i}._spf.example.com", if the domain contains macros
+	Macro string
+}
+
+// IsMacro returns whether the domain contains macros
+func (d DomainSpec) IsMacro() bool {
+	return len(d.Macro) > 0
+}
+
+// String returns the domain as in the record
+func (d DomainSpec) String() string {
+	if d.IsMacro() {
+		return d.Macro
+	}
+	return d.Name.String()
+}
+
+// Mechanism is a mechanism of an SPF record
+type Mechanism struct {
+	Qualifier Qualifier
+	Type      MechanismType
+	// Domain is the domain of include and exists mechanisms, and the optional domain of a, mx and ptr mechanisms
+	Domain *DomainSpec
+	// Prefix is the network of ip4 and ip6 mechanisms
+	Prefix netip.Prefix
+	// IPv4PrefixLen and IPv6PrefixLen are the prefix lengths of the addresses matched by a and mx mechanisms,
+	// defaulting to a single address
+	IPv4PrefixLen int
+	IPv6PrefixLen int
+}
+
+// IsLookup returns whether the mechanism causes DNS lookups, which count towards SPFLookupLimit
+func (m Mechanism) IsLookup() bool {
+	switch m.Type {
+	case MechanismInclude, MechanismA, MechanismMX, MechanismPTR, MechanismExists:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the mechanism as in the record
+func (m Mechanism) String() string {
+	var b strings.Builder
+	if m.Qualifier != QualifierPass {
+		b.WriteByte(byte(m.Qualifier))
+	}
+	b.WriteString(string(m.Type))
+	switch {
+	case m.Type == MechanismIP4 || m.Type == MechanismIP6:
+		b.WriteString(":" + m.Prefix.Masked().String())
+	case m.Domain != nil:
+		b.WriteString(":" + m.Domain.String())
+	}
+	if m.Type == MechanismA || m.Type == MechanismMX {
+		if m.IPv4PrefixLen != 32 {
+			b.WriteString("/" + strconv.Itoa(m.IPv4PrefixLen))
+		}
+		if m.IPv6PrefixLen != 128 {
+			b.WriteString("//" + strconv.Itoa(m.IPv6PrefixLen))
+		}
+	}
+	return b.String()
+}
+
+// SPF holds a Sender Policy Framework record, as specified in RFC 7208
+type SPF struct {
+	Mechanisms []Mechanism
+	// Redirect is the domain of the redirect modifier, if any
+	Redirect *DomainSpec
+	// Explanation is the domain of the exp modifier, if any
+	Explanation *DomainSpec
+	// Modifiers holds the unknown modifiers, which are ignored during evaluation
+	Modifiers map[string]string
+}
+
+// All returns the qualifier of the "all" mechanism, and whether there is one
+func (s SPF) All() (Qualifier, bool) {
+	for _, m := range s.Mechanisms {
+		if m.Type == MechanismAll {
+			return m.Qualifier, true
+		}
+	}
+	return 0, false
+}
+
+// Lookups returns the number of terms of the record causing DNS lookups
+//
+// The redirect modifier is not counted when the record has an "all" mechanism, as it is ignored.
+func (s SPF) Lookups() int {
+	n := 0
+	for _, m := range s.Mechanisms {
+		if m.IsLookup() {
+			n++
+		}
+	}
+	if s.Redirect != nil && !hasAll(s) {
+		n++
+	}
+	return n
+}
+
+// String returns the SPF record
+func (s SPF) String() string {
+	terms := []string{"v=spf1"}
+	for _, m := range s.Mechanisms {
+		terms = append(terms, m.String())
+	}
+	if s.Redirect != nil {
+		terms = append(terms, "redirect="+s.Redirect.String())
+	}
+	if s.Explanation != nil {
+		terms = append(terms, "exp="+s.Explanation.String())
+	}
+	return strings.Join(terms, " ")
+}
+
+// IsSPF returns whether the TXT record is an SPF record, i.e. starts with "v=spf1"
+func IsSPF(record string) bool {
+	return len(record) >= 6 && strings.EqualFold(record[:6], "v=spf1") && (len(record) == 6 || record[6] == ' ')
+}
+
+// ParseSPF parses an SPF record
+//
+// Returns an error if the record is not an SPF record, or has invalid syntax, which is a permanent error of the
+// evaluation.
+func ParseSPF(record string) (SPF, error) {
+	terms := strings.Fields(record)
+	if len(terms) == 0 || !strings.EqualFold(terms[0], "v=spf1") {
+		return SPF{}, errors.New("not an SPF record")
+	}
+
+	var spf SPF
+	for _, term := range terms[1:] {
+		if name, value, ok := cutModifier(term); ok {
+			if err := spf.parseModifier(name, value); err != nil {
+				return SPF{}, fmt.Errorf("invalid SPF term %s: %w", term, err)
+			}
+			continue
+		}
+
+		m, err := parseMechanism(term)
+		if err != nil {
+			return SPF{}, fmt.Errorf("invalid SPF term %s: %w", term, err)
+		}
+		spf.Mechanisms = append(spf.Mechanisms, m)
+	}
+	return spf, nil
+}
+
+// cutModifier splits a modifier into name and value, if the term is a modifier
+func cutModifier(term string) (string, string, bool) {
+	name, value, ok := strings.Cut(term, "=")
+	if !ok || len(name) == 0 || !isAlpha(name[0]) {
+		return "", "", false
+	}
+	for i := 1; i < len(name); i++ {
+		b := name[i]
+		if !isAlpha(b) && !isDigit(b) && b != '-' && b != '_' && b != '.' {
+			return "", "", false
+		}
+	}
+	return strings.ToLower(name), value, true
+}
+
+func (s *SPF) parseModifier(name, value string) error {
+	switch name {
+	case "redirect", "exp":
+		spec, err := parseDomainSpec(value)
+		if err != nil {
+			return err
+		}
+		target := &s.Redirect
+		if name == "exp" {
+			target = &s.Explanation
+		}
+		if *target != nil {
+			return errors.New("duplicate modifier")
+		}
+		*target = &spec
+	default:
+		if s.Modifiers == nil {
+			s.Modifiers = map[string]string{}
+		}
+		s.Modifiers[name] = value
+	}
+	return nil
+}
+
+func parseMechanism(term string) (Mechanism, error) {
+	m := Mechanism{Qualifier: QualifierPass, IPv4PrefixLen: 32, IPv6PrefixLen: 128}
+	switch Qualifier(term[0]) {
+	case QualifierPass, QualifierFail, QualifierSoftFail, QualifierNeutral:
+		m.Qualifier = Qualifier(term[0])
+		term = term[1:]
+	}
+
+	end := strings.IndexAny(term, ":/")
+	if end < 0 {
+		end = len(term)
+	}
+	m.Type = MechanismType(strings.ToLower(term[:end]))
+	args := term[end:]
+
+	var err error
+	switch m.Type {
+	case MechanismAll:
+		if len(args) > 0 {
+			return Mechanism{}, errors.New("unexpected arguments")
+		}
+	case MechanismInclude, MechanismExists:
+		if !strings.HasPrefix(args, ":") {
+			return Mechanism{}, errors.New("missing domain")
+		}
+		m.Domain, err = parseOptionalDomainSpec(args[1:])
+	case MechanismPTR:
+		if strings.HasPrefix(args, ":") {
+			m.Domain, err = parseOptionalDomainSpec(args[1:])
+		} else if len(args) > 0 {
+			return Mechanism{}, errors.New("unexpected arguments")
+		}
+	case MechanismA, MechanismMX:
+		spec, cidr, _ := strings.Cut(args, "/")
+		if len(spec) > 0 {
+			if !strings.HasPrefix(spec, ":") {
+				return Mechanism{}, errors.New("invalid domain")
+			}
+			if m.Domain, err = parseOptionalDomainSpec(spec[1:]); err != nil {
+				return Mechanism{}, err
+			}
+		}
+		if strings.HasPrefix(args[len(spec):], "/") {
+			m.IPv4PrefixLen, m.IPv6PrefixLen, err = parseDualCIDR(cidr)
+		}
+	case MechanismIP4, MechanismIP6:
+		if !strings.HasPrefix(args, ":") {
+			return Mechanism{}, errors.New("missing network")
+		}
+		m.Prefix, err = parseNetwork(args[1:], m.Type == MechanismIP4)
+	default:
+		return Mechanism{}, errors.New("unknown mechanism")
+	}
+	if err != nil {
+		return Mechanism{}, err
+	}
+	return m, nil
+}
+
+// parseDualCIDR parses the prefix lengths of a and mx mechanisms, in "v4", "v4//v6" or "/v6" format
+func parseDualCIDR(s string) (int, int, error) {
+	ipv4, ipv6 := 32, 128
+	v4, v6, dual := strings.Cut(s, "//")
+	if !dual && strings.HasPrefix(s, "/") {
+		v4, v6, dual = "", s[1:], true
+	}
+
+	var err error
+	if len(v4) > 0 || !dual {
+		if ipv4, err = parsePrefixLen(v4, 32); err != nil {
+			return 0, 0, err
+		}
+	}
+	if dual {
+		if ipv6, err = parsePrefixLen(v6, 128); err != nil {
+			return 0, 0, err
+		}
+	}
+	return ipv4, ipv6, nil
+}
+
+func parsePrefixLen(s string, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > max || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("invalid prefix length %s", s)
+	}
+	return n, nil
+}
+
+// parseNetwork parses the network of ip4 and ip6 mechanisms, which is an address with an optional prefix length
+func parseNetwork(s string, ipv4 bool) (netip.Prefix, error) {
+	address, length, hasLength := strings.Cut(s, "/")
+	addr, err := netip.ParseAddr(address)
+	if err != nil || addr.Is4() != ipv4 || addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("invalid address %s", address)
+	}
+
+	bits := addr.BitLen()
+	if hasLength {
+		if bits, err = parsePrefixLen(length, addr.BitLen()); err != nil {
+			return netip.Prefix{}, err
+		}
+	}
+	return addr.Prefix(bits)
+}
+
+func parseOptionalDomainSpec(s string) (*DomainSpec, error) {
+	spec, err := parseDomainSpec(s)
+	if err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// parseDomainSpec parses a domain, which may contain macros as specified in RFC 7208 section 7
+func parseDomainSpec(s string) (DomainSpec, error) {
+	if len(s) == 0 {
+		return DomainSpec{}, errors.New("empty domain")
+	}
+	if !strings.ContainsRune(s, '%') {
+		name, err := domain.Parse(s)
+		if err != nil {
+			return DomainSpec{}, err
+		}
+		return DomainSpec{Name: name}, nil
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		if i+1 == len(s) {
+			return DomainSpec{}, errors.New("incomplete macro")
+		}
+		switch s[i+1] {
+		case '%', '_', '-':
+			i++
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return DomainSpec{}, errors.New("unterminated macro")
+			}
+			if !isMacroExpand(s[i+2 : i+end]) {
+				return DomainSpec{}, fmt.Errorf("invalid macro %s", s[i:i+end+1])
+			}
+			i += end
+		default:
+			return DomainSpec{}, fmt.Errorf("invalid macro %s", s[i:i+2])
+		}
+	}
+	return DomainSpec{Macro: s}, nil
+}
+
+// isMacroExpand returns whether the string is a valid macro body, such as "ir" or "d2"
+func isMacroExpand(s string) bool {
+	if len(s) == 0 || !strings.ContainsRune("slodiphcrtvSLODIPHCRTV", rune(s[0])) {
+		return false
+	}
+	i := 1
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i < len(s) && (s[i] == 'r' || s[i] == 'R') {
+		i++
+	}
+	for ; i < len(s); i++ {
+		if !strings.ContainsRune(".-+,/_=", rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// SPFNode is an SPF record in the graph of records referenced through include mechanisms and redirect modifiers
+type SPFNode struct {
+	Name   domain.Name
+	Record SPF
+	// Children holds the records of the include mechanisms and the redirect modifier, in the order of the record,
+	// skipping those with macros
+	Children []*SPFNode
+	// Err is the reason the record could not be retrieved or parsed
+	Err error
+}
+
+// SPFEvaluation is the result of the evaluation of an SPF record and the records it references
+type SPFEvaluation struct {
+	Root *SPFNode
+	// Lookups is the number of terms causing DNS lookups, up to exceeding the limit
+	Lookups int
+	// Err is the permanent error of the evaluation, such as exceeding the lookup limits or an invalid record
+	Err error
+}
+
+// EvaluateSPF looks up the SPF record of the domain name, and the records it references
+//
+// The graph of records is evaluated as specified in RFC 7208 section 4.6.4, counting the terms causing DNS lookups
+// towards the limit of 10, and stopped once the limit is exceeded. Lookups of the addresses matched by mechanisms are
+// counted, but not performed. A referenced name without a valid SPF record is a permanent error, as is a loop. The
+// resolver defaults to DefaultResolver. Returns an error if the SPF record of the name can't be retrieved.
+func EvaluateSPF(ctx context.Context, r Resolver, name domain.Name) (SPFEvaluation, error) {
+	root, err := lookupSPFNode(ctx, r, name)
+	if err != nil {
+		return SPFEvaluation{}, err
+	}
+	if errors.Is(root.Err, ErrNoRecord) {
+		return SPFEvaluation{}, root.Err
+	}
+
+	e := SPFEvaluation{Root: root}
+	e.Err = e.evaluate(ctx, r, root, []domain.Name{name})
+	return e, nil
+}
+
+func (e *SPFEvaluation) evaluate(ctx context.Context, r Resolver, node *SPFNode, path []domain.Name) error {
+	if node.Err != nil {
+		return fmt.Errorf("SPF record of %s: %w", node.Name.String(), node.Err)
+	}
+
+	var targets []*DomainSpec
+	for _, m := range node.Record.Mechanisms {
+		if !m.IsLookup() {
+			continue
+		}
+		if e.Lookups++; e.Lookups > SPFLookupLimit {
+			return fmt.Errorf("%w: more than %d lookups", ErrSPFLookupLimit, SPFLookupLimit)
+		}
+		if m.Type == MechanismInclude {
+			targets = append(targets, m.Domain)
+		}
+	}
+	if node.Record.Redirect != nil && !hasAll(node.Record) {
+		// redirect is ignored when the record has an "all" mechanism
+		if e.Lookups++; e.Lookups > SPFLookupLimit {
+			return fmt.Errorf("%w: more than %d lookups", ErrSPFLookupLimit, SPFLookupLimit)
+		}
+		targets = append(targets, node.Record.Redirect)
+	}
+
+	for _, target := range targets {
+		if target.IsMacro() {
+			continue
+		}
+		for _, p := range path {
+			if p.String() == target.Name.String() {
+				return fmt.Errorf("%w: %s", ErrSPFLoop, target.Name.String())
+			}
+		}
+
+		child, err := lookupSPFNode(ctx, r, target.Name)
+		if err != nil {
+			return err
+		}
+		node.Children = append(node.Children, child)
+		if err = e.evaluate(ctx, r, child, append(path[:len(path):len(path)], target.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasAll(s SPF) bool {
+	_, ok := s.All()
+	return ok
+}
+
+// lookupSPFNode looks up and parses the SPF record of the name, returning an error only if the lookup fails
+// temporarily
+func lookupSPFNode(ctx context.Context, r Resolver, name domain.Name) (*SPFNode, error) {
+	record, err := lookupRecord(ctx, r, name.String(), IsSPF)
+	if err != nil {
+		if errors.Is(err, ErrNoRecord) || errors.Is(err, ErrMultipleRecords) {
+			return &SPFNode{Name: name, Err: err}, nil
+		}
+		return nil, err
+	}
+
+	node := &SPFNode{Name: name}
+	node.Record, node.Err = ParseSPF(record)
+	return node, nil
+}
+
+// LookupSPF looks up and parses the SPF record of the domain name
+//
+// The resolver defaults to DefaultResolver. Returns ErrNoRecord if the name has no SPF record, and ErrMultipleRecords
+// if it has more than one.
+func LookupSPF(ctx context.Context, r Resolver, name domain.Name) (SPF, error) {
+	record, err := lookupRecord(ctx, r, name.String(), IsSPF)
+	if err != nil {
+		return SPF{}, err
+	}
+	return ParseSPF(record)
+}
@@ -0,0 +1,145 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/detectify/n5/domain"
+	"github.com/detectify/n5/email"
+	"github.com/stretchr/testify/require"
+)
+
+// testResolver resolves TXT records from a map, returning a not found error for missing names
+type testResolver map[string][]string
+
+func (r testResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	txts, ok := r[strings.TrimSuffix(name, ".")]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return txts, nil
+}
+
+func TestParseSPF_WithValidRecord_ShouldReturnMechanisms(t *testing.T) {
+	spf, err := email.ParseSPF("v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::1 a mx:mail.example.com/24//64 " +
+		"include:_spf.example.net exists:%{i}._spf.example.com ?ptr ~all redirect=example.org unknown=value")
+	require.NoError(t, err)
+	require.Len(t, spf.Mechanisms, 8)
+
+	require.Equal(t, email.MechanismIP4, spf.Mechanisms[0].Type)
+	require.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), spf.Mechanisms[0].Prefix)
+	require.Equal(t, netip.MustParsePrefix("2001:db8::1/128"), spf.Mechanisms[1].Prefix)
+	require.Nil(t, spf.Mechanisms[2].Domain)
+	require.Equal(t, "mail.example.com", spf.Mechanisms[3].Domain.Name.String())
+	require.Equal(t, 24, spf.Mechanisms[3].IPv4PrefixLen)
+	require.Equal(t, 64, spf.Mechanisms[3].IPv6PrefixLen)
+	require.Equal(t, "_spf.example.net", spf.Mechanisms[4].Domain.Name.String())
+	require.True(t, spf.Mechanisms[5].Domain.IsMacro())
+	require.Equal(t, email.QualifierNeutral, spf.Mechanisms[6].Qualifier)
+	require.Equal(t, "example.org", spf.Redirect.Name.String())
+	require.Equal(t, "value", spf.Modifiers["unknown"])
+
+	all, ok := spf.All()
+	require.True(t, ok)
+	require.Equal(t, email.QualifierSoftFail, all)
+	require.Equal(t, "softfail", all.String())
+	require.Equal(t, 5, spf.Lookups())
+	require.Equal(t, "v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::1/128 a mx:mail.example.com/24//64 "+
+		"include:_spf.example.net exists:%{i}._spf.example.com ?ptr ~all redirect=example.org", spf.String())
+}
+
+func TestParseSPF_WithInvalidRecord_ShouldReturnError(t *testing.T) {
+	for _, record := range []string{
+		"",
+		"v=spf2 -all",
+		"v=spf1 foo:example.com",
+		"v=spf1 ip4:2001:db8::1",
+		"v=spf1 ip4:192.0.2.0/33",
+		"v=spf1 ip6:2001:db8::/01",
+		"v=spf1 include",
+		"v=spf1 all:example.com",
+		"v=spf1 a/24//200",
+		"v=spf1 exists:%{x}.example.com",
+		"v=spf1 redirect=a.example.com redirect=b.example.com",
+	} {
+		_, err := email.ParseSPF(record)
+		require.Error(t, err, record)
+	}
+}
+
+func TestIsSPF(t *testing.T) {
+	require.True(t, email.IsSPF("v=spf1 -all"))
+	require.True(t, email.IsSPF("V=SPF1"))
+	require.False(t, email.IsSPF("v=spf10 -all"))
+	require.False(t, email.IsSPF("v=DMARC1; p=none"))
+}
+
+func TestEvaluateSPF_WithIncludes_ShouldReturnGraph(t *testing.T) {
+	r := testResolver{
+		"example.com": {
+			"google-site-verification=abc",
+			"v=spf1 a mx include:_spf.example.net redirect=spf.example.org",
+		},
+		"_spf.example.net":   {"v=spf1 ip4:192.0.2.0/24 include:_spf2.example.net -all"},
+		"_spf2.example.net":  {"v=spf1 ip6:2001:db8::/32 -all"},
+		"spf.example.org":    {"v=spf1 include:%{ir}.example.org -all"},
+		"loop.example.com":   {"v=spf1 include:loop2.example.com -all"},
+		"loop2.example.com":  {"v=spf1 include:loop.example.com -all"},
+		"double.example.com": {"v=spf1 -all", "v=spf1 +all"},
+	}
+
+	e, err := email.EvaluateSPF(context.Background(), r, domain.MustParse("example.com"))
+	require.NoError(t, err)
+	require.NoError(t, e.Err)
+	require.Equal(t, 6, e.Lookups)
+	require.Len(t, e.Root.Children, 2)
+	require.Equal(t, "_spf.example.net", e.Root.Children[0].Name.String())
+	require.Equal(t, "_spf2.example.net", e.Root.Children[0].Children[0].Name.String())
+	require.Equal(t, "spf.example.org", e.Root.Children[1].Name.String())
+	require.Empty(t, e.Root.Children[1].Children)
+
+	e, err = email.EvaluateSPF(context.Background(), r, domain.MustParse("loop.example.com"))
+	require.NoError(t, err)
+	require.True(t, errors.Is(e.Err, email.ErrSPFLoop))
+
+	_, err = email.EvaluateSPF(context.Background(), r, domain.MustParse("missing.example.com"))
+	require.True(t, errors.Is(err, email.ErrNoRecord))
+
+	e, err = email.EvaluateSPF(context.Background(), r, domain.MustParse("double.example.com"))
+	require.NoError(t, err)
+	require.True(t, errors.Is(e.Err, email.ErrMultipleRecords))
+}
+
+func TestEvaluateSPF_WithTooManyLookupsOrMissingInclude_ShouldReturnError(t *testing.T) {
+	r := testResolver{
+		"example.com":        {"v=spf1 a mx include:a.example.net include:b.example.net -all"},
+		"a.example.net":      {"v=spf1 a mx ptr exists:x.example.net -all"},
+		"b.example.net":      {"v=spf1 a mx mx:mail.example.net -all"},
+		"broken.example.com": {"v=spf1 include:x.example.org include:y.example.org include:z.example.org -all"},
+	}
+
+	e, err := email.EvaluateSPF(context.Background(), r, domain.MustParse("example.com"))
+	require.NoError(t, err)
+	require.True(t, errors.Is(e.Err, email.ErrSPFLookupLimit))
+	require.Equal(t, email.SPFLookupLimit+1, e.Lookups)
+
+	e, err = email.EvaluateSPF(context.Background(), r, domain.MustParse("broken.example.com"))
+	require.NoError(t, err)
+	require.True(t, errors.Is(e.Err, email.ErrNoRecord))
+	require.Len(t, e.Root.Children, 1)
+}
+
+func TestLookupSPF_WithRecord_ShouldReturnRecord(t *testing.T) {
+	r := testResolver{"example.com": {"v=spf1 -all"}}
+
+	spf, err := email.LookupSPF(context.Background(), r, domain.MustParse("example.com"))
+	require.NoError(t, err)
+	require.Len(t, spf.Mechanisms, 1)
+
+	_, err = email.LookupSPF(context.Background(), r, domain.MustParse("example.org"))
+	require.True(t, errors.Is(err, email.ErrNoRecord))
+}
@@ -3,6 +3,7 @@ module github.com/detectify/n5
 go 1.19
 
 require (
+	github.com/miekg/dns v1.1.55
 	github.com/stretchr/testify v1.7.0
 	github.com/weppos/publicsuffix-go v0.30.1
 	golang.org/x/net v0.12.0
@@ -11,6 +12,9 @@ require (
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/mod v0.8.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
 	golang.org/x/text v0.11.0 // indirect
+	golang.org/x/tools v0.6.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
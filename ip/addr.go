@@ -0,0 +1,66 @@
+package ip
+
+import (
+	"net"
+	"net/netip"
+)
+
+// AddrFromIP converts the IP address to netip.Addr
+//
+// IPv4-mapped IPv6 addresses are converted to IPv4 addresses, as net.IP does not distinguish between them.
+// Returns false if the IP address is invalid.
+func AddrFromIP(ip net.IP) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// IPFromAddr converts the IP address to net.IP
+//
+// The zone of IPv6 addresses is dropped. Returns nil if the IP address is invalid.
+func IPFromAddr(addr netip.Addr) net.IP {
+	if !addr.IsValid() {
+		return nil
+	}
+	return net.IP(addr.AsSlice())
+}
+
+// PrefixFromIPNet converts the IP network to netip.Prefix
+//
+// IPv4-mapped IPv6 networks are converted to IPv4 networks. Returns false if the IP network is invalid, or the mask is
+// non-canonical, i.e. not a prefix.
+func PrefixFromIPNet(n net.IPNet) (netip.Prefix, bool) {
+	addr, ok := AddrFromIP(n.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	ones, bits := n.Mask.Size()
+	if bits == 0 {
+		return netip.Prefix{}, false
+	}
+	if bits == 128 && addr.Is4() {
+		ones -= 96
+	} else if bits != addr.BitLen() {
+		return netip.Prefix{}, false
+	}
+	if ones < 0 {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, ones).Masked(), true
+}
+
+// IPNetFromPrefix converts the IP network to net.IPNet
+//
+// Returns a zero net.IPNet if the IP network is invalid.
+func IPNetFromPrefix(p netip.Prefix) net.IPNet {
+	if !p.IsValid() {
+		return net.IPNet{}
+	}
+	p = p.Masked()
+	return net.IPNet{
+		IP:   IPFromAddr(p.Addr()),
+		Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen()),
+	}
+}
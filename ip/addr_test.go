@@ -0,0 +1,56 @@
+package ip_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddrFromIP_WithIP_ShouldReturnAddr(t *testing.T) {
+	addr, ok := ip.AddrFromIP(net.ParseIP("192.0.2.1"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddr("192.0.2.1"), addr)
+
+	addr, ok = ip.AddrFromIP(net.ParseIP("2001:db8::1"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddr("2001:db8::1"), addr)
+
+	_, ok = ip.AddrFromIP(nil)
+	require.False(t, ok)
+}
+
+func TestIPFromAddr_WithAddr_ShouldReturnIP(t *testing.T) {
+	require.True(t, net.ParseIP("192.0.2.1").Equal(ip.IPFromAddr(netip.MustParseAddr("192.0.2.1"))))
+	require.True(t, net.ParseIP("fe80::1").Equal(ip.IPFromAddr(netip.MustParseAddr("fe80::1%eth0"))))
+	require.Nil(t, ip.IPFromAddr(netip.Addr{}))
+}
+
+func TestPrefixFromIPNet_WithIPNet_ShouldReturnPrefix(t *testing.T) {
+	_, n, _ := net.ParseCIDR("192.0.2.0/24")
+	p, ok := ip.PrefixFromIPNet(*n)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), p)
+
+	_, n, _ = net.ParseCIDR("::ffff:10.0.0.0/104")
+	p, ok = ip.PrefixFromIPNet(*n)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), p)
+
+	_, ok = ip.PrefixFromIPNet(net.IPNet{IP: net.ParseIP("192.0.2.0").To4(), Mask: net.IPv4Mask(255, 0, 255, 0)})
+	require.False(t, ok)
+	_, ok = ip.PrefixFromIPNet(net.IPNet{})
+	require.False(t, ok)
+}
+
+func TestIPNetFromPrefix_WithPrefix_ShouldReturnIPNet(t *testing.T) {
+	n := ip.IPNetFromPrefix(netip.MustParsePrefix("2001:db8::1/32"))
+	require.Equal(t, "2001:db8::/32", n.String())
+
+	n = ip.IPNetFromPrefix(netip.MustParsePrefix("192.0.2.1/24"))
+	require.Equal(t, "192.0.2.0/24", n.String())
+
+	require.Equal(t, net.IPNet{}, ip.IPNetFromPrefix(netip.Prefix{}))
+}
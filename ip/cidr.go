@@ -0,0 +1,341 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// MaxSubnets is the maximum number of subnets SplitPrefix returns
+const MaxSubnets = 1 << 20
+
+// AddrRange is a range of IP addresses from and to the addresses, inclusive, which are of the same family
+type AddrRange struct {
+	From netip.Addr
+	To   netip.Addr
+}
+
+// ParseAddrRange parses a range of IP addresses, which is either two addresses separated by a dash
+// (10.0.0.1-10.0.0.50), a prefix in the CIDR notation (10.0.0.0/24) or a single address
+//
+// IPv4-mapped IPv6 addresses are converted to IPv4. Returns an error if an address or prefix is invalid, the addresses
+// are of different families, or the first address is greater than the last.
+func ParseAddrRange(s string) (AddrRange, error) {
+	s = strings.TrimSpace(s)
+	if from, to, ok := strings.Cut(s, "-"); ok {
+		r := AddrRange{}
+		var err error
+		if r.From, err = netip.ParseAddr(strings.TrimSpace(from)); err != nil {
+			return AddrRange{}, fmt.Errorf("invalid IP range %s: %w", s, err)
+		}
+		if r.To, err = netip.ParseAddr(strings.TrimSpace(to)); err != nil {
+			return AddrRange{}, fmt.Errorf("invalid IP range %s: %w", s, err)
+		}
+		r = AddrRange{From: r.From.Unmap().WithZone(""), To: r.To.Unmap().WithZone("")}
+		if !r.IsValid() {
+			return AddrRange{}, fmt.Errorf("invalid IP range %s", s)
+		}
+		return r, nil
+	}
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return AddrRange{}, fmt.Errorf("invalid IP range %s: %w", s, err)
+		}
+		return AddrRangeFromPrefix(p), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return AddrRange{}, fmt.Errorf("invalid IP range %s: %w", s, err)
+	}
+	addr = addr.Unmap().WithZone("")
+	return AddrRange{From: addr, To: addr}, nil
+}
+
+// AddrRangeFromPrefix returns the range of the IP addresses of the prefix
+func AddrRangeFromPrefix(p netip.Prefix) AddrRange {
+	p, ok := normalizePrefix(p)
+	if !ok {
+		return AddrRange{}
+	}
+	return AddrRange{From: p.Addr(), To: LastAddr(p)}
+}
+
+// IsValid returns whether the addresses are valid and of the same family, and the first is not greater than the last
+func (r AddrRange) IsValid() bool {
+	return r.From.IsValid() && r.To.IsValid() && r.From.BitLen() == r.To.BitLen() && r.From.Compare(r.To) <= 0
+}
+
+// Contains returns whether the IP address is in the range
+func (r AddrRange) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	return r.IsValid() && r.From.Compare(addr) <= 0 && addr.Compare(r.To) <= 0
+}
+
+// Size returns the number of IP addresses of the range
+func (r AddrRange) Size() *big.Int {
+	if !r.IsValid() {
+		return new(big.Int)
+	}
+	size := new(big.Int).Sub(addrInt(r.To), addrInt(r.From))
+	return size.Add(size, big.NewInt(1))
+}
+
+// Prefixes returns the minimal list of prefixes covering the range exactly, in order
+func (r AddrRange) Prefixes() []netip.Prefix {
+	if !r.IsValid() {
+		return nil
+	}
+
+	var prefixes []netip.Prefix
+	for from := r.From; ; {
+		// the largest prefix starting at the address which doesn't exceed the range
+		var p netip.Prefix
+		for bits := 0; bits <= from.BitLen(); bits++ {
+			p = netip.PrefixFrom(from, bits)
+			if p.Masked().Addr() == from && LastAddr(p).Compare(r.To) <= 0 {
+				break
+			}
+		}
+		prefixes = append(prefixes, p)
+
+		last := LastAddr(p)
+		if last == r.To {
+			return prefixes
+		}
+		from = last.Next()
+	}
+}
+
+// Addrs returns an iterator of the IP addresses of the range, in order
+func (r AddrRange) Addrs() *AddrIterator {
+	if !r.IsValid() {
+		return &AddrIterator{}
+	}
+	return &AddrIterator{ranges: []AddrRange{r}}
+}
+
+// String returns the range in the dash notation, such as 10.0.0.1-10.0.0.50
+func (r AddrRange) String() string {
+	return r.From.String() + "-" + r.To.String()
+}
+
+// AddrIterator iterates IP addresses lazily
+//
+//	it := r.Addrs()
+//	for it.Next() {
+//	    fmt.Println(it.Addr())
+//	}
+type AddrIterator struct {
+	ranges  []AddrRange
+	addr    netip.Addr
+	started bool
+}
+
+// Next advances to the next IP address, returning false when there are no more addresses
+func (it *AddrIterator) Next() bool {
+	switch {
+	case len(it.ranges) == 0:
+		return false
+	case !it.started:
+		it.started = true
+		it.addr = it.ranges[0].From
+	case it.addr == it.ranges[0].To:
+		if it.ranges = it.ranges[1:]; len(it.ranges) == 0 {
+			return false
+		}
+		it.addr = it.ranges[0].From
+	default:
+		it.addr = it.addr.Next()
+	}
+	return true
+}
+
+// Addr returns the current IP address
+func (it *AddrIterator) Addr() netip.Addr {
+	return it.addr
+}
+
+// PrefixAddrs returns an iterator of the IP addresses of the prefix, in order
+func PrefixAddrs(p netip.Prefix) *AddrIterator {
+	return AddrRangeFromPrefix(p).Addrs()
+}
+
+// PrefixSize returns the number of IP addresses of the prefix
+func PrefixSize(p netip.Prefix) *big.Int {
+	return AddrRangeFromPrefix(p).Size()
+}
+
+// LastAddr returns the last IP address of the prefix, i.e. the address with all host bits set
+func LastAddr(p netip.Prefix) netip.Addr {
+	if !p.IsValid() {
+		return netip.Addr{}
+	}
+	b := p.Addr().As16()
+	offset := 128 - p.Addr().BitLen()
+	for i := offset + p.Bits(); i < 128; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr := netip.AddrFrom16(b)
+	if p.Addr().Is4() {
+		addr = addr.Unmap()
+	}
+	return addr
+}
+
+// SplitPrefix splits the prefix into the subnets of the prefix length, in order
+//
+// Returns an error if the prefix length is less than the length of the prefix, or there would be more than
+// MaxSubnets subnets.
+func SplitPrefix(p netip.Prefix, bits int) ([]netip.Prefix, error) {
+	p, ok := normalizePrefix(p)
+	if !ok {
+		return nil, errors.New("invalid prefix")
+	}
+	if bits < p.Bits() || bits > p.Addr().BitLen() {
+		return nil, fmt.Errorf("invalid prefix length %d for %s", bits, p.String())
+	}
+	if bits-p.Bits() > 62 || 1<<(bits-p.Bits()) > MaxSubnets {
+		return nil, fmt.Errorf("too many subnets of length %d for %s", bits, p.String())
+	}
+
+	subnets := make([]netip.Prefix, 0, 1<<(bits-p.Bits()))
+	for addr := p.Addr(); len(subnets) < cap(subnets); {
+		subnet := netip.PrefixFrom(addr, bits)
+		subnets = append(subnets, subnet)
+		addr = LastAddr(subnet).Next()
+	}
+	return subnets, nil
+}
+
+// MergePrefixes returns the minimal list of prefixes covering the same IP addresses as the prefixes, in order, by
+// merging overlapping and adjacent prefixes
+func MergePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	return rangesToPrefixes(prefixRanges(prefixes))
+}
+
+// ExcludePrefixes returns the minimal list of prefixes covering the IP addresses of the prefixes, except those of the
+// excluded prefixes
+func ExcludePrefixes(prefixes, exclude []netip.Prefix) []netip.Prefix {
+	return rangesToPrefixes(excludeRanges(prefixRanges(prefixes), prefixRanges(exclude)))
+}
+
+// IntersectPrefixes returns the minimal list of prefixes covering the IP addresses in both lists of prefixes
+func IntersectPrefixes(a, b []netip.Prefix) []netip.Prefix {
+	return rangesToPrefixes(intersectRanges(prefixRanges(a), prefixRanges(b)))
+}
+
+func prefixRanges(prefixes []netip.Prefix) []AddrRange {
+	ranges := make([]AddrRange, 0, len(prefixes))
+	for _, p := range prefixes {
+		if r := AddrRangeFromPrefix(p); r.IsValid() {
+			ranges = append(ranges, r)
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// mergeRanges returns the valid ranges sorted, with overlapping and adjacent ranges merged
+func mergeRanges(ranges []AddrRange) []AddrRange {
+	sorted := make([]AddrRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.IsValid() {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].From.Compare(sorted[j].From) < 0
+	})
+
+	var merged []AddrRange
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && touches(merged[n-1], r) {
+			if r.To.Compare(merged[n-1].To) > 0 {
+				merged[n-1].To = r.To
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// touches returns whether the range starting at or after the previous range overlaps or is adjacent to it
+func touches(previous, r AddrRange) bool {
+	if previous.To.BitLen() != r.From.BitLen() {
+		return false
+	}
+	next := previous.To.Next()
+	return !next.IsValid() || next.Compare(r.From) >= 0
+}
+
+// excludeRanges returns the merged ranges without the addresses of the merged excluded ranges, sweeping both lists in
+// order
+func excludeRanges(ranges, exclude []AddrRange) []AddrRange {
+	var result []AddrRange
+	j := 0
+	for _, r := range ranges {
+		// the excluded ranges before the range are before the following ranges too
+		for j < len(exclude) && exclude[j].To.Compare(r.From) < 0 {
+			j++
+		}
+		for k := j; k < len(exclude) && exclude[k].From.Compare(r.To) <= 0; k++ {
+			e := exclude[k]
+			if e.From.Compare(r.From) > 0 {
+				result = append(result, AddrRange{From: r.From, To: e.From.Prev()})
+			}
+			if e.To.Compare(r.To) >= 0 {
+				r = AddrRange{}
+				break
+			}
+			r.From = e.To.Next()
+		}
+		if r.IsValid() {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// intersectRanges returns the addresses in both lists of merged ranges
+func intersectRanges(a, b []AddrRange) []AddrRange {
+	var result []AddrRange
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		from, to := a[i].From, a[i].To
+		if b[j].From.Compare(from) > 0 {
+			from = b[j].From
+		}
+		if b[j].To.Compare(to) < 0 {
+			to = b[j].To
+		}
+		if r := (AddrRange{From: from, To: to}); r.IsValid() {
+			result = append(result, r)
+		}
+		if a[i].To.Compare(b[j].To) < 0 {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+func rangesToPrefixes(ranges []AddrRange) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, r := range ranges {
+		prefixes = append(prefixes, r.Prefixes()...)
+	}
+	return prefixes
+}
+
+func addrInt(addr netip.Addr) *big.Int {
+	if addr.Is4() {
+		b := addr.As4()
+		return new(big.Int).SetBytes(b[:])
+	}
+	b := addr.As16()
+	return new(big.Int).SetBytes(b[:])
+}
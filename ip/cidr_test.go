@@ -0,0 +1,165 @@
+package ip_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddrRange_WithRange_ShouldReturnRange(t *testing.T) {
+	for s, expected := range map[string]string{
+		"10.0.0.1-10.0.0.50":       "10.0.0.1-10.0.0.50",
+		" 10.0.0.1 - 10.0.0.1 ":    "10.0.0.1-10.0.0.1",
+		"10.0.0.0/24":              "10.0.0.0-10.0.0.255",
+		"10.0.0.7/24":              "10.0.0.0-10.0.0.255",
+		"::ffff:10.0.0.1":          "10.0.0.1-10.0.0.1",
+		"2001:db8::/126":           "2001:db8::-2001:db8::3",
+		"2001:db8::1-2001:db8::ff": "2001:db8::1-2001:db8::ff",
+	} {
+		r, err := ip.ParseAddrRange(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, r.String(), s)
+	}
+
+	for _, s := range []string{"", "10.0.0.50-10.0.0.1", "10.0.0.1-::1", "10.0.0.1-", "10.0.0.0/33", "example.com"} {
+		_, err := ip.ParseAddrRange(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestAddrRange_WithRange_ShouldReturnSizeAndPrefixes(t *testing.T) {
+	r, err := ip.ParseAddrRange("10.0.0.1-10.0.0.50")
+	require.NoError(t, err)
+	require.Equal(t, int64(50), r.Size().Int64())
+	require.True(t, r.Contains(netip.MustParseAddr("10.0.0.50")))
+	require.True(t, r.Contains(netip.MustParseAddr("::ffff:10.0.0.1")))
+	require.False(t, r.Contains(netip.MustParseAddr("10.0.0.51")))
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.2/31"),
+		netip.MustParsePrefix("10.0.0.4/30"),
+		netip.MustParsePrefix("10.0.0.8/29"),
+		netip.MustParsePrefix("10.0.0.16/28"),
+		netip.MustParsePrefix("10.0.0.32/28"),
+		netip.MustParsePrefix("10.0.0.48/31"),
+		netip.MustParsePrefix("10.0.0.50/32"),
+	}, r.Prefixes())
+
+	r, err = ip.ParseAddrRange("0.0.0.0-255.255.255.255")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}, r.Prefixes())
+
+	require.Equal(t, "18446744073709551616", ip.PrefixSize(netip.MustParsePrefix("2001:db8::/64")).String())
+	require.Equal(t, "340282366920938463463374607431768211456", ip.PrefixSize(netip.MustParsePrefix("::/0")).String())
+	require.Zero(t, ip.AddrRange{}.Size().Int64())
+	require.Nil(t, ip.AddrRange{}.Prefixes())
+}
+
+func TestAddrRange_Addrs_ShouldIterateAddresses(t *testing.T) {
+	var addrs []string
+	it := ip.PrefixAddrs(netip.MustParsePrefix("255.255.255.252/30"))
+	for it.Next() {
+		addrs = append(addrs, it.Addr().String())
+	}
+	require.Equal(t, []string{"255.255.255.252", "255.255.255.253", "255.255.255.254", "255.255.255.255"}, addrs)
+
+	it = ip.AddrRange{}.Addrs()
+	require.False(t, it.Next())
+
+	// iterating is lazy, so a huge prefix is fine
+	it = ip.PrefixAddrs(netip.MustParsePrefix("2001:db8::/32"))
+	require.True(t, it.Next())
+	require.True(t, it.Next())
+	require.Equal(t, "2001:db8::1", it.Addr().String())
+}
+
+func TestLastAddr_WithPrefix_ShouldReturnLastAddress(t *testing.T) {
+	require.Equal(t, "10.255.255.255", ip.LastAddr(netip.MustParsePrefix("10.0.0.0/8")).String())
+	require.Equal(t, "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff",
+		ip.LastAddr(netip.MustParsePrefix("2001:db8::/32")).String())
+	require.Equal(t, "192.0.2.1", ip.LastAddr(netip.MustParsePrefix("192.0.2.1/32")).String())
+	require.False(t, ip.LastAddr(netip.Prefix{}).IsValid())
+}
+
+func TestSplitPrefix_WithLength_ShouldReturnSubnets(t *testing.T) {
+	subnets, err := ip.SplitPrefix(netip.MustParsePrefix("10.0.0.0/24"), 26)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/26"),
+		netip.MustParsePrefix("10.0.0.64/26"),
+		netip.MustParsePrefix("10.0.0.128/26"),
+		netip.MustParsePrefix("10.0.0.192/26"),
+	}, subnets)
+
+	subnets, err = ip.SplitPrefix(netip.MustParsePrefix("2001:db8::/32"), 48)
+	require.NoError(t, err)
+	require.Len(t, subnets, 1<<16)
+	require.Equal(t, "2001:db8:ffff::/48", subnets[len(subnets)-1].String())
+
+	_, err = ip.SplitPrefix(netip.MustParsePrefix("10.0.0.0/24"), 16)
+	require.Error(t, err)
+	_, err = ip.SplitPrefix(netip.MustParsePrefix("10.0.0.0/24"), 33)
+	require.Error(t, err)
+	_, err = ip.SplitPrefix(netip.MustParsePrefix("2001:db8::/32"), 64)
+	require.Error(t, err)
+}
+
+func TestMergePrefixes_WithOverlappingPrefixes_ShouldReturnMinimalList(t *testing.T) {
+	prefixes := ip.MergePrefixes([]netip.Prefix{
+		netip.MustParsePrefix("10.0.1.0/24"),
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.0.0.128/25"),
+		netip.MustParsePrefix("2001:db8::/33"),
+		netip.MustParsePrefix("2001:db8:8000::/33"),
+		netip.MustParsePrefix("255.255.255.255/32"),
+		netip.MustParsePrefix("255.255.255.0/24"),
+		netip.MustParsePrefix("::ffff:192.0.2.0/120"),
+	})
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/23"),
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("255.255.255.0/24"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, prefixes)
+	require.Nil(t, ip.MergePrefixes(nil))
+}
+
+func TestExcludePrefixes_WithExcludedPrefixes_ShouldReturnDifference(t *testing.T) {
+	prefixes := ip.ExcludePrefixes(
+		[]netip.Prefix{netip.MustParsePrefix("203.0.113.0/24"), netip.MustParsePrefix("2001:db8::/32")},
+		[]netip.Prefix{
+			netip.MustParsePrefix("203.0.113.5/32"),
+			netip.MustParsePrefix("203.0.113.128/25"),
+			netip.MustParsePrefix("2001:db8::/32"),
+			netip.MustParsePrefix("10.0.0.0/8"),
+		},
+	)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("203.0.113.0/30"),
+		netip.MustParsePrefix("203.0.113.4/32"),
+		netip.MustParsePrefix("203.0.113.6/31"),
+		netip.MustParsePrefix("203.0.113.8/29"),
+		netip.MustParsePrefix("203.0.113.16/28"),
+		netip.MustParsePrefix("203.0.113.32/27"),
+		netip.MustParsePrefix("203.0.113.64/26"),
+	}, prefixes)
+}
+
+func TestIntersectPrefixes_WithOverlappingPrefixes_ShouldReturnIntersection(t *testing.T) {
+	prefixes := ip.IntersectPrefixes(
+		[]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")},
+		[]netip.Prefix{
+			netip.MustParsePrefix("10.1.0.0/16"),
+			netip.MustParsePrefix("10.2.0.0/16"),
+			netip.MustParsePrefix("11.0.0.0/8"),
+			netip.MustParsePrefix("2001:db8:1::/48"),
+		},
+	)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("10.2.0.0/16"),
+		netip.MustParsePrefix("2001:db8:1::/48"),
+	}, prefixes)
+}
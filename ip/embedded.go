@@ -0,0 +1,134 @@
+package ip
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Embedding is the way an IPv4 address is embedded in an IPv6 address
+type Embedding int
+
+// Embeddings of IPv4 addresses in IPv6 addresses
+const (
+	EmbeddingNone Embedding = iota
+	// EmbeddingMapped is an IPv4-mapped address ::ffff:a.b.c.d, as specified in RFC 4291 section 2.5.5.2
+	EmbeddingMapped
+	// EmbeddingCompatible is a deprecated IPv4-compatible address ::a.b.c.d, as specified in RFC 4291 section 2.5.5.1
+	EmbeddingCompatible
+	// Embedding6to4 is a 6to4 address 2002:aabb:ccdd::/48, as specified in RFC 3056
+	Embedding6to4
+	// EmbeddingTeredo is a Teredo address in 2001::/32 with the obfuscated client address, as specified in RFC 4380
+	EmbeddingTeredo
+	// EmbeddingNAT64 is an IPv4-embedded address of the well-known NAT64 prefix 64:ff9b::/96 or the local-use prefix
+	// 64:ff9b:1::/48, as specified in RFC 6052 and RFC 8215
+	EmbeddingNAT64
+)
+
+// String returns the name of the embedding
+func (e Embedding) String() string {
+	switch e {
+	case EmbeddingNone:
+		return "none"
+	case EmbeddingMapped:
+		return "IPv4-mapped"
+	case EmbeddingCompatible:
+		return "IPv4-compatible"
+	case Embedding6to4:
+		return "6to4"
+	case EmbeddingTeredo:
+		return "Teredo"
+	case EmbeddingNAT64:
+		return "NAT64"
+	default:
+		return fmt.Sprintf("Embedding(%d)", int(e))
+	}
+}
+
+var (
+	nat64WellKnownPrefix = netip.MustParsePrefix("64:ff9b::/96")
+	nat64LocalPrefix     = netip.MustParsePrefix("64:ff9b:1::/48")
+)
+
+// EmbeddedIPv4 returns the IPv4 address embedded in the IPv6 address, with the way it is embedded, and whether there
+// is one
+//
+// IPv4-compatible addresses exclude the unspecified (::) and loopback (::1) addresses, and for Teredo addresses the
+// client address is returned, rather than the server address.
+func EmbeddedIPv4(addr netip.Addr) (netip.Addr, Embedding, bool) {
+	if !addr.Is6() {
+		return netip.Addr{}, EmbeddingNone, false
+	}
+	if addr.Is4In6() {
+		return addr.Unmap(), EmbeddingMapped, true
+	}
+
+	b := addr.As16()
+	switch {
+	case isZero(b[:12]) && (!isZero(b[12:15]) || b[15] > 1):
+		return netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]}), EmbeddingCompatible, true
+	case b[0] == 0x20 && b[1] == 0x02:
+		return netip.AddrFrom4([4]byte{b[2], b[3], b[4], b[5]}), Embedding6to4, true
+	case b[0] == 0x20 && b[1] == 0x01 && b[2] == 0 && b[3] == 0:
+		return netip.AddrFrom4([4]byte{^b[12], ^b[13], ^b[14], ^b[15]}), EmbeddingTeredo, true
+	}
+	for _, p := range []netip.Prefix{nat64WellKnownPrefix, nat64LocalPrefix} {
+		if v4, ok := NAT64Addr(addr, p); ok {
+			return v4, EmbeddingNAT64, true
+		}
+	}
+	return netip.Addr{}, EmbeddingNone, false
+}
+
+// UnwrapAddr returns the IPv4 address embedded in the IPv6 address, or the address itself if none is embedded
+func UnwrapAddr(addr netip.Addr) netip.Addr {
+	if v4, _, ok := EmbeddedIPv4(addr); ok {
+		return v4
+	}
+	return addr
+}
+
+// NAT64Addr returns the IPv4 address embedded in the IPv6 address by the NAT64 prefix, and whether the address is in
+// the prefix, as specified in RFC 6052 section 2.2
+//
+// The prefix length must be 32, 40, 48, 56, 64 or 96.
+func NAT64Addr(addr netip.Addr, prefix netip.Prefix) (netip.Addr, bool) {
+	if !addr.Is6() || addr.Is4In6() || !prefix.Addr().Is6() || !prefix.Contains(addr.WithZone("")) {
+		return netip.Addr{}, false
+	}
+
+	b := addr.As16()
+	var v4 [4]byte
+	switch prefix.Bits() {
+	case 32:
+		copy(v4[:], b[4:8])
+	case 40:
+		copy(v4[:3], b[5:8])
+		v4[3] = b[9]
+	case 48:
+		copy(v4[:2], b[6:8])
+		copy(v4[2:], b[9:11])
+	case 56:
+		v4[0] = b[7]
+		copy(v4[1:], b[9:12])
+	case 64:
+		copy(v4[:], b[9:13])
+	case 96:
+		copy(v4[:], b[12:])
+	default:
+		return netip.Addr{}, false
+	}
+	if prefix.Bits() < 96 && b[8] != 0 {
+		// bits 64 to 71 (the u octet) must be zero
+		return netip.Addr{}, false
+	}
+	return netip.AddrFrom4(v4), true
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
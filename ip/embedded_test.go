@@ -0,0 +1,82 @@
+package ip_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedIPv4_WithEmbeddedAddress_ShouldReturnIPv4(t *testing.T) {
+	for addr, expected := range map[string]struct {
+		ipv4      string
+		embedding ip.Embedding
+	}{
+		"::ffff:127.0.0.1":                     {"127.0.0.1", ip.EmbeddingMapped},
+		"::127.0.0.1":                          {"127.0.0.1", ip.EmbeddingCompatible},
+		"::2":                                  {"0.0.0.2", ip.EmbeddingCompatible},
+		"2002:7f00:1::":                        {"127.0.0.1", ip.Embedding6to4},
+		"2002:c000:22d::1%eth0":                {"192.0.2.45", ip.Embedding6to4},
+		"2001:0:4136:e378:8000:63bf:3fff:fdd2": {"192.0.2.45", ip.EmbeddingTeredo},
+		"64:ff9b::7f00:1":                      {"127.0.0.1", ip.EmbeddingNAT64},
+		"64:ff9b:1:c000:2:2d00::":              {"192.0.2.45", ip.EmbeddingNAT64},
+		"64:ff9b::192.168.1.1":                 {"192.168.1.1", ip.EmbeddingNAT64},
+	} {
+		v4, embedding, ok := ip.EmbeddedIPv4(netip.MustParseAddr(addr))
+		require.True(t, ok, addr)
+		require.Equal(t, expected.ipv4, v4.String(), addr)
+		require.Equal(t, expected.embedding, embedding, addr)
+		require.Equal(t, v4, ip.UnwrapAddr(netip.MustParseAddr(addr)), addr)
+	}
+
+	for _, addr := range []string{"::", "::1", "127.0.0.1", "2606:4700::1111", "64:ff9b:1:c000:ff02:2d00::"} {
+		_, _, ok := ip.EmbeddedIPv4(netip.MustParseAddr(addr))
+		require.False(t, ok, addr)
+		require.Equal(t, netip.MustParseAddr(addr), ip.UnwrapAddr(netip.MustParseAddr(addr)))
+	}
+}
+
+func TestNAT64Addr_WithPrefixLengths_ShouldExtractIPv4(t *testing.T) {
+	// examples of RFC 6052 section 2.4
+	for prefix, addr := range map[string]string{
+		"2001:db8::/32":         "2001:db8:c000:221::",
+		"2001:db8:100::/40":     "2001:db8:1c0:2:21::",
+		"2001:db8:122::/48":     "2001:db8:122:c000:2:2100::",
+		"2001:db8:122:300::/56": "2001:db8:122:3c0:0:221::",
+		"2001:db8:122:344::/64": "2001:db8:122:344:c0:2:2100:0",
+		"2001:db8:122:344::/96": "2001:db8:122:344::192.0.2.33",
+	} {
+		v4, ok := ip.NAT64Addr(netip.MustParseAddr(addr), netip.MustParsePrefix(prefix))
+		require.True(t, ok, prefix)
+		require.Equal(t, "192.0.2.33", v4.String(), prefix)
+	}
+
+	_, ok := ip.NAT64Addr(netip.MustParseAddr("2001:db9::1"), netip.MustParsePrefix("2001:db8::/32"))
+	require.False(t, ok)
+	_, ok = ip.NAT64Addr(netip.MustParseAddr("2001:db8::1"), netip.MustParsePrefix("2001:db8::/33"))
+	require.False(t, ok)
+}
+
+func TestPolicy_WithEmbeddedIPv4_ShouldCheckBothAddresses(t *testing.T) {
+	p := ip.RFC1918Policy()
+	for _, addr := range []string{"::10.0.0.1", "2002:a00:1::", "64:ff9b::a00:1", "2001:0:4136:e378:8000:63bf:f5ff:fffe"} {
+		d := p.Check(netip.MustParseAddr(addr))
+		require.Equal(t, ip.VerdictDeny, d.Verdict, addr)
+		require.Equal(t, "10.0.0.1", d.Embedded.String(), addr)
+		require.False(t, p.Allows(netip.MustParseAddr(addr)), addr)
+	}
+	require.Equal(t, "embedded 10.0.0.1 of 6to4 address denied by 10.0.0.0/8 (Private-Use, RFC 1918)",
+		p.Check(netip.MustParseAddr("2002:a00:1::")).Reason)
+	require.True(t, p.Allows(netip.MustParseAddr("2002:808:808::")))
+
+	require.True(t, ip.IsReservedAddr(netip.MustParseAddr("::127.0.0.1")))
+	require.True(t, ip.IsReservedAddr(netip.MustParseAddr("64:ff9b::7f00:1")))
+	require.True(t, ip.IsReservedAddr(netip.MustParseAddr("2002:7f00:1::")))
+	require.False(t, ip.IsReservedAddr(netip.MustParseAddr("::ffff:8.8.8.8")))
+}
+
+func TestEmbedding_String(t *testing.T) {
+	require.Equal(t, "6to4", ip.Embedding6to4.String())
+	require.Equal(t, "IPv4-compatible", ip.EmbeddingCompatible.String())
+}
@@ -0,0 +1,365 @@
+package ip
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Cloud and CDN providers, which have published range files
+const (
+	ProviderAWS        = "AWS"
+	ProviderGCP        = "GCP"
+	ProviderAzure      = "Azure"
+	ProviderCloudflare = "Cloudflare"
+	ProviderFastly     = "Fastly"
+)
+
+// Info holds what is known about an IP address from the datasets of an Enricher
+type Info struct {
+	// Provider is the cloud or CDN provider the address belongs to, such as ProviderAWS
+	Provider string
+	// Region is the region of the provider, such as "us-east-1"
+	Region string
+	// Service is the service of the provider, such as "EC2"
+	Service string
+	// ASN is the number of the autonomous system announcing the address, or zero if unknown
+	ASN uint32
+	// Organization is the name of the autonomous system
+	Organization string
+	// Country is the ISO 3166-1 alpha-2 code of the country the address is located in, or registered to
+	Country string
+}
+
+// Enricher finds the provider, ASN and country of IP addresses from locally loaded datasets, by longest prefix match
+//
+// The zero value is an enricher without datasets. Datasets are loaded with the Load methods, and an enricher is safe
+// for concurrent lookups once loaded, but not while loading.
+type Enricher struct {
+	providers Trie[Info]
+	asns      Trie[Info]
+	countries Trie[string]
+}
+
+// LoadFile opens the file and loads it with the function, such as Enricher.LoadAWS
+func LoadFile(name string, load func(io.Reader) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := load(f); err != nil {
+		return fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	return nil
+}
+
+// Lookup returns what is known about the IP address, and whether it is in any dataset
+func (e *Enricher) Lookup(addr netip.Addr) (Info, bool) {
+	info, found := Info{}, false
+	if _, provider, ok := e.providers.Lookup(addr); ok {
+		info, found = provider, true
+	}
+	if _, asn, ok := e.asns.Lookup(addr); ok {
+		info.ASN, info.Organization, found = asn.ASN, asn.Organization, true
+		if len(info.Country) == 0 {
+			info.Country = asn.Country
+		}
+	}
+	if _, country, ok := e.countries.Lookup(addr); ok {
+		info.Country, found = country, true
+	}
+	return info, found
+}
+
+// addProvider adds the provider prefix, unless the prefix is already known with a more specific region or service
+func (e *Enricher) addProvider(s string, info Info) error {
+	p, err := netip.ParsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid prefix %s", s)
+	}
+	if existing, ok := e.providers.Get(p); ok && infoDetail(existing) > infoDetail(info) {
+		return nil
+	}
+	e.providers.Insert(p, info)
+	return nil
+}
+
+func infoDetail(info Info) int {
+	detail := 0
+	if len(info.Region) > 0 {
+		detail++
+	}
+	if len(info.Service) > 0 {
+		detail++
+	}
+	return detail
+}
+
+// LoadAWS loads the AWS ranges, published at https://ip-ranges.amazonaws.com/ip-ranges.json
+//
+// The generic service "AMAZON" is replaced by a more specific service of the same prefix, such as "EC2".
+func (e *Enricher) LoadAWS(r io.Reader) error {
+	type awsPrefix struct {
+		IPPrefix   string `json:"ip_prefix"`
+		IPv6Prefix string `json:"ipv6_prefix"`
+		Region     string `json:"region"`
+		Service    string `json:"service"`
+	}
+	var ranges struct {
+		Prefixes     []awsPrefix `json:"prefixes"`
+		IPv6Prefixes []awsPrefix `json:"ipv6_prefixes"`
+	}
+	if err := json.NewDecoder(r).Decode(&ranges); err != nil {
+		return fmt.Errorf("invalid AWS ranges: %w", err)
+	}
+
+	for _, p := range append(ranges.Prefixes, ranges.IPv6Prefixes...) {
+		info := Info{Provider: ProviderAWS, Region: p.Region, Service: p.Service}
+		if info.Service == "AMAZON" {
+			info.Service = ""
+		}
+		if err := e.addProvider(p.IPPrefix+p.IPv6Prefix, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadGCP loads the Google Cloud ranges, published at https://www.gstatic.com/ipranges/cloud.json
+func (e *Enricher) LoadGCP(r io.Reader) error {
+	var ranges struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Service    string `json:"service"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.NewDecoder(r).Decode(&ranges); err != nil {
+		return fmt.Errorf("invalid GCP ranges: %w", err)
+	}
+
+	for _, p := range ranges.Prefixes {
+		info := Info{Provider: ProviderGCP, Region: p.Scope, Service: p.Service}
+		if err := e.addProvider(p.IPv4Prefix+p.IPv6Prefix, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadAzure loads the Azure service tags, published as ServiceTags_Public_<date>.json by Microsoft
+//
+// A prefix in several service tags is attributed to the most specific tag, i.e. with a region and service.
+func (e *Enricher) LoadAzure(r io.Reader) error {
+	var tags struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.NewDecoder(r).Decode(&tags); err != nil {
+		return fmt.Errorf("invalid Azure service tags: %w", err)
+	}
+
+	for _, tag := range tags.Values {
+		info := Info{Provider: ProviderAzure, Region: tag.Properties.Region, Service: tag.Properties.SystemService}
+		for _, p := range tag.Properties.AddressPrefixes {
+			if err := e.addProvider(p, info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFastly loads the Fastly ranges, published at https://api.fastly.com/public-ip-list
+func (e *Enricher) LoadFastly(r io.Reader) error {
+	var ranges struct {
+		Addresses     []string `json:"addresses"`
+		IPv6Addresses []string `json:"ipv6_addresses"`
+	}
+	if err := json.NewDecoder(r).Decode(&ranges); err != nil {
+		return fmt.Errorf("invalid Fastly ranges: %w", err)
+	}
+
+	for _, p := range append(ranges.Addresses, ranges.IPv6Addresses...) {
+		if err := e.addProvider(p, Info{Provider: ProviderFastly}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCloudflare loads the Cloudflare ranges, published at https://www.cloudflare.com/ips-v4 and
+// https://www.cloudflare.com/ips-v6
+func (e *Enricher) LoadCloudflare(r io.Reader) error {
+	return e.LoadPrefixes(ProviderCloudflare, r)
+}
+
+// LoadPrefixes loads the ranges of the provider from a list of prefixes, one per line
+//
+// Empty lines and comments starting with '#' are ignored.
+func (e *Enricher) LoadPrefixes(provider string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		if err := e.addProvider(line, Info{Provider: provider}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// LoadIP2ASN loads the ASN dataset of https://iptoasn.com in the TSV format, i.e. lines of the first and last
+// address, ASN, country code and AS description separated by tabs
+//
+// Ranges which are not routed, i.e. with ASN 0, are ignored.
+func (e *Enricher) LoadIP2ASN(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) == 1 && len(strings.TrimSpace(fields[0])) == 0 {
+			continue
+		}
+		if len(fields) < 5 {
+			return fmt.Errorf("line %d: expected 5 fields", line)
+		}
+		rng, err := ParseAddrRange(fields[0] + "-" + fields[1])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid ASN %s", line, fields[2])
+		}
+		if asn == 0 {
+			continue
+		}
+
+		info := Info{ASN: uint32(asn), Organization: fields[4], Country: fields[3]}
+		if info.Country == "None" {
+			info.Country = ""
+		}
+		for _, p := range rng.Prefixes() {
+			e.asns.Insert(p, info)
+		}
+	}
+	return scanner.Err()
+}
+
+// LoadMaxMindASN loads a MaxMind GeoLite2 ASN dataset in the CSV format, such as GeoLite2-ASN-Blocks-IPv4.csv
+func (e *Enricher) LoadMaxMindASN(r io.Reader) error {
+	return readMaxMindCSV(r, []string{"network", "autonomous_system_number", "autonomous_system_organization"},
+		func(fields []string) error {
+			p, err := netip.ParsePrefix(fields[0])
+			if err != nil {
+				return fmt.Errorf("invalid network %s", fields[0])
+			}
+			asn, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid ASN %s", fields[1])
+			}
+			e.asns.Insert(p, Info{ASN: uint32(asn), Organization: fields[2]})
+			return nil
+		})
+}
+
+// LoadMaxMindCountry loads a MaxMind GeoLite2 Country or City dataset in the CSV format, from the blocks, such as
+// GeoLite2-Country-Blocks-IPv4.csv, and the locations, such as GeoLite2-Country-Locations-en.csv
+//
+// The country of a network is the country it is located in, or the country it is registered to if unknown.
+func (e *Enricher) LoadMaxMindCountry(blocks, locations io.Reader) error {
+	countries := map[string]string{}
+	err := readMaxMindCSV(locations, []string{"geoname_id", "country_iso_code"}, func(fields []string) error {
+		countries[fields[0]] = fields[1]
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("invalid locations: %w", err)
+	}
+
+	err = readMaxMindCSV(blocks, []string{"network", "geoname_id", "registered_country_geoname_id"},
+		func(fields []string) error {
+			p, err := netip.ParsePrefix(fields[0])
+			if err != nil {
+				return fmt.Errorf("invalid network %s", fields[0])
+			}
+			country := countries[fields[1]]
+			if len(country) == 0 {
+				country = countries[fields[2]]
+			}
+			if len(country) > 0 {
+				e.countries.Insert(p, country)
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("invalid blocks: %w", err)
+	}
+	return nil
+}
+
+// readMaxMindCSV reads the CSV file with a header, calling the function with the fields of the columns of each record
+func readMaxMindCSV(r io.Reader, columns []string, fn func([]string) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return errors.New("missing header")
+	}
+	if err != nil {
+		return err
+	}
+
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = -1
+		for j, name := range header {
+			if name == column {
+				indexes[i] = j
+			}
+		}
+		if indexes[i] < 0 {
+			return fmt.Errorf("missing column %s", column)
+		}
+	}
+
+	fields := make([]string, len(columns))
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for i, index := range indexes {
+			if index >= len(record) {
+				line, _ := reader.FieldPos(0)
+				return fmt.Errorf("line %d: missing field %s", line, columns[i])
+			}
+			fields[i] = record[index]
+		}
+		if err := fn(fields); err != nil {
+			line, _ := reader.FieldPos(0)
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package ip_test
+
+import (
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnricher_WithProviderRanges_ShouldReturnProvider(t *testing.T) {
+	var e ip.Enricher
+	require.NoError(t, e.LoadAWS(strings.NewReader(`{
+		"syncToken": "1700000000",
+		"prefixes": [
+			{"ip_prefix": "3.5.140.0/22", "region": "ap-northeast-2", "service": "AMAZON", "network_border_group": "ap-northeast-2"},
+			{"ip_prefix": "3.5.140.0/22", "region": "ap-northeast-2", "service": "S3", "network_border_group": "ap-northeast-2"},
+			{"ip_prefix": "52.94.0.0/16", "region": "us-east-1", "service": "AMAZON", "network_border_group": "us-east-1"}
+		],
+		"ipv6_prefixes": [
+			{"ipv6_prefix": "2600:1f18::/33", "region": "us-east-1", "service": "EC2", "network_border_group": "us-east-1"}
+		]
+	}`)))
+	require.NoError(t, e.LoadGCP(strings.NewReader(`{
+		"prefixes": [{"ipv4Prefix": "34.1.208.0/20", "service": "Google Cloud", "scope": "africa-south1"}]
+	}`)))
+	require.NoError(t, e.LoadAzure(strings.NewReader(`{
+		"values": [
+			{"name": "AzureCloud", "properties": {"region": "", "systemService": "", "addressPrefixes": ["13.64.0.0/16"]}},
+			{"name": "AzureAppService.WestUS", "properties": {"region": "westus", "systemService": "AzureAppService",
+				"addressPrefixes": ["13.64.0.0/16"]}},
+			{"name": "AzureCloud.westus", "properties": {"region": "westus", "systemService": "",
+				"addressPrefixes": ["13.64.0.0/16"]}}
+		]
+	}`)))
+	require.NoError(t, e.LoadCloudflare(strings.NewReader("173.245.48.0/20\n# comment\n\n2400:cb00::/32\n")))
+	require.NoError(t, e.LoadFastly(strings.NewReader(`{"addresses": ["151.101.0.0/16"], "ipv6_addresses": ["2a04:4e40::/32"]}`)))
+
+	for addr, expected := range map[string]ip.Info{
+		"3.5.141.1":           {Provider: ip.ProviderAWS, Region: "ap-northeast-2", Service: "S3"},
+		"52.94.1.1":           {Provider: ip.ProviderAWS, Region: "us-east-1"},
+		"2600:1f18::1":        {Provider: ip.ProviderAWS, Region: "us-east-1", Service: "EC2"},
+		"34.1.208.1":          {Provider: ip.ProviderGCP, Region: "africa-south1", Service: "Google Cloud"},
+		"13.64.1.1":           {Provider: ip.ProviderAzure, Region: "westus", Service: "AzureAppService"},
+		"::ffff:173.245.48.1": {Provider: ip.ProviderCloudflare},
+		"2400:cb00::1":        {Provider: ip.ProviderCloudflare},
+		"151.101.1.1":         {Provider: ip.ProviderFastly},
+	} {
+		info, ok := e.Lookup(netip.MustParseAddr(addr))
+		require.True(t, ok, addr)
+		require.Equal(t, expected, info, addr)
+	}
+
+	_, ok := e.Lookup(netip.MustParseAddr("8.8.8.8"))
+	require.False(t, ok)
+}
+
+func TestEnricher_WithASNAndCountryDatasets_ShouldReturnASNAndCountry(t *testing.T) {
+	var e ip.Enricher
+	require.NoError(t, e.LoadIP2ASN(strings.NewReader(
+		"1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET\n"+
+			"1.0.1.0\t1.0.3.255\t0\tNone\tNot routed\n"+
+			"8.8.8.0\t8.8.8.255\t15169\tUS\tGOOGLE\n")))
+	require.NoError(t, e.LoadMaxMindASN(strings.NewReader(
+		"network,autonomous_system_number,autonomous_system_organization\n"+
+			"2001:4860::/32,15169,GOOGLE\n"+
+			"8.8.8.0/24,15169,\"Google, LLC\"\n")))
+	require.NoError(t, e.LoadMaxMindCountry(
+		strings.NewReader("network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,"+
+			"is_anonymous_proxy,is_satellite_provider\n"+
+			"8.8.8.0/24,6252001,6252001,,0,0\n"+
+			"2001:4860::/32,,2921044,,0,0\n"),
+		strings.NewReader("geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,"+
+			"is_in_european_union\n"+
+			"6252001,en,NA,\"North America\",US,\"United States\",0\n"+
+			"2921044,en,EU,Europe,DE,Germany,1\n")))
+	require.NoError(t, e.LoadAWS(strings.NewReader(`{"prefixes": [{"ip_prefix": "8.8.8.0/24", "service": "EC2"}]}`)))
+
+	info, ok := e.Lookup(netip.MustParseAddr("8.8.8.8"))
+	require.True(t, ok)
+	require.Equal(t, ip.Info{Provider: ip.ProviderAWS, Service: "EC2", ASN: 15169, Organization: "Google, LLC",
+		Country: "US"}, info)
+
+	info, ok = e.Lookup(netip.MustParseAddr("1.0.0.1"))
+	require.True(t, ok)
+	require.Equal(t, ip.Info{ASN: 13335, Organization: "CLOUDFLARENET", Country: "US"}, info)
+
+	info, ok = e.Lookup(netip.MustParseAddr("2001:4860::8888"))
+	require.True(t, ok)
+	require.Equal(t, "DE", info.Country)
+
+	_, ok = e.Lookup(netip.MustParseAddr("1.0.1.1"))
+	require.False(t, ok)
+}
+
+func TestEnricher_WithInvalidDataset_ShouldReturnError(t *testing.T) {
+	var e ip.Enricher
+	require.Error(t, e.LoadAWS(strings.NewReader(`{"prefixes": [{"ip_prefix": "3.5.140.0/33"}]}`)))
+	require.Error(t, e.LoadGCP(strings.NewReader(`[`)))
+	require.Error(t, e.LoadCloudflare(strings.NewReader("example.com\n")))
+	require.Error(t, e.LoadIP2ASN(strings.NewReader("1.0.0.0\t1.0.0.255\t13335\n")))
+	require.Error(t, e.LoadIP2ASN(strings.NewReader("1.0.0.255\t1.0.0.0\t13335\tUS\tCLOUDFLARENET\n")))
+	require.Error(t, e.LoadMaxMindASN(strings.NewReader("network,autonomous_system_number\n")))
+	require.Error(t, e.LoadMaxMindASN(strings.NewReader(
+		"network,autonomous_system_number,autonomous_system_organization\n8.8.8.0/24,x,GOOGLE\n")))
+	require.Error(t, e.LoadMaxMindCountry(strings.NewReader(""), strings.NewReader("")))
+}
+
+func TestLoadFile_WithFile_ShouldLoadDataset(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "ips-v4")
+	require.NoError(t, os.WriteFile(name, []byte("173.245.48.0/20\n"), 0o600))
+
+	var e ip.Enricher
+	require.NoError(t, ip.LoadFile(name, e.LoadCloudflare))
+	require.True(t, func() bool { _, ok := e.Lookup(netip.MustParseAddr("173.245.48.1")); return ok }())
+
+	require.Error(t, ip.LoadFile(filepath.Join(t.TempDir(), "missing"), e.LoadCloudflare))
+	require.Error(t, ip.LoadFile(name, func(io.Reader) error { return io.ErrUnexpectedEOF }))
+}
@@ -0,0 +1,220 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// IPv6Scope is the scope of an IPv6 address, as specified in RFC 4007 and RFC 7346, with the values of the scope
+// field of multicast addresses
+type IPv6Scope uint8
+
+// Scopes of IPv6 addresses
+const (
+	IPv6ScopeReserved          IPv6Scope = 0x0
+	IPv6ScopeInterfaceLocal    IPv6Scope = 0x1
+	IPv6ScopeLinkLocal         IPv6Scope = 0x2
+	IPv6ScopeRealmLocal        IPv6Scope = 0x3
+	IPv6ScopeAdminLocal        IPv6Scope = 0x4
+	IPv6ScopeSiteLocal         IPv6Scope = 0x5
+	IPv6ScopeOrganizationLocal IPv6Scope = 0x8
+	IPv6ScopeGlobal            IPv6Scope = 0xe
+)
+
+// String returns the name of the scope
+func (s IPv6Scope) String() string {
+	switch s {
+	case IPv6ScopeReserved:
+		return "reserved"
+	case IPv6ScopeInterfaceLocal:
+		return "interface-local"
+	case IPv6ScopeLinkLocal:
+		return "link-local"
+	case IPv6ScopeRealmLocal:
+		return "realm-local"
+	case IPv6ScopeAdminLocal:
+		return "admin-local"
+	case IPv6ScopeSiteLocal:
+		return "site-local"
+	case IPv6ScopeOrganizationLocal:
+		return "organization-local"
+	case IPv6ScopeGlobal:
+		return "global"
+	default:
+		return fmt.Sprintf("unassigned(%d)", uint8(s))
+	}
+}
+
+// ScopeIPv6 returns the scope of the IPv6 address, which is reserved for invalid and IPv4(-mapped) addresses
+//
+// Multicast addresses have the scope of their scope field. The loopback and link-local unicast addresses are
+// link-local, the deprecated fec0::/10 addresses are site-local, and other unicast addresses, including unique local
+// addresses (fc00::/7), are global as specified in RFC 4193.
+func ScopeIPv6(addr netip.Addr) IPv6Scope {
+	if !IsIPv6Addr(addr) {
+		return IPv6ScopeReserved
+	}
+	b := addr.As16()
+	switch {
+	case b[0] == 0xff:
+		return IPv6Scope(b[1] & 0x0f)
+	case addr.IsLoopback() || addr.IsLinkLocalUnicast():
+		return IPv6ScopeLinkLocal
+	case b[0] == 0xfe && b[1]&0xc0 == 0xc0:
+		return IPv6ScopeSiteLocal
+	case addr.IsUnspecified():
+		return IPv6ScopeReserved
+	default:
+		return IPv6ScopeGlobal
+	}
+}
+
+// Multicast holds the scope and flags of an IPv6 multicast address, as specified in RFC 4291 section 2.7 and
+// RFC 3956
+type Multicast struct {
+	Scope IPv6Scope
+	// Transient indicates a dynamically assigned address, rather than a well-known address assigned by IANA (T flag)
+	Transient bool
+	// PrefixBased indicates an address based on a unicast prefix, as specified in RFC 3306 (P flag)
+	PrefixBased bool
+	// EmbeddedRP indicates an address embedding the address of the rendezvous point, as specified in RFC 3956 (R flag)
+	EmbeddedRP bool
+}
+
+// ParseMulticast returns the scope and flags of the IPv6 multicast address, and whether it is one
+func ParseMulticast(addr netip.Addr) (Multicast, bool) {
+	if !IsIPv6Addr(addr) || !addr.IsMulticast() {
+		return Multicast{}, false
+	}
+	b := addr.As16()
+	flags := b[1] >> 4
+	return Multicast{
+		Scope:       IPv6Scope(b[1] & 0x0f),
+		Transient:   flags&0x1 != 0,
+		PrefixBased: flags&0x2 != 0,
+		EmbeddedRP:  flags&0x4 != 0,
+	}, true
+}
+
+// InterfaceID is the kind of the interface identifier of an IPv6 unicast address, i.e. the last 64 bits, which tells
+// how the address was likely assigned
+type InterfaceID int
+
+// Kinds of interface identifiers
+const (
+	InterfaceIDUnknown InterfaceID = iota
+	// InterfaceIDLowByte is an identifier of zeros except the last 16 bits, usually assigned manually, such as ::1
+	InterfaceIDLowByte
+	// InterfaceIDEmbeddedIPv4 is an identifier of zeros except an IPv4 address in the last 32 bits, such as
+	// ::c000:201 or ::192.0.2.1, which includes patterns of that form such as ::dead:beef
+	InterfaceIDEmbeddedIPv4
+	// InterfaceIDEUI64 is a modified EUI-64 identifier derived from a MAC address, as specified in RFC 4291 appendix A
+	InterfaceIDEUI64
+	// InterfaceIDISATAP is an ISATAP identifier embedding an IPv4 address, as specified in RFC 5214
+	InterfaceIDISATAP
+	// InterfaceIDPattern is an identifier of mostly zeros, usually assigned manually, such as ::dead:beef:0:1 or
+	// ::1:2:3:4
+	InterfaceIDPattern
+	// InterfaceIDRandom is a randomized identifier, such as SLAAC privacy (temporary) addresses of RFC 8981 and
+	// stable opaque identifiers of RFC 7217
+	InterfaceIDRandom
+)
+
+// String returns the name of the kind of interface identifier
+func (id InterfaceID) String() string {
+	switch id {
+	case InterfaceIDLowByte:
+		return "low-byte"
+	case InterfaceIDEmbeddedIPv4:
+		return "embedded IPv4"
+	case InterfaceIDEUI64:
+		return "EUI-64"
+	case InterfaceIDISATAP:
+		return "ISATAP"
+	case InterfaceIDPattern:
+		return "pattern"
+	case InterfaceIDRandom:
+		return "random"
+	default:
+		return "unknown"
+	}
+}
+
+// ClassifyInterfaceID returns the kind of the interface identifier of the IPv6 unicast address
+//
+// The classification is a heuristic: an identifier is considered random if at least 12 of its 16 hexadecimal digits
+// are non-zero, and a pattern otherwise. Returns InterfaceIDUnknown for multicast and IPv4(-mapped) addresses.
+func ClassifyInterfaceID(addr netip.Addr) InterfaceID {
+	if !IsIPv6Addr(addr) || addr.IsMulticast() {
+		return InterfaceIDUnknown
+	}
+	b := addr.As16()
+	id := b[8:]
+	switch {
+	case isZero(id[:6]):
+		return InterfaceIDLowByte
+	case isZero(id[:4]):
+		return InterfaceIDEmbeddedIPv4
+	case id[3] == 0xff && id[4] == 0xfe:
+		return InterfaceIDEUI64
+	case id[0]&^0x02 == 0 && id[1] == 0 && id[2] == 0x5e && id[3] == 0xfe:
+		return InterfaceIDISATAP
+	}
+
+	nonZero := 0
+	for _, c := range id {
+		if c>>4 != 0 {
+			nonZero++
+		}
+		if c&0x0f != 0 {
+			nonZero++
+		}
+	}
+	if nonZero >= 12 {
+		return InterfaceIDRandom
+	}
+	return InterfaceIDPattern
+}
+
+// EUI64MAC returns the MAC address of the modified EUI-64 interface identifier of the IPv6 address, and whether the
+// interface identifier is EUI-64
+func EUI64MAC(addr netip.Addr) (net.HardwareAddr, bool) {
+	if ClassifyInterfaceID(addr) != InterfaceIDEUI64 {
+		return nil, false
+	}
+	b := addr.As16()
+	// the universal/local bit is inverted in the modified EUI-64 format
+	return net.HardwareAddr{b[8] ^ 0x02, b[9], b[10], b[13], b[14], b[15]}, true
+}
+
+// ExpandIPv6 returns the IPv6 address in the fully expanded form, such as 2001:0db8:0000:0000:0000:0000:0000:0001,
+// or an empty string if it is not an IPv6 address
+func ExpandIPv6(addr netip.Addr) string {
+	if !addr.Is6() {
+		return ""
+	}
+	return addr.StringExpanded()
+}
+
+// CanonicalIPv6 returns the IPv6 address in the canonical form of RFC 5952, such as 2001:db8::1, or an empty string
+// if it is not an IPv6 address
+func CanonicalIPv6(addr netip.Addr) string {
+	if !addr.Is6() {
+		return ""
+	}
+	return addr.String()
+}
+
+// ReverseNibbles returns the hexadecimal digits of the IPv6 address in reverse order separated by periods, such as
+// 1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2 for 2001:db8::1, or an empty string if it is not an
+// IPv6 address
+//
+// The name for reverse DNS lookup is returned by ReverseNameAddr.
+func ReverseNibbles(addr netip.Addr) string {
+	if !addr.Is6() {
+		return ""
+	}
+	return strings.TrimSuffix(reverseName(addr, 128), "."+reverseIPv6Suffix)
+}
@@ -0,0 +1,107 @@
+package ip_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeIPv6_WithAddress_ShouldReturnScope(t *testing.T) {
+	for addr, expected := range map[string]ip.IPv6Scope{
+		"::1":             ip.IPv6ScopeLinkLocal,
+		"fe80::1%eth0":    ip.IPv6ScopeLinkLocal,
+		"fec0::1":         ip.IPv6ScopeSiteLocal,
+		"fd00::1":         ip.IPv6ScopeGlobal,
+		"2001:db8::1":     ip.IPv6ScopeGlobal,
+		"ff02::1":         ip.IPv6ScopeLinkLocal,
+		"ff05::2":         ip.IPv6ScopeSiteLocal,
+		"ff0e::101":       ip.IPv6ScopeGlobal,
+		"ff18::1":         ip.IPv6ScopeOrganizationLocal,
+		"::":              ip.IPv6ScopeReserved,
+		"127.0.0.1":       ip.IPv6ScopeReserved,
+		"::ffff:10.0.0.1": ip.IPv6ScopeReserved,
+	} {
+		require.Equal(t, expected, ip.ScopeIPv6(netip.MustParseAddr(addr)), addr)
+	}
+	require.Equal(t, "organization-local", ip.IPv6ScopeOrganizationLocal.String())
+	require.Equal(t, "unassigned(6)", ip.IPv6Scope(6).String())
+}
+
+func TestParseMulticast_WithMulticastAddress_ShouldReturnFlags(t *testing.T) {
+	m, ok := ip.ParseMulticast(netip.MustParseAddr("ff02::1"))
+	require.True(t, ok)
+	require.Equal(t, ip.Multicast{Scope: ip.IPv6ScopeLinkLocal}, m)
+
+	m, ok = ip.ParseMulticast(netip.MustParseAddr("ff3e:30:2001:db8::1"))
+	require.True(t, ok)
+	require.Equal(t, ip.Multicast{Scope: ip.IPv6ScopeGlobal, Transient: true, PrefixBased: true}, m)
+
+	m, ok = ip.ParseMulticast(netip.MustParseAddr("ff75:140:2001:db8:be:feed::1"))
+	require.True(t, ok)
+	require.Equal(t, ip.Multicast{Scope: ip.IPv6ScopeSiteLocal, Transient: true, PrefixBased: true, EmbeddedRP: true}, m)
+
+	_, ok = ip.ParseMulticast(netip.MustParseAddr("2001:db8::1"))
+	require.False(t, ok)
+	_, ok = ip.ParseMulticast(netip.MustParseAddr("224.0.0.1"))
+	require.False(t, ok)
+}
+
+func TestClassifyInterfaceID_WithAddress_ShouldReturnKind(t *testing.T) {
+	for addr, expected := range map[string]ip.InterfaceID{
+		"2001:db8::1":                      ip.InterfaceIDLowByte,
+		"2001:db8::100":                    ip.InterfaceIDLowByte,
+		"2001:db8::c000:201":               ip.InterfaceIDEmbeddedIPv4,
+		"2001:db8::192.0.2.1":              ip.InterfaceIDEmbeddedIPv4,
+		"fe80::21b:21ff:fe3a:5c8d":         ip.InterfaceIDEUI64,
+		"fe80::5efe:c000:201":              ip.InterfaceIDISATAP,
+		"fe80::200:5efe:c000:201":          ip.InterfaceIDISATAP,
+		"2001:db8::dead:beef:0:1":          ip.InterfaceIDPattern,
+		"2001:db8::1:2:3:4":                ip.InterfaceIDPattern,
+		"2001:db8:1:2:a5c3:9f1e:7b24:d80c": ip.InterfaceIDRandom,
+		"ff02::1":                          ip.InterfaceIDUnknown,
+		"192.0.2.1":                        ip.InterfaceIDUnknown,
+	} {
+		require.Equal(t, expected, ip.ClassifyInterfaceID(netip.MustParseAddr(addr)), addr)
+	}
+	require.Equal(t, "EUI-64", ip.InterfaceIDEUI64.String())
+}
+
+func TestClassifyInterfaceID_WithDocumentedExamples_ShouldReturnKind(t *testing.T) {
+	for id, expected := range map[string]ip.InterfaceID{
+		"::1":             ip.InterfaceIDLowByte,
+		"::c000:201":      ip.InterfaceIDEmbeddedIPv4,
+		"::192.0.2.1":     ip.InterfaceIDEmbeddedIPv4,
+		"::dead:beef":     ip.InterfaceIDEmbeddedIPv4,
+		"::dead:beef:0:1": ip.InterfaceIDPattern,
+		"::1:2:3:4":       ip.InterfaceIDPattern,
+	} {
+		// the identifier is the last 64 bits of an address of the documentation prefix
+		b := netip.MustParseAddr(id).As16()
+		copy(b[:8], []byte{0x20, 0x01, 0x0d, 0xb8})
+		require.Equal(t, expected, ip.ClassifyInterfaceID(netip.AddrFrom16(b)), id)
+	}
+}
+
+func TestEUI64MAC_WithEUI64Address_ShouldReturnMAC(t *testing.T) {
+	mac, ok := ip.EUI64MAC(netip.MustParseAddr("fe80::21b:21ff:fe3a:5c8d"))
+	require.True(t, ok)
+	require.Equal(t, "00:1b:21:3a:5c:8d", mac.String())
+
+	_, ok = ip.EUI64MAC(netip.MustParseAddr("2001:db8::1"))
+	require.False(t, ok)
+}
+
+func TestFormatIPv6_WithAddress_ShouldReturnForms(t *testing.T) {
+	addr := netip.MustParseAddr("2001:0DB8:0:0:0:0:0:0001")
+	require.Equal(t, "2001:db8::1", ip.CanonicalIPv6(addr))
+	require.Equal(t, "2001:0db8:0000:0000:0000:0000:0000:0001", ip.ExpandIPv6(addr))
+	require.Equal(t, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2", ip.ReverseNibbles(addr))
+	require.Equal(t, ip.ReverseNibbles(addr)+".ip6.arpa", ip.ReverseNameAddr(addr))
+	require.Equal(t, "2001:db8:0:1:1:1:1:1", ip.CanonicalIPv6(netip.MustParseAddr("2001:db8:0:1:1:1:1:1")))
+
+	require.Empty(t, ip.CanonicalIPv6(netip.MustParseAddr("192.0.2.1")))
+	require.Empty(t, ip.ExpandIPv6(netip.Addr{}))
+	require.Empty(t, ip.ReverseNibbles(netip.Addr{}))
+}
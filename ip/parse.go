@@ -0,0 +1,165 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Notation is a set of non-canonical notations an IP address is written in
+type Notation uint
+
+// Non-canonical notations of IP addresses
+const (
+	// NotationShorthand is an IPv4 address of fewer than four parts, where the last part fills the remaining bytes,
+	// such as 127.1
+	NotationShorthand Notation = 1 << iota
+	// NotationInteger is an IPv4 address of a single number, such as 2130706433
+	NotationInteger
+	// NotationOctal is an IPv4 address with octal parts, i.e. with a leading zero, such as 0177.0.0.1
+	NotationOctal
+	// NotationHex is an IPv4 address with hexadecimal parts, such as 0x7f.0.0.1
+	NotationHex
+	// NotationTrailingDot is an IPv4 address ending with a dot, such as 127.0.0.1.
+	NotationTrailingDot
+	// NotationBrackets is an IPv6 address enclosed in brackets, such as [::1]
+	NotationBrackets
+	// NotationZone is an IPv6 address with a zone, such as fe80::1%eth0
+	NotationZone
+	// NotationIPv4Mapped is an IPv4 address written as an IPv4-mapped IPv6 address, such as ::ffff:7f00:1
+	NotationIPv4Mapped
+	// NotationNonCanonicalIPv6 is an IPv6 address not in the canonical format of RFC 5952, such as 0:0::0001
+	NotationNonCanonicalIPv6
+)
+
+var notationNames = []string{
+	"shorthand", "integer", "octal", "hex", "trailing dot", "brackets", "zone", "IPv4-mapped", "non-canonical IPv6",
+}
+
+// Has returns whether the notation includes all notations of n
+func (n Notation) Has(other Notation) bool {
+	return n&other == other
+}
+
+// String returns the names of the notations, separated by commas
+func (n Notation) String() string {
+	var names []string
+	for i, name := range notationNames {
+		if n&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseLenient parses an IP address written in any notation accepted by inet_aton(3) and browsers, returning the
+// canonical address and the non-canonical notations used
+//
+// IPv4 addresses may be written with one to four parts, each decimal, octal (leading 0) or hexadecimal (leading 0x),
+// such as 127.1, 0x7f.1, 017700000001 and 2130706433. IPv6 addresses may be enclosed in brackets and include a zone,
+// which is kept. IPv4-mapped IPv6 addresses are returned as IPv4 addresses. Returns an error if the string is not an
+// IP address in any of the notations, or a part is out of range.
+func ParseLenient(s string) (netip.Addr, Notation, error) {
+	if len(s) == 0 {
+		return netip.Addr{}, 0, errors.New("empty IP address")
+	}
+
+	var n Notation
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		n |= NotationBrackets
+		s = s[1 : len(s)-1]
+	}
+	if strings.Contains(s, ":") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil || !addr.Is6() {
+			return netip.Addr{}, 0, fmt.Errorf("%s is not an IP address", s)
+		}
+		if addr.Zone() != "" {
+			n |= NotationZone
+		}
+		if addr.Is4In6() {
+			return addr.Unmap(), n | NotationIPv4Mapped, nil
+		}
+		if addr.String() != s {
+			n |= NotationNonCanonicalIPv6
+		}
+		return addr, n, nil
+	}
+	if n != 0 {
+		return netip.Addr{}, 0, fmt.Errorf("%s is not an IPv6 address", s)
+	}
+
+	addr, n, err := parseLenientIPv4(s)
+	if err != nil {
+		return netip.Addr{}, 0, fmt.Errorf("%s is not an IP address: %w", s, err)
+	}
+	return addr, n, nil
+}
+
+func parseLenientIPv4(s string) (netip.Addr, Notation, error) {
+	var n Notation
+	if strings.HasSuffix(s, ".") {
+		n |= NotationTrailingDot
+		s = s[:len(s)-1]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return netip.Addr{}, 0, errors.New("too many parts")
+	}
+	switch len(parts) {
+	case 1:
+		n |= NotationInteger
+	case 2, 3:
+		n |= NotationShorthand
+	}
+
+	var values [4]uint64
+	for i, part := range parts {
+		v, notation, err := parseLenientPart(part)
+		if err != nil {
+			return netip.Addr{}, 0, err
+		}
+		n |= notation
+		values[i] = v
+	}
+
+	// all parts but the last are bytes, and the last part fills the remaining bytes
+	last := len(parts) - 1
+	var result uint64
+	for i := 0; i < last; i++ {
+		if values[i] > 0xff {
+			return netip.Addr{}, 0, fmt.Errorf("part %s out of range", parts[i])
+		}
+		result |= values[i] << (24 - 8*i)
+	}
+	if values[last] >= 1<<(8*(4-last)) {
+		return netip.Addr{}, 0, fmt.Errorf("part %s out of range", parts[last])
+	}
+	result |= values[last]
+
+	return netip.AddrFrom4([4]byte{byte(result >> 24), byte(result >> 16), byte(result >> 8), byte(result)}), n, nil
+}
+
+func parseLenientPart(s string) (uint64, Notation, error) {
+	base, n := 10, Notation(0)
+	switch {
+	case len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X"):
+		base, n, s = 16, NotationHex, s[2:]
+		if len(s) == 0 {
+			// "0x" is zero, as in browsers and inet_aton(3)
+			return 0, n, nil
+		}
+	case len(s) >= 2 && s[0] == '0':
+		base, n, s = 8, NotationOctal, s[1:]
+	}
+	if len(s) == 0 || s[0] == '+' || s[0] == '-' {
+		return 0, 0, errors.New("empty or signed part")
+	}
+	v, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid part %s", s)
+	}
+	return v, n, nil
+}
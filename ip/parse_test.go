@@ -0,0 +1,53 @@
+package ip_test
+
+import (
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLenient_WithObfuscatedAddress_ShouldReturnCanonicalAddress(t *testing.T) {
+	for s, expected := range map[string]struct {
+		addr     string
+		notation ip.Notation
+	}{
+		"127.0.0.1":              {"127.0.0.1", 0},
+		"127.1":                  {"127.0.0.1", ip.NotationShorthand},
+		"127.0.1":                {"127.0.0.1", ip.NotationShorthand},
+		"0x7f.1":                 {"127.0.0.1", ip.NotationShorthand | ip.NotationHex},
+		"0X7F000001":             {"127.0.0.1", ip.NotationInteger | ip.NotationHex},
+		"017700000001":           {"127.0.0.1", ip.NotationInteger | ip.NotationOctal},
+		"2130706433":             {"127.0.0.1", ip.NotationInteger},
+		"0177.0.0.01":            {"127.0.0.1", ip.NotationOctal},
+		"0x.0.0.0":               {"0.0.0.0", ip.NotationHex},
+		"192.168.0.1.":           {"192.168.0.1", ip.NotationTrailingDot},
+		"[::ffff:7f00:1]":        {"127.0.0.1", ip.NotationBrackets | ip.NotationIPv4Mapped},
+		"::ffff:127.0.0.1":       {"127.0.0.1", ip.NotationIPv4Mapped},
+		"[fe80::1%eth0]":         {"fe80::1%eth0", ip.NotationBrackets | ip.NotationZone},
+		"2001:db8::1":            {"2001:db8::1", 0},
+		"2001:DB8:0:0:0:0:0:001": {"2001:db8::1", ip.NotationNonCanonicalIPv6},
+	} {
+		addr, notation, err := ip.ParseLenient(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected.addr, addr.String(), s)
+		require.Equal(t, expected.notation, notation, s)
+	}
+}
+
+func TestParseLenient_WithInvalidAddress_ShouldReturnError(t *testing.T) {
+	for _, s := range []string{
+		"", "256.0.0.1", "1.2.3.4.5", "127.16777216", "4294967296", "08.0.0.1", "0x100.0.0.1", "1..2", "-1.0.0.0",
+		"+1.0.0.0", "example.com", "[127.0.0.1]", "::g", "[::1", "1.2.3.4..",
+	} {
+		_, _, err := ip.ParseLenient(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestNotation_String(t *testing.T) {
+	require.Equal(t, "", ip.Notation(0).String())
+	require.Equal(t, "shorthand,hex", (ip.NotationShorthand | ip.NotationHex).String())
+	require.True(t, (ip.NotationShorthand | ip.NotationHex).Has(ip.NotationHex))
+	require.False(t, ip.NotationShorthand.Has(ip.NotationHex))
+}
@@ -0,0 +1,219 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// Verdict is the classification of an IP address by a policy
+type Verdict int
+
+// Verdicts of a policy
+const (
+	VerdictAllow Verdict = iota
+	VerdictDeny
+)
+
+// String returns the name of the verdict
+func (v Verdict) String() string {
+	switch v {
+	case VerdictAllow:
+		return "allow"
+	case VerdictDeny:
+		return "deny"
+	default:
+		return fmt.Sprintf("Verdict(%d)", int(v))
+	}
+}
+
+// Decision is the result of checking an IP address against a policy
+type Decision struct {
+	Verdict Verdict
+	// Prefix is the prefix of the policy deciding the verdict, or the zero value if no prefix contains the address
+	Prefix netip.Prefix
+	// Reason describes why the verdict was reached, such as "denied by 10.0.0.0/8 (Private-Use, RFC 1918)"
+	Reason string
+	// Embedded is the IPv4 address embedded in the IPv6 address, if the verdict is based on it
+	Embedded netip.Addr
+}
+
+// Policy classifies IP addresses by lists of allowed and denied prefixes, where the most specific prefix containing
+// an address decides the verdict, and addresses not in any prefix are allowed
+//
+// IPv4-mapped IPv6 addresses are checked as IPv4 addresses. For IPv6 addresses embedding an IPv4 address (see
+// EmbeddedIPv4), both addresses are checked, and the address is denied if either is denied. A policy is immutable and
+// safe for concurrent use.
+type Policy struct {
+	rules Trie[Decision]
+}
+
+// NewPolicy returns a policy of the allowed and denied prefixes, where denying takes precedence if a prefix is in
+// both lists
+//
+// Allowing a prefix within a denied prefix makes an exception, such as allowing 10.1.0.0/16 and denying 10.0.0.0/8.
+// Invalid prefixes are ignored.
+func NewPolicy(allow, deny []netip.Prefix) *Policy {
+	p := &Policy{}
+	for _, prefix := range allow {
+		p.add(prefix, VerdictAllow)
+	}
+	for _, prefix := range deny {
+		p.add(prefix, VerdictDeny)
+	}
+	return p
+}
+
+func (p *Policy) add(prefix netip.Prefix, v Verdict) {
+	prefix, ok := normalizePrefix(prefix)
+	if !ok {
+		return
+	}
+	reason := "allowed by "
+	if v == VerdictDeny {
+		reason = "denied by "
+	}
+	reason += prefix.String()
+	if r, ok := describePrefix(prefix); ok {
+		reason += " (" + r.Name + ", " + r.RFC + ")"
+	}
+	p.rules.Insert(prefix, Decision{Verdict: v, Prefix: prefix, Reason: reason})
+}
+
+// describePrefix returns the reserved or special-purpose range with exactly the prefix, if there is one
+func describePrefix(p netip.Prefix) (Range, bool) {
+	if r, ok := reserved.Get(p); ok {
+		return r, true
+	}
+	return SpecialPurposeRegistry().trie.Get(p)
+}
+
+// Check returns the decision of the policy for the IP address, which is deny if the address is invalid
+func (p *Policy) Check(addr netip.Addr) Decision {
+	if !addr.IsValid() {
+		return Decision{Verdict: VerdictDeny, Reason: "invalid IP address"}
+	}
+	d := p.check(addr)
+	if d.Verdict == VerdictDeny {
+		return d
+	}
+	if v4, e, ok := EmbeddedIPv4(addr); ok && e != EmbeddingMapped {
+		if embedded := p.check(v4); embedded.Verdict == VerdictDeny {
+			embedded.Embedded = v4
+			embedded.Reason = "embedded " + v4.String() + " of " + e.String() + " address " + embedded.Reason
+			return embedded
+		}
+	}
+	return d
+}
+
+func (p *Policy) check(addr netip.Addr) Decision {
+	if _, d, ok := p.rules.Lookup(addr); ok {
+		return d
+	}
+	return Decision{Verdict: VerdictAllow, Reason: "not in any prefix"}
+}
+
+// CheckIP returns the decision of the policy for the IP address, which is deny if the address is invalid
+func (p *Policy) CheckIP(ip net.IP) Decision {
+	addr, _ := AddrFromIP(ip)
+	return p.Check(addr)
+}
+
+// Allows returns whether the policy allows the IP address
+func (p *Policy) Allows(addr netip.Addr) bool {
+	if !addr.IsValid() || p.check(addr).Verdict == VerdictDeny {
+		return false
+	}
+	v4, e, ok := EmbeddedIPv4(addr)
+	return !ok || e == EmbeddingMapped || p.check(v4).Verdict == VerdictAllow
+}
+
+// Validate indicates whether the IP address is valid and allowed by the policy
+func (p *Policy) Validate(addr netip.Addr) error {
+	if err := ValidateAddr(addr); err != nil {
+		return err
+	}
+	if d := p.Check(addr); d.Verdict != VerdictAllow {
+		return fmt.Errorf("%s is not allowed: %s", addr.String(), d.Reason)
+	}
+	return nil
+}
+
+// denies returns whether the most specific prefix of the policy containing all IP addresses of the prefix is denied
+func (p *Policy) denies(prefix netip.Prefix) bool {
+	_, d, ok := p.rules.LookupPrefix(prefix)
+	return ok && d.Verdict == VerdictDeny
+}
+
+// Preset policies, which are created when first used
+var (
+	defaultPolicy *Policy
+
+	strictSSRFPolicy     *Policy
+	strictSSRFPolicyOnce sync.Once
+	rfc1918Policy        *Policy
+	rfc1918PolicyOnce    sync.Once
+	bogonPolicy          *Policy
+	bogonPolicyOnce      sync.Once
+)
+
+// DefaultPolicy returns the policy denying the reserved ranges, which the package functions such as IsReservedAddr
+// are based on
+func DefaultPolicy() *Policy {
+	return defaultPolicy
+}
+
+// StrictSSRFPolicy returns a policy for preventing server-side request forgery, which denies the reserved ranges and
+// all special-purpose ranges that are not globally reachable
+func StrictSSRFPolicy() *Policy {
+	strictSSRFPolicyOnce.Do(func() {
+		var deny []netip.Prefix
+		for _, r := range reservedRanges {
+			deny = append(deny, r.Prefix)
+		}
+		for _, r := range SpecialPurposeRegistry().Ranges() {
+			// the IPv4-mapped range would deny all IPv4 addresses, which are checked on their own
+			if !r.GloballyReachable && !r.Prefix.Addr().Is4In6() {
+				deny = append(deny, r.Prefix)
+			}
+		}
+		strictSSRFPolicy = NewPolicy(nil, deny)
+	})
+	return strictSSRFPolicy
+}
+
+// RFC1918Policy returns a policy denying only the private-use IPv4 ranges of RFC 1918
+func RFC1918Policy() *Policy {
+	rfc1918PolicyOnce.Do(func() {
+		rfc1918Policy = NewPolicy(nil, []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("172.16.0.0/12"),
+			netip.MustParsePrefix("192.168.0.0/16"),
+		})
+	})
+	return rfc1918Policy
+}
+
+// BogonPolicy returns a policy denying bogons, i.e. addresses which should not be routed on the internet: the
+// reserved and special-purpose IPv4 ranges which are not globally reachable, and all IPv6 addresses outside the
+// global unicast range 2000::/3 and its reserved ranges
+func BogonPolicy() *Policy {
+	bogonPolicyOnce.Do(func() {
+		allow := []netip.Prefix{netip.MustParsePrefix("2000::/3")}
+		deny := []netip.Prefix{netip.MustParsePrefix("::/0")}
+		for _, r := range reservedRanges {
+			if r.Prefix.Addr().Is4() || r.Prefix.Overlaps(allow[0]) {
+				deny = append(deny, r.Prefix)
+			}
+		}
+		for _, r := range SpecialPurposeRegistry().Ranges() {
+			if !r.GloballyReachable && (r.Prefix.Addr().Is4() || r.Prefix.Overlaps(allow[0])) {
+				deny = append(deny, r.Prefix)
+			}
+		}
+		bogonPolicy = NewPolicy(allow, deny)
+	})
+	return bogonPolicy
+}
@@ -0,0 +1,91 @@
+package ip_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_WithAllowAndDenyLists_ShouldReturnMostSpecificVerdict(t *testing.T) {
+	p := ip.NewPolicy(
+		[]netip.Prefix{netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("192.0.2.0/24")},
+		[]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("10.1.2.0/24"),
+			netip.MustParsePrefix("192.0.2.0/24")},
+	)
+
+	d := p.Check(netip.MustParseAddr("10.0.0.1"))
+	require.Equal(t, ip.VerdictDeny, d.Verdict)
+	require.Equal(t, "10.0.0.0/8", d.Prefix.String())
+	require.Equal(t, "denied by 10.0.0.0/8 (Private-Use, RFC 1918)", d.Reason)
+
+	d = p.Check(netip.MustParseAddr("::ffff:10.1.0.1"))
+	require.Equal(t, ip.VerdictAllow, d.Verdict)
+	require.Equal(t, "allowed by 10.1.0.0/16", d.Reason)
+
+	require.False(t, p.Allows(netip.MustParseAddr("10.1.2.3")))
+	require.False(t, p.Allows(netip.MustParseAddr("192.0.2.1")))
+	require.True(t, p.Allows(netip.MustParseAddr("8.8.8.8")))
+	require.False(t, p.Check(netip.MustParseAddr("8.8.8.8")).Prefix.IsValid())
+	require.Equal(t, ip.VerdictDeny, p.CheckIP(nil).Verdict)
+	require.Equal(t, ip.VerdictDeny, p.CheckIP(net.ParseIP("10.0.0.1")).Verdict)
+
+	require.NoError(t, p.Validate(netip.MustParseAddr("8.8.8.8")))
+	require.EqualError(t, p.Validate(netip.MustParseAddr("10.0.0.1")),
+		"10.0.0.1 is not allowed: denied by 10.0.0.0/8 (Private-Use, RFC 1918)")
+	require.Error(t, p.Validate(netip.Addr{}))
+}
+
+func TestPolicy_WithPresets_ShouldClassifyAddresses(t *testing.T) {
+	for _, test := range []struct {
+		policy  *ip.Policy
+		denied  []string
+		allowed []string
+	}{
+		{
+			policy: ip.DefaultPolicy(),
+			denied: []string{"10.0.0.1", "127.0.0.1", "224.0.0.1", "::1", "fe80::1", "2001:db8::1", "192.31.196.1",
+				"192.52.193.1", "2001:3::1"},
+			allowed: []string{"8.8.8.8", "1.1.1.1", "2606:4700::1111"},
+		},
+		{
+			policy: ip.StrictSSRFPolicy(),
+			denied: []string{"10.0.0.1", "192.0.0.9", "::", "2001:2::1", "5f00::1", "3fff::1", "192.31.196.1",
+				"192.52.193.1"},
+			allowed: []string{"8.8.8.8", "1.1.1.1", "::ffff:8.8.8.8", "2606:4700::1111"},
+		},
+		{
+			policy:  ip.RFC1918Policy(),
+			denied:  []string{"10.0.0.1", "172.16.0.1", "::ffff:192.168.1.1"},
+			allowed: []string{"8.8.8.8", "127.0.0.1", "100.64.0.1", "fc00::1"},
+		},
+		{
+			policy:  ip.BogonPolicy(),
+			denied:  []string{"10.0.0.1", "240.0.0.1", "::1", "fc00::1", "5f00::1", "2001:db8::1", "3fff::1"},
+			allowed: []string{"8.8.8.8", "2606:4700::1111", "2a00:1450::1"},
+		},
+	} {
+		for _, addr := range test.denied {
+			require.False(t, test.policy.Allows(netip.MustParseAddr(addr)), addr)
+		}
+		for _, addr := range test.allowed {
+			require.True(t, test.policy.Allows(netip.MustParseAddr(addr)), addr)
+		}
+	}
+}
+
+func TestDefaultPolicy_WithModifiedRanges_ShouldNotChange(t *testing.T) {
+	prefixes := ip.ReservedIPv4Prefixes
+	ip.ReservedIPv4Prefixes = nil
+	defer func() { ip.ReservedIPv4Prefixes = prefixes }()
+
+	require.True(t, ip.IsReservedAddr(netip.MustParseAddr("10.0.0.1")))
+	require.False(t, ip.DefaultPolicy().Allows(netip.MustParseAddr("10.0.0.1")))
+}
+
+func TestVerdict_String(t *testing.T) {
+	require.Equal(t, "allow", ip.VerdictAllow.String())
+	require.Equal(t, "deny", ip.VerdictDeny.String())
+}
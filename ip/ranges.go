@@ -0,0 +1,201 @@
+package ip
+
+import (
+	"net"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// Scope is the scope an address range is used in, based on https://en.wikipedia.org/wiki/Reserved_IP_addresses
+type Scope string
+
+// Scopes of address ranges
+const (
+	ScopeSoftware       Scope = "software"
+	ScopeHost           Scope = "host"
+	ScopeSubnet         Scope = "subnet"
+	ScopePrivateNetwork Scope = "private network"
+	ScopeDocumentation  Scope = "documentation"
+	ScopeRouting        Scope = "routing"
+	ScopeInternet       Scope = "internet"
+)
+
+// Range holds an address range with its purpose, as specified in the IANA IPv4 and IPv6 Special-Purpose Address
+// Registries (RFC 6890)
+type Range struct {
+	Prefix netip.Prefix
+	// Name is the purpose of the range, such as "Private-Use" or "Loopback"
+	Name string
+	// RFC is the document defining the range, such as "RFC 1918"
+	RFC string
+	// Scope is where the range is used, which is not part of the registries
+	Scope Scope
+	// Source indicates an address of the range is valid as the source address of a packet
+	Source bool
+	// Destination indicates an address of the range is valid as the destination address of a packet
+	Destination bool
+	// Forwardable indicates a router may forward a packet with an address of the range
+	Forwardable bool
+	// GloballyReachable indicates a destination address of the range is reachable beyond the local administrative
+	// domain
+	GloballyReachable bool
+	// ReservedByProtocol indicates the range is reserved by the IP protocol itself, rather than by assignment
+	ReservedByProtocol bool
+	// Allocated is the month the range was allocated, if known
+	Allocated time.Time
+	// Terminated is the month the range was deprecated, or zero if it is in use
+	Terminated time.Time
+}
+
+// reservedRanges holds the reserved ranges with their metadata, in order of address, IPv4 before IPv6
+var reservedRanges = reservedFromRegistry(SpecialPurposeRegistry().Ranges())
+
+// reservedReachable holds the globally reachable ranges of the registries which are reserved, as their addresses are
+// anycast services of the DNS and multicast infrastructure or translate to IPv4 addresses, rather than hosts
+var reservedReachable = []netip.Prefix{
+	netip.MustParsePrefix("192.31.196.0/24"),   // AS112-v4
+	netip.MustParsePrefix("192.52.193.0/24"),   // AMT
+	netip.MustParsePrefix("192.175.48.0/24"),   // Direct Delegation AS112 Service
+	netip.MustParsePrefix("64:ff9b::/96"),      // IPv4-IPv6 Translation
+	netip.MustParsePrefix("2001:3::/32"),       // AMT
+	netip.MustParsePrefix("2001:4:112::/48"),   // AS112-v6
+	netip.MustParsePrefix("2620:4f:8000::/48"), // Direct Delegation AS112 Service
+}
+
+// reservedExtras holds the reserved ranges which are not in the registries
+var reservedExtras = []Range{
+	{Prefix: netip.MustParsePrefix("224.0.0.0/4"), Name: "Multicast",
+		RFC: "RFC 5771", Scope: ScopeInternet,
+		Destination: true, Forwardable: true},
+	{Prefix: netip.MustParsePrefix("::ffff:0:0:0/96"), Name: "IPv4-translated Address",
+		RFC: "RFC 2765", Scope: ScopeSoftware,
+		ReservedByProtocol: true},
+	{Prefix: netip.MustParsePrefix("ff00::/8"), Name: "Multicast",
+		RFC: "RFC 4291", Scope: ScopeInternet,
+		Destination: true, Forwardable: true},
+}
+
+// reservedScopes holds the scopes of the reserved ranges of the registries, which is the scope of the enclosing
+// reserved range if not listed, or ScopeInternet if there is none
+var reservedScopes = map[netip.Prefix]Scope{
+	netip.MustParsePrefix("0.0.0.0/8"):          ScopeSoftware,
+	netip.MustParsePrefix("10.0.0.0/8"):         ScopePrivateNetwork,
+	netip.MustParsePrefix("100.64.0.0/10"):      ScopePrivateNetwork,
+	netip.MustParsePrefix("127.0.0.0/8"):        ScopeHost,
+	netip.MustParsePrefix("169.254.0.0/16"):     ScopeSubnet,
+	netip.MustParsePrefix("172.16.0.0/12"):      ScopePrivateNetwork,
+	netip.MustParsePrefix("192.0.0.0/24"):       ScopePrivateNetwork,
+	netip.MustParsePrefix("192.0.2.0/24"):       ScopeDocumentation,
+	netip.MustParsePrefix("192.168.0.0/16"):     ScopePrivateNetwork,
+	netip.MustParsePrefix("198.18.0.0/15"):      ScopePrivateNetwork,
+	netip.MustParsePrefix("198.51.100.0/24"):    ScopeDocumentation,
+	netip.MustParsePrefix("203.0.113.0/24"):     ScopeDocumentation,
+	netip.MustParsePrefix("255.255.255.255/32"): ScopeSubnet,
+	netip.MustParsePrefix("::/128"):             ScopeSoftware,
+	netip.MustParsePrefix("::1/128"):            ScopeHost,
+	netip.MustParsePrefix("64:ff9b:1::/48"):     ScopePrivateNetwork,
+	netip.MustParsePrefix("100::/64"):           ScopeRouting,
+	netip.MustParsePrefix("2001::/23"):          ScopePrivateNetwork,
+	netip.MustParsePrefix("2001::/32"):          ScopeInternet,
+	netip.MustParsePrefix("2001:2::/48"):        ScopePrivateNetwork,
+	netip.MustParsePrefix("2001:3::/32"):        ScopeInternet,
+	netip.MustParsePrefix("2001:4:112::/48"):    ScopeInternet,
+	netip.MustParsePrefix("2001:10::/28"):       ScopeSoftware,
+	netip.MustParsePrefix("2001:20::/28"):       ScopeSoftware,
+	netip.MustParsePrefix("2001:db8::/32"):      ScopeDocumentation,
+	netip.MustParsePrefix("3fff::/20"):          ScopeDocumentation,
+	netip.MustParsePrefix("5f00::/16"):          ScopeRouting,
+	netip.MustParsePrefix("fc00::/7"):           ScopePrivateNetwork,
+	netip.MustParsePrefix("fe80::/10"):          ScopeSubnet,
+}
+
+// reservedFromRegistry returns the reserved ranges of the special-purpose ranges, in order of address, which are the
+// ranges not globally reachable (including terminated ranges), the ranges of reservedReachable, the ranges within
+// those, and reservedExtras
+//
+// The IPv4-mapped range is not reserved, as IPv4-mapped addresses are checked as IPv4 addresses.
+func reservedFromRegistry(ranges []Range) []Range {
+	var result []Range
+	for _, r := range ranges {
+		if isIPv4MappedPrefix(r.Prefix) {
+			continue
+		}
+		if !r.GloballyReachable || containsPrefix(reservedReachable, r.Prefix) {
+			result = append(result, r)
+		}
+	}
+	reserved := len(result)
+	for _, r := range ranges {
+		if r.GloballyReachable && !containsPrefix(reservedReachable, r.Prefix) &&
+			enclosingRange(result[:reserved], r.Prefix) >= 0 {
+			result = append(result, r)
+		}
+	}
+	result = append(result, reservedExtras...)
+	sort.SliceStable(result, func(i, j int) bool {
+		if c := result[i].Prefix.Addr().Compare(result[j].Prefix.Addr()); c != 0 {
+			return c < 0
+		}
+		return result[i].Prefix.Bits() < result[j].Prefix.Bits()
+	})
+
+	for i := range result {
+		if len(result[i].Scope) > 0 {
+			continue
+		}
+		result[i].Scope = ScopeInternet
+		if scope, ok := reservedScopes[result[i].Prefix]; ok {
+			result[i].Scope = scope
+		} else if enclosing := enclosingRange(result[:i], result[i].Prefix); enclosing >= 0 {
+			// the enclosing range precedes the range, hence its scope is set
+			result[i].Scope = result[enclosing].Scope
+		}
+	}
+	return result
+}
+
+func isIPv4MappedPrefix(p netip.Prefix) bool {
+	return p.Addr().Is4In6() && p.Bits() == 96
+}
+
+func containsPrefix(prefixes []netip.Prefix, p netip.Prefix) bool {
+	for _, q := range prefixes {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingRange returns the index of the last of the ranges containing the prefix, or -1 if none
+func enclosingRange(ranges []Range, p netip.Prefix) int {
+	for i := len(ranges) - 1; i >= 0; i-- {
+		if q := ranges[i].Prefix; q != p && q.Bits() <= p.Bits() && q.Contains(p.Addr()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReservedRanges returns the reserved ranges with their metadata, IPv4 before IPv6
+func ReservedRanges() []Range {
+	return append([]Range(nil), reservedRanges...)
+}
+
+// LookupReservedIP returns the reserved range containing the IP address, and whether there is one
+func LookupReservedIP(ip net.IP) (Range, bool) {
+	addr, ok := AddrFromIP(ip)
+	if !ok {
+		return Range{}, false
+	}
+	return LookupReservedAddr(addr)
+}
+
+// LookupReservedAddr returns the reserved range containing the IP address, and whether there is one
+//
+// IPv4-mapped IPv6 addresses are looked up as IPv4 addresses.
+func LookupReservedAddr(addr netip.Addr) (Range, bool) {
+	_, r, ok := reserved.Lookup(addr)
+	return r, ok
+}
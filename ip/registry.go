@@ -0,0 +1,228 @@
+package ip
+
+import (
+	"bytes"
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IANA Special-Purpose Address Registry files, as published at https://www.iana.org/assignments/iana-ipv4-special-registry
+// and https://www.iana.org/assignments/iana-ipv6-special-registry
+const (
+	IPv4RegistryFile = "iana-ipv4-special-registry-1.csv"
+	IPv6RegistryFile = "iana-ipv6-special-registry-1.csv"
+)
+
+//go:embed registry/*.csv
+var registryFiles embed.FS
+
+// defaultRegistry holds the snapshot of the IANA registries, which is parsed when first used
+var (
+	defaultRegistry     *Registry
+	defaultRegistryOnce sync.Once
+)
+
+func loadDefaultRegistry() {
+	var ranges []Range
+	for _, name := range []string{IPv4RegistryFile, IPv6RegistryFile} {
+		b, err := registryFiles.ReadFile("registry/" + name)
+		if err != nil {
+			panic(err)
+		}
+		r, err := ParseRegistry(bytes.NewReader(b))
+		if err != nil {
+			panic(fmt.Sprintf("invalid %s: %v", name, err))
+		}
+		ranges = append(ranges, r...)
+	}
+	defaultRegistry = NewRegistry(ranges)
+}
+
+// Registry holds special-purpose address ranges, and finds the most specific range containing an IP address
+//
+// A registry is immutable and safe for concurrent use.
+type Registry struct {
+	ranges []Range
+	trie   Trie[Range]
+}
+
+// NewRegistry returns a registry of the ranges, where a later range replaces an earlier one with the same prefix
+func NewRegistry(ranges []Range) *Registry {
+	r := &Registry{ranges: append([]Range(nil), ranges...)}
+	for _, rng := range r.ranges {
+		if rng.Prefix.Addr().Is4In6() && rng.Prefix.Bits() == 96 {
+			// IPv4-mapped addresses are looked up as IPv4, so the range would contain all IPv4 addresses
+			continue
+		}
+		r.trie.Insert(rng.Prefix, rng)
+	}
+	return r
+}
+
+// SpecialPurposeRegistry returns the registry of the IANA IPv4 and IPv6 Special-Purpose Address Registries, from the
+// snapshot included in the package
+func SpecialPurposeRegistry() *Registry {
+	defaultRegistryOnce.Do(loadDefaultRegistry)
+	return defaultRegistry
+}
+
+// LoadRegistry returns a registry of the ranges of the IANA Special-Purpose Address Registry CSV files, such as a
+// more recent copy of IPv4RegistryFile and IPv6RegistryFile
+func LoadRegistry(files ...string) (*Registry, error) {
+	var ranges []Range
+	for _, name := range files {
+		r, err := readRegistryFile(name)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r...)
+	}
+	return NewRegistry(ranges), nil
+}
+
+func readRegistryFile(name string) ([]Range, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranges, err := ParseRegistry(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return ranges, nil
+}
+
+// Ranges returns the ranges of the registry, in the order they were added
+func (r *Registry) Ranges() []Range {
+	return append([]Range(nil), r.ranges...)
+}
+
+// Lookup returns the most specific range containing the IP address, and whether there is one
+//
+// IPv4-mapped IPv6 addresses are looked up as IPv4 addresses, i.e. the IPv4-mapped address range itself never matches.
+func (r *Registry) Lookup(addr netip.Addr) (Range, bool) {
+	_, rng, ok := r.trie.Lookup(addr)
+	return rng, ok
+}
+
+// registry columns, as named in the header of the CSV files
+var registryColumns = []string{
+	"Address Block", "Name", "RFC", "Allocation Date", "Termination Date",
+	"Source", "Destination", "Forwardable", "Globally Reachable", "Reserved-by-Protocol",
+}
+
+var (
+	footnotePattern = regexp.MustCompile(`\s*\[\d+\]`)
+	rfcPattern      = regexp.MustCompile(`\[RFC(\d+)\]`)
+)
+
+// ParseRegistry parses an IANA Special-Purpose Address Registry in the CSV format, returning the ranges in order
+//
+// An address block with several prefixes results in a range per prefix, and footnote references are ignored. The
+// attributes of terminated ranges, and those not applicable ("N/A"), are false. Returns an error if a column is
+// missing, or an address block, date or attribute is invalid.
+func ParseRegistry(r io.Reader) ([]Range, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range registryColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+
+	var ranges []Range
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return ranges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		r, err := parseRegistryRecord(record, columns)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranges = append(ranges, r...)
+	}
+}
+
+func parseRegistryRecord(record []string, columns map[string]int) ([]Range, error) {
+	field := func(name string) string {
+		return strings.TrimSpace(footnotePattern.ReplaceAllString(record[columns[name]], ""))
+	}
+
+	rng := Range{Name: strings.Trim(field("Name"), `"`)}
+	var rfcs []string
+	for _, m := range rfcPattern.FindAllStringSubmatch(record[columns["RFC"]], -1) {
+		rfcs = append(rfcs, "RFC "+m[1])
+	}
+	rng.RFC = strings.Join(rfcs, ", ")
+
+	var err error
+	if rng.Allocated, err = parseRegistryDate(field("Allocation Date")); err != nil {
+		return nil, err
+	}
+	if rng.Terminated, err = parseRegistryDate(field("Termination Date")); err != nil {
+		return nil, err
+	}
+	for name, attr := range map[string]*bool{
+		"Source":               &rng.Source,
+		"Destination":          &rng.Destination,
+		"Forwardable":          &rng.Forwardable,
+		"Globally Reachable":   &rng.GloballyReachable,
+		"Reserved-by-Protocol": &rng.ReservedByProtocol,
+	} {
+		switch v := field(name); v {
+		case "True":
+			*attr = true
+		case "False", "N/A", "":
+		default:
+			return nil, fmt.Errorf("invalid %s %s", name, v)
+		}
+	}
+
+	var ranges []Range
+	for _, block := range strings.Split(field("Address Block"), ",") {
+		p, err := netip.ParsePrefix(strings.TrimSpace(block))
+		if err != nil {
+			return nil, fmt.Errorf("invalid address block %s", block)
+		}
+		rng.Prefix = p.Masked()
+		ranges = append(ranges, rng)
+	}
+	return ranges, nil
+}
+
+func parseRegistryDate(s string) (time.Time, error) {
+	if len(s) == 0 || s == "N/A" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %s", s)
+	}
+	return t, nil
+}
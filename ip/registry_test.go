@@ -0,0 +1,97 @@
+package ip_test
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecialPurposeRegistry_ShouldParseSnapshot(t *testing.T) {
+	ranges := ip.SpecialPurposeRegistry().Ranges()
+	require.NotEmpty(t, ranges)
+	for _, r := range ranges {
+		require.True(t, r.Prefix.IsValid())
+		require.NotEmpty(t, r.Name, r.Prefix.String())
+		require.False(t, r.Allocated.IsZero(), r.Prefix.String())
+	}
+
+	for _, p := range []string{"192.31.196.0/24", "192.52.193.0/24", "2001:2::/48", "5f00::/16"} {
+		_, ok := ip.SpecialPurposeRegistry().Lookup(netip.MustParsePrefix(p).Addr())
+		require.True(t, ok, p)
+	}
+}
+
+func TestRegistry_Lookup_ShouldReturnMostSpecificRange(t *testing.T) {
+	registry := ip.SpecialPurposeRegistry()
+
+	r, ok := registry.Lookup(netip.MustParseAddr("192.0.0.9"))
+	require.True(t, ok)
+	require.Equal(t, "Port Control Protocol Anycast", r.Name)
+	require.Equal(t, "RFC 7723", r.RFC)
+	require.True(t, r.GloballyReachable)
+
+	r, ok = registry.Lookup(netip.MustParseAddr("192.0.0.171"))
+	require.True(t, ok)
+	require.Equal(t, "NAT64/DNS64 Discovery", r.Name)
+	require.Equal(t, "RFC 8880, RFC 7050", r.RFC)
+
+	r, ok = registry.Lookup(netip.MustParseAddr("127.0.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "This network", registry.Ranges()[0].Name)
+	require.Equal(t, "Loopback", r.Name)
+	require.False(t, r.Source)
+	require.True(t, r.ReservedByProtocol)
+
+	r, ok = registry.Lookup(netip.MustParseAddr("192.88.99.1"))
+	require.True(t, ok)
+	require.Equal(t, 2015, r.Terminated.Year())
+	require.False(t, r.Destination)
+
+	r, ok = registry.Lookup(netip.MustParseAddr("2002::1"))
+	require.True(t, ok)
+	require.Equal(t, "6to4", r.Name)
+	require.False(t, r.GloballyReachable)
+
+	_, ok = registry.Lookup(netip.MustParseAddr("::ffff:8.8.8.8"))
+	require.False(t, ok)
+	_, ok = registry.Lookup(netip.MustParseAddr("2606:4700::1111"))
+	require.False(t, ok)
+}
+
+func TestParseRegistry_WithInvalidRegistry_ShouldReturnError(t *testing.T) {
+	header := "Address Block,Name,RFC,Allocation Date,Termination Date,Source,Destination,Forwardable," +
+		"Globally Reachable,Reserved-by-Protocol\n"
+	for _, registry := range []string{
+		"",
+		"Address Block,Name\n",
+		header + "10.0.0.0/33,Private-Use,[RFC1918],1996-02,N/A,True,True,True,False,False\n",
+		header + "10.0.0.0/8,Private-Use,[RFC1918],1996,N/A,True,True,True,False,False\n",
+		header + "10.0.0.0/8,Private-Use,[RFC1918],1996-02,N/A,Yes,True,True,False,False\n",
+		header + "10.0.0.0/8,Private-Use,[RFC1918]\n",
+	} {
+		_, err := ip.ParseRegistry(strings.NewReader(registry))
+		require.Error(t, err, registry)
+	}
+}
+
+func TestLoadRegistry_WithFiles_ShouldReturnRegistry(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ip.IPv4RegistryFile)
+	require.NoError(t, os.WriteFile(name, []byte("Address Block,Name,RFC,Allocation Date,Termination Date,Source,"+
+		"Destination,Forwardable,Globally Reachable,Reserved-by-Protocol\n"+
+		"\"10.0.0.0/8 [1], 10.1.0.0/16\",Private-Use,[RFC1918],1996-02,N/A,True,True,True,False [1],False\n"), 0o600))
+
+	registry, err := ip.LoadRegistry(name)
+	require.NoError(t, err)
+	require.Len(t, registry.Ranges(), 2)
+	r, ok := registry.Lookup(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "10.1.0.0/16", r.Prefix.String())
+
+	_, err = ip.LoadRegistry(filepath.Join(t.TempDir(), "missing.csv"))
+	require.Error(t, err)
+}
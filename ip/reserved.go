@@ -2,61 +2,64 @@ package ip
 
 import (
 	"net"
+	"net/netip"
 )
 
-// List of reserved IP address ranges based on https://en.wikipedia.org/wiki/Reserved_IP_addresses
+// List of reserved IP address ranges, derived from the IANA Special-Purpose Address Registries
+//
+// The lists are derived from ReservedRanges, and modifying them has no effect on the reserved checks.
 var (
 	// ReservedIPv4Ranges holds the reserved IPv4 ranges
+	//
+	// Deprecated: Use IsReservedIPv4 to check an address, or LookupReservedIP to find its range.
 	ReservedIPv4Ranges []net.IPNet
 
+	// ReservedIPv4Prefixes holds the reserved IPv4 ranges as prefixes
+	//
+	// Deprecated: Use DefaultPolicy().Check, which also returns the denying prefix, or IsReservedIPv4Addr.
+	ReservedIPv4Prefixes []netip.Prefix
+
 	// ReservedIPv4RangeStrings holds the reserved IPv4 ranges in string format
-	ReservedIPv4RangeStrings = []string{
-		"0.0.0.0/8",
-		"10.0.0.0/8",
-		"100.64.0.0/10",
-		"127.0.0.0/8",
-		"169.254.0.0/16",
-		"172.16.0.0/12",
-		"192.0.0.0/24",
-		"192.0.2.0/24",
-		"192.88.99.0/24",
-		"192.168.0.0/16",
-		"198.18.0.0/15",
-		"198.51.100.0/24",
-		"203.0.113.0/24",
-		"224.0.0.0/4",
-		"240.0.0.0/4",
-		"255.255.255.255/32",
-	}
+	//
+	// Deprecated: Use ReservedRanges, whose Prefix fields hold the ranges.
+	ReservedIPv4RangeStrings []string
 
 	// ReservedIPv6Ranges holds the reserved IPv6 ranges
+	//
+	// Deprecated: Use IsReservedIPv6 to check an address, or LookupReservedIP to find its range.
 	ReservedIPv6Ranges []net.IPNet
 
+	// ReservedIPv6Prefixes holds the reserved IPv6 ranges as prefixes
+	//
+	// Deprecated: Use DefaultPolicy().Check, which also returns the denying prefix, or IsReservedIPv6Addr.
+	ReservedIPv6Prefixes []netip.Prefix
+
 	// ReservedIPv6RangeStrings holds the reserved IPv6 ranges in string format
-	ReservedIPv6RangeStrings = []string{
-		"::1/128",
-		"::ffff:0:0:0/96",
-		"64:ff9b::/96",
-		"100::/64",
-		"2001::/32",
-		"2001:20::/28",
-		"2001:db8::/32",
-		"2002::/16",
-		"fc00::/7",
-		"fe80::/10",
-		"ff00::/8",
-	}
+	//
+	// Deprecated: Use ReservedRanges, whose Prefix fields hold the ranges.
+	ReservedIPv6RangeStrings []string
 )
 
+// reserved holds the reserved IPv4 and IPv6 ranges with their metadata
+var reserved Trie[Range]
+
 func init() {
-	for _, r := range ReservedIPv4RangeStrings {
-		_, ipNet, _ := net.ParseCIDR(r)
-		ReservedIPv4Ranges = append(ReservedIPv4Ranges, *ipNet)
-	}
-	for _, r := range ReservedIPv6RangeStrings {
-		_, ipNet, _ := net.ParseCIDR(r)
-		ReservedIPv6Ranges = append(ReservedIPv6Ranges, *ipNet)
+	var prefixes []netip.Prefix
+	for _, r := range reservedRanges {
+		p := r.Prefix
+		if p.Addr().Is4() {
+			ReservedIPv4RangeStrings = append(ReservedIPv4RangeStrings, p.String())
+			ReservedIPv4Prefixes = append(ReservedIPv4Prefixes, p)
+			ReservedIPv4Ranges = append(ReservedIPv4Ranges, IPNetFromPrefix(p))
+		} else {
+			ReservedIPv6RangeStrings = append(ReservedIPv6RangeStrings, p.String())
+			ReservedIPv6Prefixes = append(ReservedIPv6Prefixes, p)
+			ReservedIPv6Ranges = append(ReservedIPv6Ranges, IPNetFromPrefix(p))
+		}
+		prefixes = append(prefixes, p)
+		reserved.Insert(p, r)
 	}
+	defaultPolicy = NewPolicy(nil, prefixes)
 }
 
 // IsReserved checks if the specified IP address is reserved
@@ -66,39 +69,41 @@ func IsReserved(s string) bool {
 
 // IsReservedIP checks if the specified IP address is reserved
 func IsReservedIP(ip net.IP) bool {
-	if IsReservedIPv4(ip) {
-		return true
-	}
-	if IsReservedIPv6(ip) {
-		return true
-	}
-	return false
+	addr, ok := AddrFromIP(ip)
+	return ok && IsReservedAddr(addr)
 }
 
 // IsReservedIPv4 checks if the specified IP address is reserved
 func IsReservedIPv4(ip net.IP) bool {
-	if ip == nil {
-		return false
-	}
-
-	for _, r := range ReservedIPv4Ranges {
-		if r.Contains(ip) {
-			return true
-		}
-	}
-	return false
+	addr, ok := AddrFromIP(ip)
+	return ok && IsReservedIPv4Addr(addr)
 }
 
 // IsReservedIPv6 checks if the specified IP address is reserved
 func IsReservedIPv6(ip net.IP) bool {
-	if ip == nil {
-		return false
-	}
+	addr, ok := AddrFromIP(ip)
+	return ok && IsReservedIPv6Addr(addr)
+}
 
-	for _, r := range ReservedIPv6Ranges {
-		if r.Contains(ip) {
-			return true
-		}
-	}
-	return false
+// IsReservedAddr checks if the specified IP address is reserved
+//
+// IPv4-mapped IPv6 addresses are checked as IPv4 addresses, and IPv6 addresses embedding a reserved IPv4 address, such
+// as the IPv4-compatible ::127.0.0.1 or the NAT64 64:ff9b::7f00:1, are reserved.
+func IsReservedAddr(addr netip.Addr) bool {
+	return addr.IsValid() && !defaultPolicy.Allows(addr)
+}
+
+// IsReservedIPv4Addr checks if the specified IP address is a reserved IPv4 (or IPv4-mapped IPv6) address
+func IsReservedIPv4Addr(addr netip.Addr) bool {
+	return addr.Unmap().Is4() && !defaultPolicy.Allows(addr)
+}
+
+// IsReservedIPv6Addr checks if the specified IP address is a reserved IPv6 address
+func IsReservedIPv6Addr(addr netip.Addr) bool {
+	return addr.Is6() && !addr.Is4In6() && !defaultPolicy.Allows(addr)
+}
+
+// IsReservedPrefix checks if all IP addresses of the specified prefix are reserved
+func IsReservedPrefix(p netip.Prefix) bool {
+	return defaultPolicy.denies(p)
 }
@@ -1,6 +1,8 @@
 package ip_test
 
 import (
+	"net"
+	"net/netip"
 	"testing"
 
 	"github.com/detectify/n5/ip"
@@ -47,3 +49,138 @@ func TestIsReserved_WithNonReservedIPv6_ShouldReturnFalse(t *testing.T) {
 	require.False(t, ip.IsReserved("1965:0db8:0000:0000:0000:8a2e:0370:7334"))
 	require.False(t, ip.IsReserved("2003::"))
 }
+
+func TestIsReservedAddr_WithReservedAddr_ShouldReturnTrue(t *testing.T) {
+	require.True(t, ip.IsReservedAddr(netip.MustParseAddr("192.168.0.1")))
+	require.True(t, ip.IsReservedAddr(netip.MustParseAddr("::ffff:192.168.0.1")))
+	require.True(t, ip.IsReservedAddr(netip.MustParseAddr("fe80::1%eth0")))
+	require.True(t, ip.IsReservedIPv4Addr(netip.MustParseAddr("127.0.0.1")))
+	require.True(t, ip.IsReservedIPv6Addr(netip.MustParseAddr("2001:db8::1")))
+
+	require.False(t, ip.IsReservedAddr(netip.MustParseAddr("8.8.8.8")))
+	require.False(t, ip.IsReservedAddr(netip.MustParseAddr("::ffff:8.8.8.8")))
+	require.False(t, ip.IsReservedAddr(netip.Addr{}))
+	require.False(t, ip.IsReservedIPv4Addr(netip.MustParseAddr("::1")))
+	require.False(t, ip.IsReservedIPv6Addr(netip.MustParseAddr("127.0.0.1")))
+}
+
+func TestIsReservedAddr_ShouldNotAllocate(t *testing.T) {
+	addrs := []netip.Addr{netip.MustParseAddr("8.8.8.8"), netip.MustParseAddr("2606:4700::1111")}
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, a := range addrs {
+			ip.IsReservedAddr(a)
+		}
+	})
+	require.Zero(t, allocs)
+}
+
+func TestIsReservedPrefix_WithPrefix_ShouldReturnWhetherReserved(t *testing.T) {
+	require.True(t, ip.IsReservedPrefix(netip.MustParsePrefix("10.1.0.0/16")))
+	require.True(t, ip.IsReservedPrefix(netip.MustParsePrefix("10.0.0.0/8")))
+	require.True(t, ip.IsReservedPrefix(netip.MustParsePrefix("2001:db8:1::/48")))
+	require.False(t, ip.IsReservedPrefix(netip.MustParsePrefix("8.0.0.0/7")))
+	require.False(t, ip.IsReservedPrefix(netip.MustParsePrefix("0.0.0.0/0")))
+	require.False(t, ip.IsReservedPrefix(netip.Prefix{}))
+}
+
+func TestReservedPrefixes_ShouldMatchRanges(t *testing.T) {
+	require.Len(t, ip.ReservedIPv4Prefixes, len(ip.ReservedIPv4Ranges))
+	require.Len(t, ip.ReservedIPv4Prefixes, len(ip.ReservedIPv4RangeStrings))
+	for i, p := range ip.ReservedIPv4Prefixes {
+		require.Equal(t, p.String(), ip.ReservedIPv4Ranges[i].String())
+		require.Equal(t, p.String(), ip.ReservedIPv4RangeStrings[i])
+	}
+	require.Len(t, ip.ReservedIPv6Prefixes, len(ip.ReservedIPv6Ranges))
+	require.Len(t, ip.ReservedIPv6Prefixes, len(ip.ReservedIPv6RangeStrings))
+	for i, p := range ip.ReservedIPv6Prefixes {
+		require.Equal(t, p.String(), ip.ReservedIPv6Ranges[i].String())
+		require.Equal(t, p.String(), ip.ReservedIPv6RangeStrings[i])
+	}
+}
+
+func TestLookupReservedAddr_WithReservedAddr_ShouldReturnRange(t *testing.T) {
+	r, ok := ip.LookupReservedAddr(netip.MustParseAddr("192.168.1.1"))
+	require.True(t, ok)
+	require.Equal(t, "192.168.0.0/16", r.Prefix.String())
+	require.Equal(t, "Private-Use", r.Name)
+	require.Equal(t, "RFC 1918", r.RFC)
+	require.Equal(t, ip.ScopePrivateNetwork, r.Scope)
+	require.True(t, r.Forwardable)
+	require.False(t, r.GloballyReachable)
+
+	r, ok = ip.LookupReservedAddr(netip.MustParseAddr("::ffff:100.64.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "RFC 6598", r.RFC)
+
+	r, ok = ip.LookupReservedIP(net.ParseIP("2001:db8::1"))
+	require.True(t, ok)
+	require.Equal(t, ip.ScopeDocumentation, r.Scope)
+
+	_, ok = ip.LookupReservedAddr(netip.MustParseAddr("8.8.8.8"))
+	require.False(t, ok)
+	_, ok = ip.LookupReservedIP(nil)
+	require.False(t, ok)
+}
+
+func TestIsReservedAddr_WithRegistryRanges_ShouldCoverNonGloballyReachable(t *testing.T) {
+	for _, r := range ip.SpecialPurposeRegistry().Ranges() {
+		if r.GloballyReachable {
+			continue
+		}
+		require.True(t, ip.IsReservedAddr(r.Prefix.Addr()), r.Prefix.String())
+		// IPv4-mapped addresses are checked as IPv4 addresses, hence the IPv4-mapped range is not reserved as a whole
+		if !r.Prefix.Addr().Is4In6() {
+			require.True(t, ip.IsReservedPrefix(r.Prefix), r.Prefix.String())
+		}
+	}
+}
+
+func TestReservedRanges_WithRegistryRanges_ShouldIncludeThem(t *testing.T) {
+	reserved := map[netip.Prefix]ip.Range{}
+	for _, r := range ip.ReservedRanges() {
+		reserved[r.Prefix] = r
+	}
+	for _, r := range ip.SpecialPurposeRegistry().Ranges() {
+		if r.Prefix.Addr().Is4In6() {
+			continue
+		}
+		// the globally reachable ranges are within a reserved range, or anycast or translation ranges
+		require.Contains(t, reserved, r.Prefix)
+		require.Equal(t, r.Name, reserved[r.Prefix].Name)
+		require.Equal(t, r.Allocated, reserved[r.Prefix].Allocated)
+	}
+}
+
+func TestLookupReservedAddr_WithAnycastServiceAddr_ShouldReturnRange(t *testing.T) {
+	for addr, name := range map[string]string{
+		"192.31.196.1":    "AS112-v4",
+		"192.175.48.1":    "Direct Delegation AS112 Service",
+		"2001:4:112::1":   "AS112-v6",
+		"2620:4f:8000::1": "Direct Delegation AS112 Service",
+		"192.52.193.1":    "AMT",
+		"2001:3::1":       "AMT",
+	} {
+		r, ok := ip.LookupReservedAddr(netip.MustParseAddr(addr))
+		require.True(t, ok, addr)
+		require.Equal(t, name, r.Name, addr)
+		require.Equal(t, ip.ScopeInternet, r.Scope, addr)
+		require.True(t, r.GloballyReachable, addr)
+		require.True(t, ip.IsReserved(addr), addr)
+	}
+	require.True(t, ip.IsReservedIPv4(net.ParseIP("192.175.48.1")))
+	require.True(t, ip.IsReservedIPv6(net.ParseIP("2620:4f:8000::1")))
+}
+
+func TestReservedRanges_ShouldMatchPrefixes(t *testing.T) {
+	ranges := ip.ReservedRanges()
+	require.Len(t, ranges, len(ip.ReservedIPv4Prefixes)+len(ip.ReservedIPv6Prefixes))
+	for i, p := range append(append([]netip.Prefix(nil), ip.ReservedIPv4Prefixes...), ip.ReservedIPv6Prefixes...) {
+		require.Equal(t, p, ranges[i].Prefix)
+		require.NotEmpty(t, ranges[i].Name, p.String())
+		require.NotEmpty(t, ranges[i].RFC, p.String())
+		require.NotEmpty(t, ranges[i].Scope, p.String())
+	}
+
+	ranges[0].Name = "changed"
+	require.Equal(t, "This network", ip.ReservedRanges()[0].Name)
+}
@@ -0,0 +1,181 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const (
+	reverseIPv4Suffix = "in-addr.arpa"
+	reverseIPv6Suffix = "ip6.arpa"
+	hexDigits         = "0123456789abcdef"
+)
+
+// ReverseName returns the name used for reverse DNS (PTR) lookup of the specified IP address
+//
+// IPv4 (including IPv4-mapped IPv6) addresses map to the in-addr.arpa domain, other IPv6 addresses to the ip6.arpa
+// domain, e.g. "1.0.0.127.in-addr.arpa". The name is returned without the trailing period.
+// Returns empty string if the IP address is invalid.
+func ReverseName(ip net.IP) string {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ""
+	}
+	return ReverseNameAddr(addr)
+}
+
+// ReverseNameAddr returns the name used for reverse DNS (PTR) lookup of the specified IP address
+//
+// Returns empty string if the IP address is invalid.
+func ReverseNameAddr(addr netip.Addr) string {
+	if !addr.IsValid() {
+		return ""
+	}
+	addr = addr.Unmap()
+	return reverseName(addr, addr.BitLen())
+}
+
+// ReverseZones returns the reverse DNS zone names covering the specified prefix
+//
+// Reverse zones are delegated on octet boundaries for IPv4 and nibble boundaries for IPv6, therefore a prefix not
+// aligned to a boundary is covered by several zones, e.g. 10.0.0.0/15 results in "0.10.in-addr.arpa" and
+// "1.10.in-addr.arpa". Returns nil if the prefix is invalid.
+func ReverseZones(prefix netip.Prefix) []string {
+	if !prefix.IsValid() {
+		return nil
+	}
+	prefix = prefix.Masked()
+	addr := prefix.Addr()
+
+	step := 4
+	if addr.Is4() {
+		step = 8
+	}
+	bits := (prefix.Bits() + step - 1) / step * step
+
+	count := 1 << (bits - prefix.Bits())
+	zones := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			addr = nextAddr(addr, bits)
+		}
+		zones = append(zones, reverseName(addr, bits))
+	}
+	return zones
+}
+
+// ParseReverseName parses a name in the in-addr.arpa or ip6.arpa domain and returns the prefix it represents
+//
+// A name containing all labels of an address results in a single address prefix (/32 or /128), while a partial name,
+// such as a reverse zone, results in the corresponding shorter prefix, e.g. "10.in-addr.arpa" is parsed to
+// 10.0.0.0/8. Returns an error if the name is not a valid reverse DNS name.
+func ParseReverseName(s string) (netip.Prefix, error) {
+	name := strings.TrimSuffix(strings.ToLower(s), ".")
+
+	switch {
+	case name == reverseIPv4Suffix || strings.HasSuffix(name, "."+reverseIPv4Suffix):
+		return parseReverseIPv4(s, strings.TrimSuffix(name, reverseIPv4Suffix))
+	case name == reverseIPv6Suffix || strings.HasSuffix(name, "."+reverseIPv6Suffix):
+		return parseReverseIPv6(s, strings.TrimSuffix(name, reverseIPv6Suffix))
+	default:
+		return netip.Prefix{}, fmt.Errorf("%s is not in the %s or %s domain", s, reverseIPv4Suffix, reverseIPv6Suffix)
+	}
+}
+
+func parseReverseIPv4(s, labels string) (netip.Prefix, error) {
+	var octets [4]byte
+	parts := reverseLabels(labels)
+	if len(parts) > len(octets) {
+		return netip.Prefix{}, fmt.Errorf("%s has more than %d address labels", s, len(octets))
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 8)
+		if err != nil || (len(p) > 1 && p[0] == '0') {
+			return netip.Prefix{}, fmt.Errorf("%s has invalid IPv4 label '%s'", s, p)
+		}
+		octets[i] = byte(v)
+	}
+	return netip.PrefixFrom(netip.AddrFrom4(octets), len(parts)*8), nil
+}
+
+func parseReverseIPv6(s, labels string) (netip.Prefix, error) {
+	var bytes [16]byte
+	parts := reverseLabels(labels)
+	if len(parts) > len(bytes)*2 {
+		return netip.Prefix{}, fmt.Errorf("%s has more than %d address labels", s, len(bytes)*2)
+	}
+	for i, p := range parts {
+		if len(p) != 1 || strings.IndexByte(hexDigits, p[0]) < 0 {
+			return netip.Prefix{}, fmt.Errorf("%s has invalid IPv6 label '%s'", s, p)
+		}
+		nibble := byte(strings.IndexByte(hexDigits, p[0]))
+		if i%2 == 0 {
+			nibble <<= 4
+		}
+		bytes[i/2] |= nibble
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(bytes), len(parts)*4), nil
+}
+
+// reverseLabels splits the labels preceding the reverse domain suffix and returns them in address order
+func reverseLabels(labels string) []string {
+	labels = strings.TrimSuffix(labels, ".")
+	if len(labels) == 0 {
+		return nil
+	}
+	parts := strings.Split(labels, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return parts
+}
+
+// reverseName returns the reverse DNS name of the first bits of the address, bits being on a label boundary
+func reverseName(addr netip.Addr, bits int) string {
+	var b strings.Builder
+	if addr.Is4() {
+		octets := addr.As4()
+		for i := bits/8 - 1; i >= 0; i-- {
+			b.WriteString(strconv.Itoa(int(octets[i])))
+			b.WriteByte('.')
+		}
+		b.WriteString(reverseIPv4Suffix)
+		return b.String()
+	}
+
+	bytes := addr.As16()
+	for i := bits/4 - 1; i >= 0; i-- {
+		nibble := bytes[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		b.WriteByte(hexDigits[nibble&0xf])
+		b.WriteByte('.')
+	}
+	b.WriteString(reverseIPv6Suffix)
+	return b.String()
+}
+
+// nextAddr returns the address incremented at the specified bit position
+func nextAddr(addr netip.Addr, bits int) netip.Addr {
+	bytes := addr.As16()
+	offset := 0
+	if addr.Is4() {
+		offset = 96
+	}
+	i := (offset + bits - 1) / 8
+	carry := uint16(1) << (7 - uint(offset+bits-1)%8)
+	for ; i >= 0 && carry > 0; i-- {
+		sum := uint16(bytes[i]) + carry
+		bytes[i] = byte(sum)
+		carry = sum >> 8
+	}
+	next := netip.AddrFrom16(bytes)
+	if addr.Is4() {
+		return next.Unmap()
+	}
+	return next
+}
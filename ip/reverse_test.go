@@ -0,0 +1,102 @@
+package ip_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseName_WithIPv4_ShouldReturnInAddrName(t *testing.T) {
+	require.Equal(t, "1.0.0.127.in-addr.arpa", ip.ReverseName(net.ParseIP("127.0.0.1")))
+	require.Equal(t, "4.3.2.1.in-addr.arpa", ip.ReverseName(net.ParseIP("1.2.3.4")))
+	require.Equal(t, "4.3.2.1.in-addr.arpa", ip.ReverseName(net.ParseIP("::ffff:1.2.3.4")))
+	require.Equal(t, "4.3.2.1.in-addr.arpa", ip.ReverseNameAddr(netip.MustParseAddr("::ffff:1.2.3.4")))
+}
+
+func TestReverseName_WithIPv6_ShouldReturnIP6Name(t *testing.T) {
+	require.Equal(t,
+		"b.a.9.8.7.6.5.0.4.0.0.0.3.0.0.0.2.0.0.0.1.0.0.0.0.0.0.0.1.2.3.4.ip6.arpa",
+		ip.ReverseName(net.ParseIP("4321:0:1:2:3:4:567:89ab")))
+	require.Equal(t,
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa",
+		ip.ReverseNameAddr(netip.MustParseAddr("::1")))
+}
+
+func TestReverseName_WithInvalidIP_ShouldReturnEmpty(t *testing.T) {
+	require.Equal(t, "", ip.ReverseName(nil))
+	require.Equal(t, "", ip.ReverseName(net.IP{1, 2, 3}))
+	require.Equal(t, "", ip.ReverseNameAddr(netip.Addr{}))
+}
+
+func TestReverseZones_WithAlignedPrefix_ShouldReturnSingleZone(t *testing.T) {
+	require.Equal(t, []string{"10.in-addr.arpa"}, ip.ReverseZones(netip.MustParsePrefix("10.0.0.0/8")))
+	require.Equal(t, []string{"2.0.192.in-addr.arpa"}, ip.ReverseZones(netip.MustParsePrefix("192.0.2.0/24")))
+	require.Equal(t, []string{"in-addr.arpa"}, ip.ReverseZones(netip.MustParsePrefix("0.0.0.0/0")))
+	require.Equal(t, []string{"8.b.d.0.1.0.0.2.ip6.arpa"}, ip.ReverseZones(netip.MustParsePrefix("2001:db8::/32")))
+}
+
+func TestReverseZones_WithUnalignedPrefix_ShouldReturnCoveringZones(t *testing.T) {
+	require.Equal(t,
+		[]string{"0.10.in-addr.arpa", "1.10.in-addr.arpa"},
+		ip.ReverseZones(netip.MustParsePrefix("10.0.0.0/15")))
+	require.Equal(t,
+		[]string{"16.172.in-addr.arpa", "17.172.in-addr.arpa", "18.172.in-addr.arpa", "19.172.in-addr.arpa"},
+		ip.ReverseZones(netip.MustParsePrefix("172.16.0.0/14")))
+	require.Equal(t,
+		[]string{"254.255.255.255.in-addr.arpa", "255.255.255.255.in-addr.arpa"},
+		ip.ReverseZones(netip.MustParsePrefix("255.255.255.254/31")))
+	require.Equal(t,
+		[]string{"c.f.ip6.arpa", "d.f.ip6.arpa"},
+		ip.ReverseZones(netip.MustParsePrefix("fc00::/7")))
+	require.Nil(t, ip.ReverseZones(netip.Prefix{}))
+}
+
+func TestParseReverseName_WithAddressName_ShouldReturnSingleAddress(t *testing.T) {
+	prefix, err := ip.ParseReverseName("1.0.0.127.in-addr.arpa.")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("127.0.0.1/32"), prefix)
+
+	prefix, err = ip.ParseReverseName("B.A.9.8.7.6.5.0.4.0.0.0.3.0.0.0.2.0.0.0.1.0.0.0.0.0.0.0.1.2.3.4.IP6.ARPA")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("4321:0:1:2:3:4:567:89ab/128"), prefix)
+}
+
+func TestParseReverseName_WithZoneName_ShouldReturnPrefix(t *testing.T) {
+	prefix, err := ip.ParseReverseName("10.in-addr.arpa")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), prefix)
+
+	prefix, err = ip.ParseReverseName("in-addr.arpa")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("0.0.0.0/0"), prefix)
+
+	prefix, err = ip.ParseReverseName("8.b.d.0.1.0.0.2.ip6.arpa")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("2001:db8::/32"), prefix)
+
+	prefix, err = ip.ParseReverseName("c.f.ip6.arpa")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("fc00::/8"), prefix)
+}
+
+func TestParseReverseName_WithInvalidName_ShouldReturnError(t *testing.T) {
+	_, err := ip.ParseReverseName("example.com")
+	require.Error(t, err)
+	_, err = ip.ParseReverseName("fooin-addr.arpa")
+	require.Error(t, err)
+	_, err = ip.ParseReverseName("256.in-addr.arpa")
+	require.Error(t, err)
+	_, err = ip.ParseReverseName("01.in-addr.arpa")
+	require.Error(t, err)
+	_, err = ip.ParseReverseName("1.1.1.1.1.in-addr.arpa")
+	require.Error(t, err)
+	_, err = ip.ParseReverseName("10..in-addr.arpa")
+	require.Error(t, err)
+	_, err = ip.ParseReverseName("g.ip6.arpa")
+	require.Error(t, err)
+	_, err = ip.ParseReverseName("ab.ip6.arpa")
+	require.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package ip
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Set is a set of IPv4 and IPv6 addresses, such as a scan scope, which is held as a minimal list of ranges
+//
+// The zero value is an empty set. A set is immutable and safe for concurrent use, and is created with a SetBuilder
+// or ParseSet, or by combining sets. IPv4-mapped IPv6 addresses are handled as IPv4.
+type Set struct {
+	ranges []AddrRange
+}
+
+// SetBuilder builds a set by adding and removing addresses in order. The zero value is an empty builder.
+//
+// Consecutive additions and removals are merged at once, when followed by the other operation or by building the set,
+// hence building a set of n ranges takes O(n log n) time.
+type SetBuilder struct {
+	ranges  []AddrRange
+	added   []AddrRange
+	removed []AddrRange
+}
+
+// Add adds the addresses of the range, ignoring an invalid range
+func (b *SetBuilder) Add(r AddrRange) {
+	b.add(r)
+}
+
+// AddPrefix adds the addresses of the prefix, ignoring an invalid prefix
+func (b *SetBuilder) AddPrefix(p netip.Prefix) {
+	b.Add(AddrRangeFromPrefix(p))
+}
+
+// AddAddr adds the address, ignoring an invalid address
+func (b *SetBuilder) AddAddr(addr netip.Addr) {
+	addr = addr.Unmap().WithZone("")
+	b.Add(AddrRange{From: addr, To: addr})
+}
+
+// AddSet adds the addresses of the set
+func (b *SetBuilder) AddSet(s *Set) {
+	b.add(s.ranges...)
+}
+
+// add adds the ranges once the removals preceding them are applied
+func (b *SetBuilder) add(ranges ...AddrRange) {
+	if len(b.removed) > 0 {
+		b.merge()
+	}
+	b.added = append(b.added, ranges...)
+}
+
+// Remove removes the addresses of the range, ignoring an invalid range
+func (b *SetBuilder) Remove(r AddrRange) {
+	b.removed = append(b.removed, r)
+}
+
+// RemovePrefix removes the addresses of the prefix, ignoring an invalid prefix
+func (b *SetBuilder) RemovePrefix(p netip.Prefix) {
+	b.Remove(AddrRangeFromPrefix(p))
+}
+
+// RemoveAddr removes the address, ignoring an invalid address
+func (b *SetBuilder) RemoveAddr(addr netip.Addr) {
+	addr = addr.Unmap().WithZone("")
+	b.Remove(AddrRange{From: addr, To: addr})
+}
+
+// RemoveSet removes the addresses of the set
+func (b *SetBuilder) RemoveSet(s *Set) {
+	b.removed = append(b.removed, s.ranges...)
+}
+
+// Set returns the set of the addresses added and not removed. The builder can be used further without affecting it.
+func (b *SetBuilder) Set() *Set {
+	b.merge()
+	return &Set{ranges: append([]AddrRange(nil), b.ranges...)}
+}
+
+// merge applies the pending additions, followed by the pending removals
+func (b *SetBuilder) merge() {
+	if len(b.added) > 0 {
+		b.ranges = mergeRanges(append(b.ranges, b.added...))
+	}
+	if len(b.removed) > 0 {
+		b.ranges = excludeRanges(b.ranges, mergeRanges(b.removed))
+	}
+	b.added, b.removed = nil, nil
+}
+
+// ParseSet returns the set of the included addresses except the excluded, which are in any format of ParseAddrRange,
+// such as "203.0.113.0/24" except "203.0.113.5" and "203.0.113.128/28"
+//
+// Returns an error if any range is invalid.
+func ParseSet(include, exclude []string) (*Set, error) {
+	var b SetBuilder
+	for _, s := range include {
+		r, err := ParseAddrRange(s)
+		if err != nil {
+			return nil, err
+		}
+		b.Add(r)
+	}
+	for _, s := range exclude {
+		r, err := ParseAddrRange(s)
+		if err != nil {
+			return nil, err
+		}
+		b.Remove(r)
+	}
+	return b.Set(), nil
+}
+
+// Contains returns whether the address is in the set
+func (s *Set) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	i := sort.Search(len(s.ranges), func(i int) bool {
+		return s.ranges[i].To.Compare(addr) >= 0
+	})
+	return i < len(s.ranges) && s.ranges[i].Contains(addr)
+}
+
+// ContainsIP returns whether the address is in the set
+func (s *Set) ContainsIP(ip net.IP) bool {
+	addr, ok := AddrFromIP(ip)
+	return ok && s.Contains(addr)
+}
+
+// ContainsPrefix returns whether all addresses of the prefix are in the set
+func (s *Set) ContainsPrefix(p netip.Prefix) bool {
+	r := AddrRangeFromPrefix(p)
+	i := sort.Search(len(s.ranges), func(i int) bool {
+		return s.ranges[i].To.Compare(r.From) >= 0
+	})
+	return r.IsValid() && i < len(s.ranges) && s.ranges[i].Contains(r.From) && s.ranges[i].Contains(r.To)
+}
+
+// IsEmpty returns whether the set has no addresses
+func (s *Set) IsEmpty() bool {
+	return len(s.ranges) == 0
+}
+
+// Union returns the set of the addresses in either set
+func (s *Set) Union(other *Set) *Set {
+	return &Set{ranges: mergeRanges(append(append([]AddrRange(nil), s.ranges...), other.ranges...))}
+}
+
+// Intersect returns the set of the addresses in both sets
+func (s *Set) Intersect(other *Set) *Set {
+	return &Set{ranges: intersectRanges(s.ranges, other.ranges)}
+}
+
+// Difference returns the set of the addresses in the set but not in the other set
+func (s *Set) Difference(other *Set) *Set {
+	return &Set{ranges: excludeRanges(s.ranges, other.ranges)}
+}
+
+// Ranges returns the minimal list of ranges of the set, in order
+func (s *Set) Ranges() []AddrRange {
+	return append([]AddrRange(nil), s.ranges...)
+}
+
+// Prefixes returns the minimal list of prefixes of the set, in order
+func (s *Set) Prefixes() []netip.Prefix {
+	return rangesToPrefixes(s.ranges)
+}
+
+// Size returns the number of addresses of the set
+func (s *Set) Size() *big.Int {
+	size := new(big.Int)
+	for _, r := range s.ranges {
+		size.Add(size, r.Size())
+	}
+	return size
+}
+
+// Addrs returns an iterator of the addresses of the set, in order
+func (s *Set) Addrs() *AddrIterator {
+	return &AddrIterator{ranges: s.ranges}
+}
+
+// String returns the minimal list of prefixes of the set, separated by commas
+func (s *Set) String() string {
+	prefixes := s.Prefixes()
+	result := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		result[i] = p.String()
+	}
+	return strings.Join(result, ",")
+}
@@ -0,0 +1,151 @@
+package ip_test
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSet_WithExclusions_ShouldReturnRemainingAddresses(t *testing.T) {
+	s, err := ip.ParseSet(
+		[]string{"203.0.113.0/24", "2001:db8::1-2001:db8::4"},
+		[]string{"203.0.113.5", "203.0.113.128/28", "203.0.113.144-203.0.113.255", "2001:db8::2"},
+	)
+	require.NoError(t, err)
+
+	require.True(t, s.Contains(netip.MustParseAddr("203.0.113.4")))
+	require.True(t, s.Contains(netip.MustParseAddr("::ffff:203.0.113.127")))
+	require.True(t, s.ContainsIP(net.ParseIP("2001:db8::3")))
+	require.False(t, s.Contains(netip.MustParseAddr("203.0.113.5")))
+	require.False(t, s.Contains(netip.MustParseAddr("203.0.113.130")))
+	require.False(t, s.Contains(netip.MustParseAddr("2001:db8::2")))
+	require.False(t, s.Contains(netip.MustParseAddr("8.8.8.8")))
+	require.False(t, s.Contains(netip.Addr{}))
+	require.False(t, s.ContainsIP(nil))
+	require.True(t, s.ContainsPrefix(netip.MustParsePrefix("203.0.113.64/26")))
+	require.False(t, s.ContainsPrefix(netip.MustParsePrefix("203.0.113.0/25")))
+
+	require.Equal(t, int64(127+3), s.Size().Int64())
+	require.Equal(t, "203.0.113.0/30,203.0.113.4/32,203.0.113.6/31,203.0.113.8/29,203.0.113.16/28,"+
+		"203.0.113.32/27,203.0.113.64/26,2001:db8::1/128,2001:db8::3/128,2001:db8::4/128", s.String())
+	require.Len(t, s.Ranges(), 4)
+
+	var count int
+	for it := s.Addrs(); it.Next(); count++ {
+		require.True(t, s.Contains(it.Addr()), it.Addr().String())
+	}
+	require.Equal(t, 130, count)
+
+	_, err = ip.ParseSet([]string{"203.0.113.0/33"}, nil)
+	require.Error(t, err)
+	_, err = ip.ParseSet(nil, []string{"foo"})
+	require.Error(t, err)
+}
+
+func TestSetBuilder_WithOperationsInOrder_ShouldApplyThem(t *testing.T) {
+	var b ip.SetBuilder
+	b.AddPrefix(netip.MustParsePrefix("10.0.0.0/24"))
+	b.RemovePrefix(netip.MustParsePrefix("10.0.0.0/25"))
+	b.AddAddr(netip.MustParseAddr("10.0.0.1"))
+	b.RemoveAddr(netip.MustParseAddr("10.0.0.255"))
+	b.AddPrefix(netip.Prefix{})
+	s := b.Set()
+
+	require.Equal(t, "10.0.0.1/32,10.0.0.128/26,10.0.0.192/27,10.0.0.224/28,10.0.0.240/29,10.0.0.248/30,"+
+		"10.0.0.252/31,10.0.0.254/32", s.String())
+
+	b.AddSet(s)
+	b.RemoveSet(s)
+	require.True(t, b.Set().IsEmpty())
+	require.False(t, s.IsEmpty())
+	require.True(t, (&ip.Set{}).IsEmpty())
+}
+
+func TestSet_WithOtherSet_ShouldCombineSets(t *testing.T) {
+	a, err := ip.ParseSet([]string{"10.0.0.0/24", "2001:db8::/32"}, nil)
+	require.NoError(t, err)
+	b, err := ip.ParseSet([]string{"10.0.0.128/25", "10.0.1.0/24"}, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, "10.0.0.0/23,2001:db8::/32", a.Union(b).String())
+	require.Equal(t, "10.0.0.128/25", a.Intersect(b).String())
+	require.Equal(t, "10.0.0.0/25,2001:db8::/32", a.Difference(b).String())
+	require.Equal(t, "10.0.1.0/24", b.Difference(a).String())
+	require.Equal(t, "10.0.0.0/24,2001:db8::/32", a.String())
+}
+
+func TestSet_WithConcurrentReaders_ShouldBeSafe(t *testing.T) {
+	s, err := ip.ParseSet([]string{"10.0.0.0/8", "2001:db8::/32"}, []string{"10.1.0.0/16"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				s.Contains(netip.MustParseAddr("10.2.3.4"))
+				s.Union(s)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSetBuilder_WhenUsedAfterSet_ShouldNotAffectIt(t *testing.T) {
+	var b ip.SetBuilder
+	b.AddPrefix(netip.MustParsePrefix("10.0.0.0/24"))
+	b.RemoveAddr(netip.MustParseAddr("10.0.0.1"))
+	s := b.Set()
+
+	b.AddAddr(netip.MustParseAddr("10.0.0.1"))
+	b.RemovePrefix(netip.MustParsePrefix("10.0.0.128/25"))
+	require.False(t, s.Contains(netip.MustParseAddr("10.0.0.1")))
+	require.True(t, s.Contains(netip.MustParseAddr("10.0.0.200")))
+
+	s = b.Set()
+	require.True(t, s.Contains(netip.MustParseAddr("10.0.0.1")))
+	require.False(t, s.Contains(netip.MustParseAddr("10.0.0.200")))
+	require.Equal(t, "10.0.0.0/25", s.String())
+}
+
+func BenchmarkParseSet(b *testing.B) {
+	prefixes := randomPrefixes(10000)
+	include := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		include[i] = p.String()
+	}
+	exclude := include[:len(include)/2]
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ip.ParseSet(include, exclude)
+	}
+}
+
+func BenchmarkSetBuilder_WithDisjointRanges(b *testing.B) {
+	var include, exclude []netip.Prefix
+	for i := 0; i < 20000; i++ {
+		addr := netip.AddrFrom4([4]byte{10, byte(i >> 7), byte(i << 1), 0})
+		include = append(include, netip.PrefixFrom(addr, 24))
+		exclude = append(exclude, netip.PrefixFrom(addr.Next(), 32))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var builder ip.SetBuilder
+		for _, p := range include {
+			builder.AddPrefix(p)
+		}
+		for _, p := range exclude {
+			builder.RemovePrefix(p)
+		}
+		_ = builder.Set()
+	}
+}
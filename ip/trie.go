@@ -0,0 +1,290 @@
+package ip
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net/netip"
+)
+
+// Trie holds IP prefixes with associated values, and finds the longest prefix matching an IP address
+//
+// The trie is a path-compressed binary (radix) trie per address family, which makes lookups O(prefix length)
+// regardless of the number of prefixes, without allocations. IPv4-mapped IPv6 addresses and prefixes are handled as
+// IPv4. The zero value is an empty trie ready to use. A trie is not safe for concurrent use while it is modified.
+type Trie[T any] struct {
+	ipv4 *trieNode[T]
+	ipv6 *trieNode[T]
+	size int
+}
+
+// trieNode is a node of the trie, which holds a value if set, or is a branch of its children otherwise
+type trieNode[T any] struct {
+	key      trieKey
+	prefix   netip.Prefix
+	children [2]*trieNode[T]
+	value    T
+	set      bool
+}
+
+// trieKey holds the bits of an address, left aligned in 128 bits
+type trieKey struct {
+	hi uint64
+	lo uint64
+}
+
+// Insert adds the prefix with the value to the trie, replacing the value if the prefix already exists
+//
+// The prefix is masked, i.e. the bits of the address after the prefix length are ignored. Returns false if the
+// prefix is invalid.
+func (t *Trie[T]) Insert(p netip.Prefix, value T) bool {
+	p, ok := normalizePrefix(p)
+	if !ok {
+		return false
+	}
+
+	pos := t.root(p.Addr())
+	k := keyOf(p.Addr())
+	length := p.Bits()
+	for {
+		n := *pos
+		if n == nil {
+			*pos = &trieNode[T]{key: k, prefix: p, value: value, set: true}
+			t.size++
+			return true
+		}
+
+		common := commonBits(n.key, k)
+		if common > length {
+			common = length
+		}
+		if common > n.prefix.Bits() {
+			common = n.prefix.Bits()
+		}
+
+		switch {
+		case common == n.prefix.Bits() && common == length:
+			// existing node, which may be a branch without value
+			if !n.set {
+				t.size++
+			}
+			n.value, n.set = value, true
+			return true
+		case common == n.prefix.Bits():
+			pos = &n.children[k.bit(common)]
+			continue
+		case common == length:
+			// the new prefix is the parent of the existing node
+			parent := &trieNode[T]{key: k, prefix: p, value: value, set: true}
+			parent.children[n.key.bit(common)] = n
+			*pos = parent
+		default:
+			branch := &trieNode[T]{key: k.masked(common), prefix: netip.PrefixFrom(p.Addr(), common).Masked()}
+			branch.children[k.bit(common)] = &trieNode[T]{key: k, prefix: p, value: value, set: true}
+			branch.children[n.key.bit(common)] = n
+			*pos = branch
+		}
+		t.size++
+		return true
+	}
+}
+
+// Delete removes the prefix from the trie, returning whether it existed
+func (t *Trie[T]) Delete(p netip.Prefix) bool {
+	p, ok := normalizePrefix(p)
+	if !ok {
+		return false
+	}
+
+	var parentPos **trieNode[T]
+	pos := t.root(p.Addr())
+	k := keyOf(p.Addr())
+	for *pos != nil {
+		n := *pos
+		if n.prefix.Bits() > p.Bits() || !n.key.matches(k, n.prefix.Bits()) {
+			return false
+		}
+		if n.prefix.Bits() < p.Bits() {
+			parentPos, pos = pos, &n.children[k.bit(n.prefix.Bits())]
+			continue
+		}
+		if !n.set {
+			return false
+		}
+
+		var zero T
+		n.value, n.set = zero, false
+		t.size--
+		t.compact(pos)
+		if parentPos != nil {
+			t.compact(parentPos)
+		}
+		return true
+	}
+	return false
+}
+
+// compact removes the branch node without value at the position, if it has less than two children
+func (t *Trie[T]) compact(pos **trieNode[T]) {
+	n := *pos
+	if n.set {
+		return
+	}
+	switch {
+	case n.children[0] == nil:
+		*pos = n.children[1]
+	case n.children[1] == nil:
+		*pos = n.children[0]
+	}
+}
+
+// Get returns the value of the exact prefix, and whether it exists in the trie
+func (t *Trie[T]) Get(p netip.Prefix) (T, bool) {
+	var zero T
+	p, ok := normalizePrefix(p)
+	if !ok {
+		return zero, false
+	}
+	match, value, ok := t.lookup(p.Addr(), p.Bits())
+	if !ok || match.Bits() != p.Bits() {
+		return zero, false
+	}
+	return value, true
+}
+
+// Lookup returns the longest prefix containing the IP address, with its value, and whether there is one
+func (t *Trie[T]) Lookup(addr netip.Addr) (netip.Prefix, T, bool) {
+	addr = addr.Unmap().WithZone("")
+	return t.lookup(addr, addr.BitLen())
+}
+
+// LookupPrefix returns the longest prefix containing all IP addresses of the prefix, with its value, and whether
+// there is one
+func (t *Trie[T]) LookupPrefix(p netip.Prefix) (netip.Prefix, T, bool) {
+	p, ok := normalizePrefix(p)
+	if !ok {
+		var zero T
+		return netip.Prefix{}, zero, false
+	}
+	return t.lookup(p.Addr(), p.Bits())
+}
+
+// Contains returns whether any prefix of the trie contains the IP address
+func (t *Trie[T]) Contains(addr netip.Addr) bool {
+	_, _, ok := t.Lookup(addr)
+	return ok
+}
+
+func (t *Trie[T]) lookup(addr netip.Addr, length int) (netip.Prefix, T, bool) {
+	var match *trieNode[T]
+	if addr.IsValid() {
+		k := keyOf(addr)
+		n := *t.root(addr)
+		for n != nil && n.prefix.Bits() <= length && n.key.matches(k, n.prefix.Bits()) {
+			if n.set {
+				match = n
+			}
+			if n.prefix.Bits() == length {
+				break
+			}
+			n = n.children[k.bit(n.prefix.Bits())]
+		}
+	}
+
+	if match == nil {
+		var zero T
+		return netip.Prefix{}, zero, false
+	}
+	return match.prefix, match.value, true
+}
+
+// Len returns the number of prefixes in the trie
+func (t *Trie[T]) Len() int {
+	return t.size
+}
+
+// Walk calls the function for each prefix of the trie in order, IPv4 before IPv6, stopping if the function returns
+// false
+func (t *Trie[T]) Walk(fn func(netip.Prefix, T) bool) {
+	if walk(t.ipv4, fn) {
+		walk(t.ipv6, fn)
+	}
+}
+
+func walk[T any](n *trieNode[T], fn func(netip.Prefix, T) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.set && !fn(n.prefix, n.value) {
+		return false
+	}
+	return walk(n.children[0], fn) && walk(n.children[1], fn)
+}
+
+func (t *Trie[T]) root(addr netip.Addr) **trieNode[T] {
+	if addr.Is4() {
+		return &t.ipv4
+	}
+	return &t.ipv6
+}
+
+// normalizePrefix returns the masked prefix, with IPv4-mapped IPv6 prefixes converted to IPv4
+func normalizePrefix(p netip.Prefix) (netip.Prefix, bool) {
+	if !p.IsValid() {
+		return netip.Prefix{}, false
+	}
+	addr := p.Addr()
+	if addr.Is4In6() && p.Bits() >= 96 {
+		return netip.PrefixFrom(addr.Unmap(), p.Bits()-96).Masked(), true
+	}
+	return p.Masked(), true
+}
+
+func keyOf(addr netip.Addr) trieKey {
+	if addr.Is4() {
+		b := addr.As4()
+		return trieKey{hi: uint64(binary.BigEndian.Uint32(b[:])) << 32}
+	}
+	b := addr.As16()
+	return trieKey{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+// bit returns the bit of the key at the index, counting from the most significant bit
+func (k trieKey) bit(i int) int {
+	if i < 64 {
+		return int(k.hi>>(63-i)) & 1
+	}
+	return int(k.lo>>(127-i)) & 1
+}
+
+// masked returns the key with the bits after the length cleared
+func (k trieKey) masked(length int) trieKey {
+	hi, lo := maskBits(length)
+	return trieKey{hi: k.hi & hi, lo: k.lo & lo}
+}
+
+// matches returns whether the first bits of the keys are equal
+func (k trieKey) matches(other trieKey, length int) bool {
+	hi, lo := maskBits(length)
+	return (k.hi^other.hi)&hi == 0 && (k.lo^other.lo)&lo == 0
+}
+
+func maskBits(length int) (uint64, uint64) {
+	switch {
+	case length <= 0:
+		return 0, 0
+	case length < 64:
+		return ^uint64(0) << (64 - length), 0
+	case length < 128:
+		return ^uint64(0), ^uint64(0) << (128 - length)
+	default:
+		return ^uint64(0), ^uint64(0)
+	}
+}
+
+// commonBits returns the number of leading bits the keys have in common
+func commonBits(a, b trieKey) int {
+	if x := a.hi ^ b.hi; x != 0 {
+		return bits.LeadingZeros64(x)
+	}
+	return 64 + bits.LeadingZeros64(a.lo^b.lo)
+}
@@ -0,0 +1,203 @@
+package ip_test
+
+import (
+	"math/rand"
+	"net/netip"
+	"testing"
+
+	"github.com/detectify/n5/ip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrie_WithPrefixes_ShouldReturnLongestMatch(t *testing.T) {
+	var trie ip.Trie[string]
+	require.True(t, trie.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a"))
+	require.True(t, trie.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b"))
+	require.True(t, trie.Insert(netip.MustParsePrefix("10.1.2.3/24"), "c"))
+	require.True(t, trie.Insert(netip.MustParsePrefix("2001:db8::/32"), "d"))
+	require.True(t, trie.Insert(netip.MustParsePrefix("::/0"), "e"))
+	require.False(t, trie.Insert(netip.Prefix{}, "f"))
+	require.Equal(t, 5, trie.Len())
+
+	for addr, expected := range map[string]string{
+		"10.0.0.1":        "10.0.0.0/8",
+		"10.1.0.1":        "10.1.0.0/16",
+		"10.1.2.255":      "10.1.2.0/24",
+		"::ffff:10.1.2.4": "10.1.2.0/24",
+		"2001:db8::1":     "2001:db8::/32",
+		"2001:db9::1":     "::/0",
+	} {
+		p, _, ok := trie.Lookup(netip.MustParseAddr(addr))
+		require.True(t, ok, addr)
+		require.Equal(t, expected, p.String(), addr)
+	}
+	_, _, ok := trie.Lookup(netip.MustParseAddr("11.0.0.1"))
+	require.False(t, ok)
+	require.False(t, trie.Contains(netip.Addr{}))
+
+	p, v, ok := trie.LookupPrefix(netip.MustParsePrefix("10.1.2.0/23"))
+	require.True(t, ok)
+	require.Equal(t, "10.1.0.0/16", p.String())
+	require.Equal(t, "b", v)
+	_, _, ok = trie.LookupPrefix(netip.MustParsePrefix("10.0.0.0/7"))
+	require.False(t, ok)
+}
+
+func TestTrie_WithExistingPrefix_ShouldReplaceValue(t *testing.T) {
+	var trie ip.Trie[int]
+	trie.Insert(netip.MustParsePrefix("192.0.2.0/24"), 1)
+	trie.Insert(netip.MustParsePrefix("192.0.2.128/24"), 2)
+	require.Equal(t, 1, trie.Len())
+
+	v, ok := trie.Get(netip.MustParsePrefix("192.0.2.0/24"))
+	require.True(t, ok)
+	require.Equal(t, 2, v)
+	v, ok = trie.Get(netip.MustParsePrefix("::ffff:192.0.2.0/120"))
+	require.True(t, ok)
+	require.Equal(t, 2, v)
+	_, ok = trie.Get(netip.MustParsePrefix("192.0.2.0/25"))
+	require.False(t, ok)
+}
+
+func TestTrie_WithDeletedPrefix_ShouldNotMatch(t *testing.T) {
+	var trie ip.Trie[int]
+	for i, p := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16", "10.1.1.0/24"} {
+		trie.Insert(netip.MustParsePrefix(p), i)
+	}
+
+	require.True(t, trie.Delete(netip.MustParsePrefix("10.1.0.0/16")))
+	require.False(t, trie.Delete(netip.MustParsePrefix("10.1.0.0/16")))
+	require.False(t, trie.Delete(netip.MustParsePrefix("10.0.0.0/16")))
+	require.Equal(t, 3, trie.Len())
+
+	p, _, ok := trie.Lookup(netip.MustParseAddr("10.1.2.1"))
+	require.True(t, ok)
+	require.Equal(t, "10.0.0.0/8", p.String())
+	p, _, ok = trie.Lookup(netip.MustParseAddr("10.1.1.1"))
+	require.True(t, ok)
+	require.Equal(t, "10.1.1.0/24", p.String())
+
+	require.True(t, trie.Delete(netip.MustParsePrefix("10.0.0.0/8")))
+	require.True(t, trie.Delete(netip.MustParsePrefix("10.2.0.0/16")))
+	require.True(t, trie.Delete(netip.MustParsePrefix("10.1.1.0/24")))
+	require.Equal(t, 0, trie.Len())
+	require.False(t, trie.Contains(netip.MustParseAddr("10.1.1.1")))
+}
+
+func TestTrie_Walk_ShouldVisitPrefixesInOrder(t *testing.T) {
+	var trie ip.Trie[struct{}]
+	for _, p := range []string{"2001:db8::/32", "10.1.0.0/16", "10.0.0.0/8", "192.0.2.0/24", "::/0"} {
+		trie.Insert(netip.MustParsePrefix(p), struct{}{})
+	}
+
+	var prefixes []string
+	trie.Walk(func(p netip.Prefix, _ struct{}) bool {
+		prefixes = append(prefixes, p.String())
+		return true
+	})
+	require.Equal(t, []string{"10.0.0.0/8", "10.1.0.0/16", "192.0.2.0/24", "::/0", "2001:db8::/32"}, prefixes)
+
+	prefixes = nil
+	trie.Walk(func(p netip.Prefix, _ struct{}) bool {
+		prefixes = append(prefixes, p.String())
+		return len(prefixes) < 2
+	})
+	require.Len(t, prefixes, 2)
+}
+
+func TestTrie_WithRandomPrefixes_ShouldMatchLinearScan(t *testing.T) {
+	prefixes := randomPrefixes(1000)
+	var trie ip.Trie[int]
+	for i, p := range prefixes {
+		trie.Insert(p, i)
+	}
+
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 10000; i++ {
+		addr := randomAddr(rnd)
+		expected, found := netip.Prefix{}, false
+		for _, p := range prefixes {
+			if p.Contains(addr) && (!found || p.Bits() > expected.Bits()) {
+				expected, found = p, true
+			}
+		}
+
+		p, _, ok := trie.Lookup(addr)
+		require.Equal(t, found, ok, addr.String())
+		require.Equal(t, expected, p, addr.String())
+	}
+}
+
+func BenchmarkTrie_Lookup(b *testing.B) {
+	prefixes := randomPrefixes(10000)
+	var trie ip.Trie[struct{}]
+	for _, p := range prefixes {
+		trie.Insert(p, struct{}{})
+	}
+	addrs := randomAddrs(1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Contains(addrs[i%len(addrs)])
+	}
+}
+
+func BenchmarkLinearScan_Lookup(b *testing.B) {
+	prefixes := randomPrefixes(10000)
+	addrs := randomAddrs(1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		addr := addrs[i%len(addrs)]
+		for _, p := range prefixes {
+			if p.Contains(addr) {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkIsReservedAddr(b *testing.B) {
+	addrs := randomAddrs(1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ip.IsReservedAddr(addrs[i%len(addrs)])
+	}
+}
+
+// randomPrefixes returns random IPv4 and IPv6 prefixes, the same for each call
+func randomPrefixes(n int) []netip.Prefix {
+	rnd := rand.New(rand.NewSource(1))
+	prefixes := make([]netip.Prefix, n)
+	for i := range prefixes {
+		addr := randomAddr(rnd)
+		prefixes[i] = netip.PrefixFrom(addr, 8+rnd.Intn(addr.BitLen()-7)).Masked()
+	}
+	return prefixes
+}
+
+func randomAddrs(n int) []netip.Addr {
+	rnd := rand.New(rand.NewSource(3))
+	addrs := make([]netip.Addr, n)
+	for i := range addrs {
+		addrs[i] = randomAddr(rnd)
+	}
+	return addrs
+}
+
+// randomAddr returns a random IPv4 address, or IPv6 address in 2001::/16, which makes matches likely
+func randomAddr(rnd *rand.Rand) netip.Addr {
+	if rnd.Intn(2) == 0 {
+		var b [4]byte
+		rnd.Read(b[:])
+		return netip.AddrFrom4(b)
+	}
+	var b [16]byte
+	rnd.Read(b[:])
+	b[0], b[1] = 0x20, 0x01
+	return netip.AddrFrom16(b)
+}
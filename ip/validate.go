@@ -3,6 +3,7 @@ package ip
 import (
 	"fmt"
 	"net"
+	"net/netip"
 	"strings"
 )
 
@@ -44,3 +45,32 @@ func IsIPv6(s string) bool {
 	ip := net.ParseIP(s)
 	return ip != nil && strings.Contains(ip.String(), ":")
 }
+
+// ValidateAddr indicates whether the specified IP address is valid, i.e. not the zero value
+func ValidateAddr(addr netip.Addr) error {
+	if !addr.IsValid() {
+		return fmt.Errorf("%s is not an IP address", addr.String())
+	}
+	return nil
+}
+
+// ValidateNonReservedAddr indicates whether the specified IP address is a valid non-reserved IP address
+func ValidateNonReservedAddr(addr netip.Addr) error {
+	if err := ValidateAddr(addr); err != nil {
+		return err
+	}
+	if IsReservedAddr(addr) {
+		return fmt.Errorf("%s is a reserved IP address", addr.String())
+	}
+	return nil
+}
+
+// IsIPv4Addr indicates whether the specified IP address is an IP v4 (or IPv4-mapped IPv6) address
+func IsIPv4Addr(addr netip.Addr) bool {
+	return addr.Unmap().Is4()
+}
+
+// IsIPv6Addr indicates whether the specified IP address is an IP v6 address, which is not IPv4-mapped
+func IsIPv6Addr(addr netip.Addr) bool {
+	return addr.Is6() && !addr.Is4In6()
+}
@@ -1,6 +1,7 @@
 package ip_test
 
 import (
+	"net/netip"
 	"testing"
 
 	"github.com/detectify/n5/ip"
@@ -52,3 +53,21 @@ func TestIsIPv6_WithMalformedIPv6_ShouldReturnFalse(t *testing.T) {
 	require.False(t, ip.IsIPv6("::ffffff"))
 	require.False(t, ip.IsIPv6("::g"))
 }
+
+func TestValidateAddr_WithAddr_ShouldReturnError(t *testing.T) {
+	require.NoError(t, ip.ValidateAddr(netip.MustParseAddr("192.0.2.1")))
+	require.Error(t, ip.ValidateAddr(netip.Addr{}))
+
+	require.NoError(t, ip.ValidateNonReservedAddr(netip.MustParseAddr("8.8.8.8")))
+	require.Error(t, ip.ValidateNonReservedAddr(netip.MustParseAddr("10.0.0.1")))
+	require.Error(t, ip.ValidateNonReservedAddr(netip.Addr{}))
+}
+
+func TestIsIPv4Addr_WithAddr_ShouldReturnWhetherIPv4(t *testing.T) {
+	require.True(t, ip.IsIPv4Addr(netip.MustParseAddr("192.0.2.1")))
+	require.True(t, ip.IsIPv4Addr(netip.MustParseAddr("::ffff:192.0.2.1")))
+	require.False(t, ip.IsIPv4Addr(netip.MustParseAddr("2001:db8::1")))
+	require.True(t, ip.IsIPv6Addr(netip.MustParseAddr("2001:db8::1")))
+	require.False(t, ip.IsIPv6Addr(netip.MustParseAddr("::ffff:192.0.2.1")))
+	require.False(t, ip.IsIPv6Addr(netip.Addr{}))
+}
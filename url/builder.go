@@ -0,0 +1,145 @@
+package url
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// Builder builds a URL from its components, percent-encoding them as needed. The zero value is an empty builder.
+type Builder struct {
+	scheme      string
+	host        domain.Host
+	username    string
+	password    string
+	hasUserinfo bool
+	path        []string
+	query       []string
+	fragment    string
+	hasFragment bool
+}
+
+// NewBuilder returns a builder of a URL with the scheme and host, such as https and example.com
+func NewBuilder(scheme string, host domain.Host) *Builder {
+	return &Builder{scheme: strings.ToLower(scheme), host: host}
+}
+
+// SetScheme sets the scheme
+func (b *Builder) SetScheme(scheme string) {
+	b.scheme = strings.ToLower(scheme)
+}
+
+// SetHost sets the host, with its port if any
+func (b *Builder) SetHost(host domain.Host) {
+	b.host = host
+}
+
+// SetPort sets the port of the host
+func (b *Builder) SetPort(port uint16) {
+	b.host = b.host.WithPort(port)
+}
+
+// SetUser sets the username and password of the userinfo, omitting the password if empty
+func (b *Builder) SetUser(username, password string) {
+	b.username, b.password, b.hasUserinfo = username, password, true
+}
+
+// AddPath appends the segments to the path, percent-encoding them, hence a segment such as a/b is a single segment
+//
+// Returns an error without appending any segment if a segment is . or .., which would be resolved as a dot-segment
+// even when percent-encoded, as %2E is equivalent to a period (RFC 3986 section 2.3).
+func (b *Builder) AddPath(segments ...string) error {
+	for _, segment := range segments {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("path segment %s is a dot-segment", segment)
+		}
+	}
+	b.path = append(b.path, segments...)
+	return nil
+}
+
+// AddQuery appends the query parameter, percent-encoding its name and value
+func (b *Builder) AddQuery(name, value string) {
+	b.query = append(b.query, escape(name, isQueryChar)+"="+escape(value, isQueryChar))
+}
+
+// SetFragment sets the fragment, percent-encoding it
+func (b *Builder) SetFragment(fragment string) {
+	b.fragment, b.hasFragment = fragment, true
+}
+
+// String returns the URL, such as https://user@example.com:8443/a%20b?q=%26#top
+//
+// The path is "/" if no segments were added.
+func (b *Builder) String() string {
+	var sb strings.Builder
+	sb.WriteString(b.scheme + "://")
+	if b.hasUserinfo {
+		sb.WriteString(escape(b.username, isUserChar))
+		if len(b.password) > 0 {
+			sb.WriteString(":" + escape(b.password, isUserChar))
+		}
+		sb.WriteByte('@')
+	}
+	if b.host.IsName() || b.host.IsAddr() {
+		sb.WriteString(b.host.String())
+	}
+	if len(b.path) == 0 {
+		sb.WriteByte('/')
+	}
+	for _, segment := range b.path {
+		sb.WriteString("/" + escape(segment, isPathChar))
+	}
+	if len(b.query) > 0 {
+		sb.WriteString("?" + strings.Join(b.query, "&"))
+	}
+	if b.hasFragment {
+		sb.WriteString("#" + escape(b.fragment, isFragmentChar))
+	}
+	return sb.String()
+}
+
+// URL returns the URL parsed with Parse
+//
+// Returns an error if the URL is invalid, such as if the scheme is invalid or the host is missing.
+func (b *Builder) URL() (*URL, error) {
+	return Parse(b.String())
+}
+
+// escape percent-encodes the bytes of the string which are not allowed, with upper case hexadecimal digits
+func escape(s string, allowed func(byte) bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; allowed(c) {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(upperHex[c>>4])
+			sb.WriteByte(upperHex[c&0x0f])
+		}
+	}
+	return sb.String()
+}
+
+// characters allowed in components of URLs by RFC 3986 section 3, returning whether the byte is allowed
+
+func isSubDelim(c byte) bool {
+	return strings.IndexByte("!$&'()*+,;=", c) >= 0
+}
+
+func isUserChar(c byte) bool {
+	return isUnreserved(c) || isSubDelim(c)
+}
+
+func isPathChar(c byte) bool {
+	return isUserChar(c) || c == ':' || c == '@'
+}
+
+func isQueryChar(c byte) bool {
+	return isUnreserved(c) || strings.IndexByte("!$'()*,;:@/?", c) >= 0
+}
+
+func isFragmentChar(c byte) bool {
+	return isPathChar(c) || c == '/' || c == '?'
+}
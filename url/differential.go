@@ -0,0 +1,239 @@
+package url
+
+import (
+	"errors"
+	"net/netip"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parser is a URL parser compared by CompareParsers
+type Parser string
+
+// URL parsers compared by CompareParsers
+const (
+	// ParserRegex is the regular expression of Host, which only extracts the host of URLs with an authority
+	ParserRegex Parser = "regex"
+	// ParserNetURL is net/url of the Go standard library, as used by most Go HTTP clients and servers
+	ParserNetURL Parser = "net/url"
+	// ParserWHATWG is Parse, following the WHATWG URL Standard as browsers do
+	ParserWHATWG Parser = "WHATWG"
+)
+
+var parsers = []Parser{ParserRegex, ParserNetURL, ParserWHATWG}
+
+// Component is a component of a URL compared by CompareParsers
+type Component string
+
+// Components of URLs compared by CompareParsers
+const (
+	ComponentHost     Component = "host"
+	ComponentPort     Component = "port"
+	ComponentUserinfo Component = "userinfo"
+	ComponentPath     Component = "path"
+)
+
+var components = []Component{ComponentHost, ComponentPort, ComponentUserinfo, ComponentPath}
+
+// ParseResult holds the components of a URL as interpreted by a parser
+//
+// The components are normalized so that only differences in interpretation remain: the host is in lower case without
+// brackets, with internationalized names in ASCII and IP addresses in canonical form, the port is a decimal number
+// which is the default port of the scheme if none, the userinfo and path are percent-decoded, and the path of a URL
+// with a host is "/" if empty.
+type ParseResult struct {
+	Parser   Parser
+	Host     string
+	Port     string
+	Userinfo string
+	Path     string
+	// Err is the error of the parser, if it rejected the URL
+	Err error
+}
+
+// Difference holds the values of a component of a URL which differs between parsers
+type Difference struct {
+	Component Component
+	// Values are the values of the component by parser, excluding parsers which rejected the URL or do not extract the
+	// component
+	Values map[Parser]string
+}
+
+// ParserDiff holds how a URL is interpreted by different parsers, and the components which differ between them
+type ParserDiff struct {
+	Input       string
+	Results     []ParseResult
+	Differences []Difference
+}
+
+// CompareParsers parses the absolute URL with each parser, i.e. the regular expression of Host, net/url and the
+// WHATWG URL Standard, and returns the components interpreted differently
+//
+// Differences indicate a URL which may confuse an application validating it with one parser and fetching it with
+// another, such as in server-side request forgery (SSRF) and open redirects, e.g. the host of http://good.com#@evil.com/
+// is good.com to browsers and net/url, but good.com#@evil.com to the regular expression. Parsers which reject the URL
+// are not compared.
+func CompareParsers(s string) ParserDiff {
+	d := ParserDiff{
+		Input:   s,
+		Results: []ParseResult{parseRegex(s), parseNetURL(s), parseWHATWG(s)},
+	}
+	for _, c := range components {
+		values := map[Parser]string{}
+		distinct := map[string]bool{}
+		for _, r := range d.Results {
+			if v, ok := r.component(c); ok {
+				values[r.Parser] = v
+				distinct[v] = true
+			}
+		}
+		if len(distinct) > 1 {
+			d.Differences = append(d.Differences, Difference{Component: c, Values: values})
+		}
+	}
+	return d
+}
+
+// HasDifferences returns whether the URL is interpreted differently by the parsers
+func (d ParserDiff) HasDifferences() bool {
+	return len(d.Differences) > 0
+}
+
+// Result returns the result of the parser
+func (d ParserDiff) Result(p Parser) (ParseResult, bool) {
+	for _, r := range d.Results {
+		if r.Parser == p {
+			return r, true
+		}
+	}
+	return ParseResult{}, false
+}
+
+// String returns the component and the values by parser, such as
+// `host: net/url="0x7f.1" WHATWG="127.0.0.1"`
+func (d Difference) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(d.Component))
+	sb.WriteByte(':')
+	for _, p := range parsers {
+		if v, ok := d.Values[p]; ok {
+			sb.WriteString(" " + string(p) + "=" + strconv.Quote(v))
+		}
+	}
+	return sb.String()
+}
+
+// component returns the value of the component, and whether the parser extracted it
+func (r ParseResult) component(c Component) (string, bool) {
+	if r.Err != nil || r.Parser == ParserRegex && c != ComponentHost {
+		return "", false
+	}
+	switch c {
+	case ComponentHost:
+		return r.Host, true
+	case ComponentPort:
+		return r.Port, true
+	case ComponentUserinfo:
+		return r.Userinfo, true
+	default:
+		return r.Path, true
+	}
+}
+
+// authorityRegex matches the start of an absolute URL with an authority, i.e. a scheme followed by a slash or backslash
+var authorityRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:[/\\]`)
+
+// parseRegex returns the host of the regular expression of Host, which is only extracted from URLs with an authority,
+// as it would be the userinfo or path of URLs such as mailto:user@example.com
+func parseRegex(s string) ParseResult {
+	if !authorityRegex.MatchString(s) {
+		return ParseResult{Parser: ParserRegex, Err: errors.New("missing authority")}
+	}
+	return ParseResult{Parser: ParserRegex, Host: normalizeHost(Host(s))}
+}
+
+func parseNetURL(s string) ParseResult {
+	u, err := neturl.Parse(s)
+	if err != nil {
+		return ParseResult{Parser: ParserNetURL, Err: err}
+	}
+	r := ParseResult{
+		Parser: ParserNetURL,
+		Host:   normalizeHost(u.Hostname()),
+		Port:   portOrDefault(strings.ToLower(u.Scheme), u.Port()),
+		Path:   u.Path,
+	}
+	if len(u.Opaque) > 0 {
+		// the path of a URL without an authority, such as mailto:user@example.com, is opaque and not decoded
+		r.Path = u.Opaque
+		if path, err := neturl.PathUnescape(u.Opaque); err == nil {
+			r.Path = path
+		}
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		r.Userinfo = userinfo(u.User.Username(), password)
+	}
+	if len(r.Path) == 0 && len(u.Host) > 0 {
+		r.Path = "/"
+	}
+	return r
+}
+
+func parseWHATWG(s string) ParseResult {
+	u, err := Parse(s)
+	if err != nil {
+		return ParseResult{Parser: ParserWHATWG, Err: err}
+	}
+	port := ""
+	if p, ok := u.Port(); ok {
+		port = strconv.Itoa(int(p))
+	}
+	r := ParseResult{
+		Parser:   ParserWHATWG,
+		Host:     normalizeHost(u.Hostname()),
+		Port:     portOrDefault(u.Scheme(), port),
+		Userinfo: userinfo(string(percentDecode(u.Username())), string(percentDecode(u.Password()))),
+		Path:     string(percentDecode(u.Path())),
+	}
+	if len(r.Path) == 0 && u.HasHost() && !u.HasOpaquePath() {
+		r.Path = "/"
+	}
+	return r
+}
+
+// normalizeHost returns the host in lower case without brackets, converting an internationalized name to ASCII and an
+// IP address to canonical form, such that bücher.de equals xn--bcher-kva.de and [2001:db8::0:1] equals 2001:db8::1
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.String()
+	}
+	if !isASCII(host) {
+		if ascii, err := idnaProfile.ToASCII(host); err == nil {
+			return ascii
+		}
+	}
+	return host
+}
+
+// portOrDefault returns the port as a decimal number without leading zeros, such that 080 equals 80, or the default
+// port of the scheme if none
+func portOrDefault(scheme, port string) string {
+	if p := specialSchemes[scheme]; len(port) == 0 && p > 0 {
+		return strconv.Itoa(int(p))
+	}
+	if p, err := strconv.ParseUint(port, 10, 64); err == nil {
+		return strconv.FormatUint(p, 10)
+	}
+	return port
+}
+
+func userinfo(username, password string) string {
+	if len(password) == 0 {
+		return username
+	}
+	return username + ":" + password
+}
@@ -13,7 +13,7 @@ var hostRegex = regexp.MustCompile(`^(?:(?:\w+:)*\/\/?)*(?:\w+(?::\w+)?@)*([^:\/
 //
 // The function is designed to be as generic as possible, working with non-RFC compliant URLs that may occur as a
 // result of URL fuzzing. Handles various cases of scheme, userinfo and relative part.
-// Can lead to unexpected results with relative URLs.
+// Can lead to unexpected results with relative URLs, which can be resolved against the URL of the page with Resolve.
 func Host(u string) string {
 	match := hostRegex.FindStringSubmatch(u)
 	if len(match) > 1 {
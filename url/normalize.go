@@ -0,0 +1,234 @@
+package url
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// Normalization is a set of normalizations of URLs applied by Normalize
+type Normalization uint
+
+// Normalizations of URLs, as described in RFC 3986 section 6
+const (
+	// NormalizeCase converts the scheme and host to lower case, and hexadecimal digits of percent-encodings to upper
+	// case, such as HTTP://Example.COM/%7e to http://example.com/%7E
+	NormalizeCase Normalization = 1 << iota
+	// NormalizeIDN converts an internationalized host to ASCII (punycode) with domain.Parse, such as пример.мкд to
+	// xn--e1afmkfd.xn--d1alf
+	NormalizeIDN
+	// NormalizeDefaultPort removes the default port of the scheme, and an empty port, such as http://example.com:80/
+	NormalizeDefaultPort
+	// NormalizeEmptyPath replaces the empty path of a URL with a host by "/", such as http://example.com
+	NormalizeEmptyPath
+	// NormalizeDotSegments removes "." and ".." segments of the path, such as /a/./b/../c to /a/c
+	NormalizeDotSegments
+	// NormalizeUnreserved decodes percent-encoded unreserved characters, i.e. letters, digits, '-', '.', '_' and '~',
+	// such as /%7Euser to /~user
+	NormalizeUnreserved
+	// NormalizeSortQuery sorts the query parameters by name, keeping the order of the values of the same name
+	NormalizeSortQuery
+	// NormalizeRemoveFragment removes the fragment
+	NormalizeRemoveFragment
+	// NormalizeRemoveTracking removes the query parameters used for tracking, see IsTrackingParameter, and empty
+	// parameters, and the query if no parameters remain
+	NormalizeRemoveTracking
+	// NormalizeAddTrailingSlash adds a slash to a path not ending with one, unless the last segment is a file name
+	// with an extension, such as /a/b to /a/b/ but not /a/b.html
+	NormalizeAddTrailingSlash
+	// NormalizeRemoveTrailingSlash removes the trailing slash of a path other than "/", such as /a/b/ to /a/b, unless
+	// NormalizeAddTrailingSlash is set
+	NormalizeRemoveTrailingSlash
+)
+
+// Sets of normalizations of URLs
+const (
+	// NormalizeSafe are the normalizations which preserve the semantics of URLs, as described in RFC 3986 section
+	// 6.2.2 and 6.2.3
+	NormalizeSafe = NormalizeCase | NormalizeIDN | NormalizeDefaultPort | NormalizeEmptyPath | NormalizeDotSegments |
+		NormalizeUnreserved
+	// NormalizeDedup are the normalizations for deduplicating crawled URLs, which may change the semantics of URLs in
+	// rare cases
+	NormalizeDedup = NormalizeSafe | NormalizeSortQuery | NormalizeRemoveFragment | NormalizeRemoveTracking
+)
+
+// Has returns whether the normalization includes all normalizations of other
+func (n Normalization) Has(other Normalization) bool {
+	return n&other == other
+}
+
+// trackingParameters are the names of query parameters used for tracking, in addition to utm_ prefixed parameters
+var trackingParameters = map[string]bool{
+	"fbclid":    true,
+	"gclid":     true,
+	"gclsrc":    true,
+	"dclid":     true,
+	"gbraid":    true,
+	"wbraid":    true,
+	"msclkid":   true,
+	"yclid":     true,
+	"twclid":    true,
+	"ttclid":    true,
+	"igshid":    true,
+	"mc_cid":    true,
+	"mc_eid":    true,
+	"_hsenc":    true,
+	"_hsmi":     true,
+	"mkt_tok":   true,
+	"_ga":       true,
+	"_gl":       true,
+	"vero_id":   true,
+	"li_fat_id": true,
+}
+
+// IsTrackingParameter returns whether the name of the query parameter is a known tracking parameter, such as
+// utm_source or fbclid, which are compared case-insensitively
+func IsTrackingParameter(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || trackingParameters[name]
+}
+
+// Normalize returns the URL with the normalizations applied, leaving the other components as they are, such as
+// HTTP://www.Example.com:80/a/../b?utm_source=x&q=1#top to http://www.example.com/b?q=1 with NormalizeDedup
+//
+// The URL is processed as specified by RFC 3986, hence it is not parsed or encoded as by browsers, unlike Parse, and
+// may be relative. Returns an error if the scheme or port is invalid.
+func Normalize(s string, n Normalization) (string, error) {
+	r, err := splitReference(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %s: %w", s, err)
+	}
+	if n.Has(NormalizeCase) {
+		r.scheme = strings.ToLower(r.scheme)
+	}
+
+	if r.hasAuthority {
+		if r.authority, err = normalizeAuthority(strings.ToLower(r.scheme), r.authority, n); err != nil {
+			return "", fmt.Errorf("invalid URL %s: %w", s, err)
+		}
+		if len(r.path) == 0 && n.Has(NormalizeEmptyPath) {
+			r.path = "/"
+		}
+	}
+
+	path := normalizePercentEncoding(r.path, n)
+	if r.hasScheme && n.Has(NormalizeDotSegments) {
+		path = removeDotSegments(path)
+	}
+	switch {
+	case n.Has(NormalizeAddTrailingSlash):
+		last := path[strings.LastIndex(path, "/")+1:]
+		if len(path) > 0 && len(last) > 0 && !strings.Contains(last, ".") {
+			path += "/"
+		}
+	case n.Has(NormalizeRemoveTrailingSlash):
+		if len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+	}
+	r.path = path
+
+	if r.hasQuery {
+		r.query = normalizeQuery(normalizePercentEncoding(r.query, n), n)
+		r.hasQuery = len(r.query) > 0 || !n.Has(NormalizeRemoveTracking)
+	}
+	r.fragment = normalizePercentEncoding(r.fragment, n)
+	r.hasFragment = r.hasFragment && !n.Has(NormalizeRemoveFragment)
+	return r.String(), nil
+}
+
+// normalizeAuthority normalizes the userinfo, host and port of the authority
+func normalizeAuthority(scheme, authority string, n Normalization) (string, error) {
+	userinfo, hostport := "", authority
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		userinfo, hostport = authority[:i+1], authority[i+1:]
+	}
+
+	host, port := hostport, ""
+	if i := strings.LastIndex(hostport, ":"); i >= 0 && i > strings.LastIndex(hostport, "]") {
+		host, port = hostport[:i], hostport[i:]
+	}
+	if len(port) > 1 {
+		p, err := strconv.ParseUint(port[1:], 10, 16)
+		if err != nil {
+			return "", fmt.Errorf("invalid port %s", port[1:])
+		}
+		if defaultPort := specialSchemes[scheme]; n.Has(NormalizeDefaultPort) && defaultPort > 0 &&
+			p == uint64(defaultPort) {
+			port = ""
+		}
+	}
+	if port == ":" && n.Has(NormalizeDefaultPort) {
+		port = ""
+	}
+
+	host = normalizePercentEncoding(host, n)
+	if n.Has(NormalizeCase) {
+		// the decoded unreserved characters, such as %41, are lower-cased too, while the hexadecimal digits of the
+		// percent-encodings are upper case
+		host = normalizePercentEncoding(strings.ToLower(host), n)
+	}
+	if n.Has(NormalizeIDN) && !isASCII(host) {
+		if name, err := domain.Parse(host); err == nil {
+			// a trailing dot is kept, as for ASCII hosts
+			host = name.String() + host[len(strings.TrimSuffix(host, ".")):]
+		}
+	}
+	return normalizePercentEncoding(userinfo, n) + host + port, nil
+}
+
+// normalizePercentEncoding converts the hexadecimal digits of percent-encodings to upper case and decodes unreserved
+// characters, as configured, leaving invalid percent-encodings as they are
+func normalizePercentEncoding(s string, n Normalization) string {
+	if !strings.Contains(s, "%") || !n.Has(NormalizeCase) && !n.Has(NormalizeUnreserved) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		switch c := unhex(s[i+1])<<4 | unhex(s[i+2]); {
+		case n.Has(NormalizeUnreserved) && isUnreserved(c):
+			sb.WriteByte(c)
+		case n.Has(NormalizeCase):
+			sb.WriteString(strings.ToUpper(s[i : i+3]))
+		default:
+			sb.WriteString(s[i : i+3])
+		}
+		i += 2
+	}
+	return sb.String()
+}
+
+// normalizeQuery removes tracking parameters and sorts the parameters, as configured
+func normalizeQuery(query string, n Normalization) string {
+	if !n.Has(NormalizeSortQuery) && !n.Has(NormalizeRemoveTracking) {
+		return query
+	}
+	var params []string
+	for _, param := range strings.Split(query, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		if n.Has(NormalizeRemoveTracking) && (len(param) == 0 || IsTrackingParameter(name)) {
+			continue
+		}
+		params = append(params, param)
+	}
+	if n.Has(NormalizeSortQuery) {
+		sort.SliceStable(params, func(i, j int) bool {
+			a, _, _ := strings.Cut(params[i], "=")
+			b, _, _ := strings.Cut(params[j], "=")
+			return a < b
+		})
+	}
+	return strings.Join(params, "&")
+}
+
+func isUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' ||
+		c == '~'
+}
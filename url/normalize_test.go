@@ -0,0 +1,107 @@
+package url_test
+
+import (
+	"testing"
+
+	"github.com/detectify/n5/url"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize_WithSafeNormalizations_ShouldPreserveSemantics(t *testing.T) {
+	for s, expected := range map[string]string{
+		"HTTP://www.Example.COM":                       "http://www.example.com/",
+		"http://example.com:80/a/./b/../c":             "http://example.com/a/c",
+		"https://example.com:443?q":                    "https://example.com/?q",
+		"https://example.com:8443/":                    "https://example.com:8443/",
+		"http://example.com:/":                         "http://example.com/",
+		"http://example.com/%7euser/%2fx%2E%3a":        "http://example.com/~user/%2Fx.%3A",
+		"http://User%3a@Example.com/a?b=%7e#%7e":       "http://User%3A@example.com/a?b=~#~",
+		"http://пример.мкд/":                           "http://xn--e1afmkfd.xn--d1alf/",
+		"http://пример.мкд./":                          "http://xn--e1afmkfd.xn--d1alf./",
+		"http://Example.com./":                         "http://example.com./",
+		"http://Ex%c3%a4mple.com/":                     "http://ex%C3%A4mple.com/",
+		"http://%41.com/":                              "http://a.com/",
+		"http://[2001:DB8::1]:80/":                     "http://[2001:db8::1]/",
+		"foo://example.com:80/":                        "foo://example.com:80/",
+		"http://example.com/a/b/../../../c":            "http://example.com/c",
+		"http://example.com/a/%2e%2E/b":                "http://example.com/b",
+		"../a/./b":                                     "../a/./b",
+		"mailto:User@Example.com":                      "mailto:User@Example.com",
+		"http://example.com/?utm_source=x&b=2&a=1#top": "http://example.com/?utm_source=x&b=2&a=1#top",
+	} {
+		normalized, err := url.Normalize(s, url.NormalizeSafe)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, normalized, s)
+	}
+}
+
+func TestNormalize_WithDedupNormalizations_ShouldRemoveTrackingAndSortQuery(t *testing.T) {
+	for s, expected := range map[string]string{
+		"HTTP://www.Example.com:80/a/../b?utm_source=x&q=1#top": "http://www.example.com/b?q=1",
+		"http://example.com/?b=2&a=1&b=1&&fbclid=x":             "http://example.com/?a=1&b=2&b=1",
+		"http://example.com/?UTM_Campaign=x&gclid=y":            "http://example.com/",
+		"http://example.com/#":                                  "http://example.com/",
+	} {
+		normalized, err := url.Normalize(s, url.NormalizeDedup)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, normalized, s)
+	}
+}
+
+func TestNormalize_WithTrailingSlashPolicy_ShouldChangePath(t *testing.T) {
+	for s, expected := range map[string]string{
+		"http://example.com/a/b":      "http://example.com/a/b/",
+		"http://example.com/a/b/":     "http://example.com/a/b/",
+		"http://example.com/a/b.html": "http://example.com/a/b.html",
+		"http://example.com":          "http://example.com/",
+	} {
+		normalized, err := url.Normalize(s, url.NormalizeSafe|url.NormalizeAddTrailingSlash)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, normalized, s)
+	}
+	for s, expected := range map[string]string{
+		"http://example.com/a/b/": "http://example.com/a/b",
+		"http://example.com/?q":   "http://example.com/?q",
+	} {
+		normalized, err := url.Normalize(s, url.NormalizeSafe|url.NormalizeRemoveTrailingSlash)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, normalized, s)
+	}
+}
+
+func TestNormalize_WhenNormalized_ShouldNotChange(t *testing.T) {
+	for _, s := range []string{
+		"HTTP://www.Example.COM", "http://%41.com/", "http://Ex%c3%a4mple.com/", "http://%c3%84.com/",
+		"http://User%3a@Example.com/a?b=%7e#%7e", "http://example.com/a/%2e%2E/b", "http://example.com/%2E%2e%2fx",
+		"http://пример.мкд./", "http://[2001:DB8::1]:80/", "HTTP://www.Example.com:80/a/../b?utm_source=x&q=1#top",
+		"http://example.com/?b=2&a=1&b=1&&fbclid=x", "../a/./b", "mailto:User@Example.com",
+	} {
+		for _, n := range []url.Normalization{url.NormalizeSafe, url.NormalizeDedup} {
+			normalized, err := url.Normalize(s, n)
+			require.NoError(t, err, s)
+			again, err := url.Normalize(normalized, n)
+			require.NoError(t, err, s)
+			require.Equal(t, normalized, again, s)
+		}
+	}
+}
+
+func TestNormalize_WithoutNormalizations_ShouldReturnURL(t *testing.T) {
+	s := "HTTP://Example.com:80/a/../%7e?b&a#f"
+	normalized, err := url.Normalize(s, 0)
+	require.NoError(t, err)
+	require.Equal(t, s, normalized)
+}
+
+func TestNormalize_WithInvalidURL_ShouldReturnError(t *testing.T) {
+	for _, s := range []string{"http://example.com:http/", "http://example.com:65536/", "1http://example.com/"} {
+		_, err := url.Normalize(s, url.NormalizeSafe)
+		require.Error(t, err, s)
+	}
+}
+
+func TestIsTrackingParameter_WithName_ShouldMatchKnownParameters(t *testing.T) {
+	require.True(t, url.IsTrackingParameter("utm_medium"))
+	require.True(t, url.IsTrackingParameter("FBCLID"))
+	require.False(t, url.IsTrackingParameter("id"))
+}
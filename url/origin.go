@@ -0,0 +1,199 @@
+package url
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/detectify/n5/domain"
+)
+
+// opaqueOrigins is the number of opaque origins created, identifying each opaque origin
+var opaqueOrigins atomic.Uint64
+
+// Origin is the origin of a URL as specified by the HTML Standard (https://html.spec.whatwg.org/#origin), used by
+// browsers for the same-origin policy, CORS and cookies
+//
+// An origin is either a tuple of scheme, host and port, such as https://example.com:8443, or an opaque origin, such as
+// the origin of data: and file: URLs, which is only same-origin with itself. An origin is immutable, and created with
+// URL.Origin or ParseOrigin.
+type Origin struct {
+	scheme  string
+	host    string
+	port    uint16
+	hasPort bool
+	opaque  uint64
+}
+
+// Origin returns the origin of the URL
+//
+// The origin of http, https, ws, wss and ftp URLs is their scheme, host and port, the origin of blob: URLs is the
+// origin of the URL of their path if http or https, and the origin of other URLs, including data: and file: URLs, is
+// a new opaque origin.
+func (u *URL) Origin() Origin {
+	switch u.scheme {
+	case "ftp", "http", "https", "ws", "wss":
+		return Origin{scheme: u.scheme, host: u.host, port: u.port, hasPort: u.hasPort}
+	case "blob":
+		if path, err := Parse(u.Path()); err == nil && (path.scheme == "http" || path.scheme == "https") {
+			return path.Origin()
+		}
+	}
+	return newOpaqueOrigin()
+}
+
+func newOpaqueOrigin() Origin {
+	return Origin{opaque: opaqueOrigins.Add(1)}
+}
+
+// ParseOrigin parses the serialized origin, such as the value of the Origin header, e.g. https://example.com:8443
+//
+// "null" is parsed as a new opaque origin. Returns an error if the string is not a URL without userinfo, path, query
+// and fragment, or the URL does not have a tuple origin.
+func ParseOrigin(s string) (Origin, error) {
+	if s == "null" {
+		return newOpaqueOrigin(), nil
+	}
+	u, err := Parse(s)
+	if err != nil {
+		return Origin{}, fmt.Errorf("invalid origin %s: %w", s, err)
+	}
+	_, hasQuery := u.Query()
+	_, hasFragment := u.Fragment()
+	o := u.Origin()
+	if o.IsOpaque() || len(u.username) > 0 || len(u.password) > 0 || u.Path() != "/" || hasQuery || hasFragment ||
+		strings.HasSuffix(s, "/") {
+		return Origin{}, fmt.Errorf("invalid origin %s", s)
+	}
+	return o, nil
+}
+
+// IsOpaque returns whether the origin is an opaque origin, i.e. not a tuple of scheme, host and port
+func (o Origin) IsOpaque() bool {
+	return o.opaque > 0
+}
+
+// Scheme returns the scheme of the origin, or an empty string if opaque
+func (o Origin) Scheme() string {
+	return o.scheme
+}
+
+// Hostname returns the host of the origin as serialized in URLs, with IPv6 addresses in brackets, or an empty string
+// if opaque
+func (o Origin) Hostname() string {
+	return o.host
+}
+
+// Host returns the host of the origin with its port, if any, and whether it is valid
+//
+// Returns false if the origin is opaque, or the host is not a valid domain name, such as a_b.example.com.
+func (o Origin) Host() (domain.Host, bool) {
+	if o.IsOpaque() {
+		return domain.Host{}, false
+	}
+	h, err := domain.ParseHost(o.host)
+	if err != nil {
+		return domain.Host{}, false
+	}
+	if o.hasPort {
+		h = h.WithPort(o.port)
+	}
+	return h, true
+}
+
+// Port returns the port of the origin, and whether the origin has a port, i.e. is a tuple origin with a port other
+// than the default port of the scheme
+func (o Origin) Port() (uint16, bool) {
+	return o.port, o.hasPort
+}
+
+// String returns the serialization of the origin, such as https://example.com:8443, or "null" if opaque
+func (o Origin) String() string {
+	if o.IsOpaque() {
+		return "null"
+	}
+	s := o.scheme + "://" + o.host
+	if o.hasPort {
+		s += ":" + strconv.Itoa(int(o.port))
+	}
+	return s
+}
+
+// IsSameOrigin returns whether the origins are the same, i.e. the same opaque origin, or tuples of the same scheme,
+// host and port
+func (o Origin) IsSameOrigin(other Origin) bool {
+	return o == other
+}
+
+// Site is the site of an origin as specified by the HTML Standard (https://html.spec.whatwg.org/#sites), i.e. the
+// scheme and registrable domain of the host, such as https://example.co.uk for https://www.example.co.uk:8443
+//
+// The registrable domain is the apex domain of domain.Name, including private public suffixes, hence the sites of
+// a.github.io and b.github.io differ. The site of a host without registrable domain, such as an IP address,
+// localhost or a public suffix, is the host, and the site of an opaque origin is the origin.
+type Site struct {
+	scheme string
+	host   string
+	opaque uint64
+}
+
+// Site returns the site of the origin
+func (o Origin) Site() Site {
+	if o.IsOpaque() {
+		return Site{opaque: o.opaque}
+	}
+	return Site{scheme: o.scheme, host: registrableDomain(o.host)}
+}
+
+// registrableDomain returns the registrable domain of the host, or the host if it does not have one
+func registrableDomain(host string) string {
+	name, err := domain.Parse(host)
+	if strings.HasPrefix(host, "[") || endsInNumber(host) || err != nil || !name.IsApexOrSubdomain() {
+		return host
+	}
+	if strings.HasSuffix(host, ".") {
+		return name.Apex().String() + "."
+	}
+	return name.Apex().String()
+}
+
+// IsOpaque returns whether the site is the site of an opaque origin
+func (s Site) IsOpaque() bool {
+	return s.opaque > 0
+}
+
+// Scheme returns the scheme of the site, or an empty string if opaque
+func (s Site) Scheme() string {
+	return s.scheme
+}
+
+// Hostname returns the registrable domain of the site, or its host if it does not have one, or an empty string if
+// opaque
+func (s Site) Hostname() string {
+	return s.host
+}
+
+// String returns the site as a URL, such as https://example.co.uk, or "null" if opaque
+func (s Site) String() string {
+	if s.IsOpaque() {
+		return "null"
+	}
+	return s.scheme + "://" + s.host
+}
+
+// IsSameSite returns whether the origins are schemelessly same site, i.e. the same opaque origin, or tuples with the
+// same registrable domain or host regardless of scheme and port, such as http://a.example.com and
+// https://b.example.com
+//
+// This is how SameSite cookies were historically scoped. See IsSchemefulSameSite for the current definition.
+func (o Origin) IsSameSite(other Origin) bool {
+	s, t := o.Site(), other.Site()
+	return s.opaque == t.opaque && s.host == t.host
+}
+
+// IsSchemefulSameSite returns whether the origins are same site, i.e. schemelessly same site with the same scheme,
+// such as https://a.example.com and https://b.example.com, but not http://a.example.com
+func (o Origin) IsSchemefulSameSite(other Origin) bool {
+	return o.Site() == other.Site()
+}
@@ -0,0 +1,510 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// state is a state of the basic URL parser of the WHATWG URL Standard
+type state int
+
+const (
+	stateSchemeStart state = iota
+	stateScheme
+	stateNoScheme
+	stateSpecialRelativeOrAuthority
+	statePathOrAuthority
+	stateRelative
+	stateRelativeSlash
+	stateSpecialAuthoritySlashes
+	stateSpecialAuthorityIgnoreSlashes
+	stateAuthority
+	stateHost
+	statePort
+	stateFile
+	stateFileSlash
+	stateFileHost
+	statePathStart
+	statePath
+	stateOpaquePath
+	stateQuery
+	stateFragment
+)
+
+const eof = -1
+
+// parse runs the basic URL parser of the WHATWG URL Standard, with the base URL if not nil
+func parse(s string, base *URL) (*URL, error) {
+	u, err := parseURL(s, base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", s, err)
+	}
+	return u, nil
+}
+
+func parseURL(s string, base *URL) (*URL, error) {
+	input := []rune(strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, strings.TrimFunc(s, func(r rune) bool {
+		return r <= ' '
+	})))
+
+	u := &URL{}
+	st := stateSchemeStart
+	var buf []rune
+	var atSignSeen, insideBrackets, passwordTokenSeen bool
+	for p := 0; ; p++ {
+		c := rune(eof)
+		if p < len(input) {
+			c = input[p]
+		}
+		slash := c == '/' || u.IsSpecial() && c == '\\'
+
+		switch st {
+		case stateSchemeStart:
+			if isASCIIAlpha(c) {
+				buf = append(buf, toLower(c))
+				st = stateScheme
+			} else {
+				st = stateNoScheme
+				p--
+			}
+
+		case stateScheme:
+			switch {
+			case isASCIIAlpha(c) || isASCIIDigit(c) || c == '+' || c == '-' || c == '.':
+				buf = append(buf, toLower(c))
+			case c == ':':
+				u.scheme, buf = string(buf), buf[:0]
+				switch {
+				case u.scheme == "file":
+					st = stateFile
+				case u.IsSpecial() && base != nil && base.scheme == u.scheme:
+					st = stateSpecialRelativeOrAuthority
+				case u.IsSpecial():
+					st = stateSpecialAuthoritySlashes
+				case p+1 < len(input) && input[p+1] == '/':
+					st = statePathOrAuthority
+					p++
+				default:
+					u.hasOpaquePath = true
+					st = stateOpaquePath
+				}
+			default:
+				// start over without scheme
+				buf = buf[:0]
+				st = stateNoScheme
+				p = -1
+			}
+
+		case stateNoScheme:
+			switch {
+			case base == nil || base.hasOpaquePath && c != '#':
+				return nil, errors.New("missing scheme")
+			case base.hasOpaquePath:
+				u.scheme, u.opaquePath, u.hasOpaquePath = base.scheme, base.opaquePath, true
+				u.query, u.hasQuery = base.query, base.hasQuery
+				u.hasFragment = true
+				st = stateFragment
+			case base.scheme != "file":
+				st = stateRelative
+				p--
+			default:
+				st = stateFile
+				p--
+			}
+
+		case stateSpecialRelativeOrAuthority:
+			if c == '/' && p+1 < len(input) && input[p+1] == '/' {
+				st = stateSpecialAuthorityIgnoreSlashes
+				p++
+			} else {
+				st = stateRelative
+				p--
+			}
+
+		case statePathOrAuthority:
+			if c == '/' {
+				st = stateAuthority
+			} else {
+				st = statePath
+				p--
+			}
+
+		case stateRelative:
+			u.scheme = base.scheme
+			if c == '/' || u.IsSpecial() && c == '\\' {
+				st = stateRelativeSlash
+				break
+			}
+			u.copyAuthority(base)
+			u.path = append([]string(nil), base.path...)
+			u.query, u.hasQuery = base.query, base.hasQuery
+			switch c {
+			case '?':
+				u.query, u.hasQuery = "", true
+				st = stateQuery
+			case '#':
+				u.hasFragment = true
+				st = stateFragment
+			case eof:
+			default:
+				u.query, u.hasQuery = "", false
+				u.shortenPath()
+				st = statePath
+				p--
+			}
+
+		case stateRelativeSlash:
+			switch {
+			case u.IsSpecial() && (c == '/' || c == '\\'):
+				st = stateSpecialAuthorityIgnoreSlashes
+			case c == '/':
+				st = stateAuthority
+			default:
+				u.copyAuthority(base)
+				st = statePath
+				p--
+			}
+
+		case stateSpecialAuthoritySlashes:
+			if c == '/' && p+1 < len(input) && input[p+1] == '/' {
+				p++
+			} else {
+				p--
+			}
+			st = stateSpecialAuthorityIgnoreSlashes
+
+		case stateSpecialAuthorityIgnoreSlashes:
+			if c != '/' && c != '\\' {
+				st = stateAuthority
+				p--
+			}
+
+		case stateAuthority:
+			switch {
+			case c == '@':
+				if atSignSeen {
+					buf = append([]rune("%40"), buf...)
+				}
+				atSignSeen = true
+				var username, password []rune
+				for _, r := range buf {
+					switch {
+					case r == ':' && !passwordTokenSeen:
+						passwordTokenSeen = true
+					case passwordTokenSeen:
+						password = appendEncoded(password, r, inUserinfoSet)
+					default:
+						username = appendEncoded(username, r, inUserinfoSet)
+					}
+				}
+				u.username += string(username)
+				u.password += string(password)
+				buf = buf[:0]
+			case c == eof || slash || c == '?' || c == '#':
+				if atSignSeen && len(buf) == 0 {
+					return nil, errors.New("missing host")
+				}
+				p -= len(buf) + 1
+				buf = buf[:0]
+				st = stateHost
+			default:
+				buf = append(buf, c)
+			}
+
+		case stateHost:
+			switch {
+			case c == ':' && !insideBrackets:
+				if len(buf) == 0 {
+					return nil, errors.New("missing host")
+				}
+				if err := u.setHost(string(buf)); err != nil {
+					return nil, err
+				}
+				buf = buf[:0]
+				st = statePort
+			case c == eof || slash || c == '?' || c == '#':
+				p--
+				if u.IsSpecial() && len(buf) == 0 {
+					return nil, errors.New("missing host")
+				}
+				if err := u.setHost(string(buf)); err != nil {
+					return nil, err
+				}
+				buf = buf[:0]
+				st = statePathStart
+			default:
+				if c == '[' {
+					insideBrackets = true
+				} else if c == ']' {
+					insideBrackets = false
+				}
+				buf = append(buf, c)
+			}
+
+		case statePort:
+			switch {
+			case isASCIIDigit(c):
+				buf = append(buf, c)
+			case c == eof || slash || c == '?' || c == '#':
+				if len(buf) > 0 {
+					port, err := strconv.ParseUint(string(buf), 10, 16)
+					if err != nil {
+						return nil, fmt.Errorf("invalid port %s", string(buf))
+					}
+					if port != uint64(specialSchemes[u.scheme]) || !u.IsSpecial() {
+						u.port, u.hasPort = uint16(port), true
+					}
+					buf = buf[:0]
+				}
+				st = statePathStart
+				p--
+			default:
+				return nil, fmt.Errorf("invalid port %s", string(append(buf, c)))
+			}
+
+		case stateFile:
+			u.scheme = "file"
+			u.host, u.hasHost = "", true
+			switch {
+			case c == '/' || c == '\\':
+				st = stateFileSlash
+			case base != nil && base.scheme == "file":
+				u.host, u.hasHost, u.addr = base.host, base.hasHost, base.addr
+				u.path = append([]string(nil), base.path...)
+				u.query, u.hasQuery = base.query, base.hasQuery
+				switch c {
+				case '?':
+					u.query, u.hasQuery = "", true
+					st = stateQuery
+				case '#':
+					u.hasFragment = true
+					st = stateFragment
+				case eof:
+				default:
+					u.query, u.hasQuery = "", false
+					if startsWithWindowsDriveLetter(input[p:]) {
+						u.path = nil
+					} else {
+						u.shortenPath()
+					}
+					st = statePath
+					p--
+				}
+			default:
+				st = statePath
+				p--
+			}
+
+		case stateFileSlash:
+			if c == '/' || c == '\\' {
+				st = stateFileHost
+				break
+			}
+			if base != nil && base.scheme == "file" {
+				u.host, u.hasHost, u.addr = base.host, base.hasHost, base.addr
+				if !startsWithWindowsDriveLetter(input[p:]) && len(base.path) > 0 &&
+					isNormalizedWindowsDriveLetter(base.path[0]) {
+					u.path = append(u.path, base.path[0])
+				}
+			}
+			st = statePath
+			p--
+
+		case stateFileHost:
+			if c != eof && c != '/' && c != '\\' && c != '?' && c != '#' {
+				buf = append(buf, c)
+				break
+			}
+			p--
+			switch {
+			case isWindowsDriveLetter(buf):
+				// the buffer is kept as the first segment of the path, e.g. file://C:/
+				st = statePath
+			case len(buf) == 0:
+				u.host, u.hasHost = "", true
+				st = statePathStart
+			default:
+				if err := u.setHost(string(buf)); err != nil {
+					return nil, err
+				}
+				if u.host == "localhost" {
+					u.host = ""
+				}
+				buf = buf[:0]
+				st = statePathStart
+			}
+
+		case statePathStart:
+			switch {
+			case u.IsSpecial():
+				st = statePath
+				if c != '/' && c != '\\' {
+					p--
+				}
+			case c == '?':
+				u.query, u.hasQuery = "", true
+				st = stateQuery
+			case c == '#':
+				u.hasFragment = true
+				st = stateFragment
+			case c != eof:
+				st = statePath
+				if c != '/' {
+					p--
+				}
+			}
+
+		case statePath:
+			if c != eof && !slash && c != '?' && c != '#' {
+				buf = appendEncoded(buf, c, inPathSet)
+				break
+			}
+			switch segment := string(buf); {
+			case isDoubleDotSegment(segment):
+				u.shortenPath()
+				if !slash {
+					u.path = append(u.path, "")
+				}
+			case isSingleDotSegment(segment):
+				if !slash {
+					u.path = append(u.path, "")
+				}
+			default:
+				if u.scheme == "file" && len(u.path) == 0 && isWindowsDriveLetter(buf) {
+					segment = string(buf[:1]) + ":"
+				}
+				u.path = append(u.path, segment)
+			}
+			buf = buf[:0]
+			switch c {
+			case '?':
+				u.query, u.hasQuery = "", true
+				st = stateQuery
+			case '#':
+				u.hasFragment = true
+				st = stateFragment
+			}
+
+		case stateOpaquePath:
+			if c != eof && c != '?' && c != '#' {
+				buf = appendEncoded(buf, c, inC0ControlSet)
+				break
+			}
+			u.opaquePath, buf = string(buf), buf[:0]
+			switch c {
+			case '?':
+				u.query, u.hasQuery = "", true
+				st = stateQuery
+			case '#':
+				u.hasFragment = true
+				st = stateFragment
+			}
+
+		case stateQuery:
+			if c != eof && c != '#' {
+				buf = append(buf, c)
+				break
+			}
+			set := inQuerySet
+			if u.IsSpecial() {
+				set = inSpecialQuerySet
+			}
+			var query []rune
+			for _, r := range buf {
+				query = appendEncoded(query, r, set)
+			}
+			u.query, buf = string(query), buf[:0]
+			if c == '#' {
+				u.hasFragment = true
+				st = stateFragment
+			}
+
+		case stateFragment:
+			if c != eof {
+				buf = appendEncoded(buf, c, inFragmentSet)
+			} else {
+				u.fragment = string(buf)
+			}
+		}
+
+		if p >= len(input) {
+			return u, nil
+		}
+	}
+}
+
+// setHost parses the host, which is opaque unless the scheme is special
+func (u *URL) setHost(s string) error {
+	host, addr, err := parseHost(s, !u.IsSpecial())
+	if err != nil {
+		return err
+	}
+	u.host, u.hasHost, u.addr = host, true, addr
+	return nil
+}
+
+func (u *URL) copyAuthority(base *URL) {
+	u.username, u.password = base.username, base.password
+	u.host, u.hasHost, u.addr = base.host, base.hasHost, base.addr
+	u.port, u.hasPort = base.port, base.hasPort
+}
+
+// shortenPath removes the last segment of the path, unless it is the drive letter of a file URL
+func (u *URL) shortenPath() {
+	if u.scheme == "file" && len(u.path) == 1 && isNormalizedWindowsDriveLetter(u.path[0]) {
+		return
+	}
+	if len(u.path) > 0 {
+		u.path = u.path[:len(u.path)-1]
+	}
+}
+
+func isSingleDotSegment(s string) bool {
+	return s == "." || strings.EqualFold(s, "%2e")
+}
+
+func isDoubleDotSegment(s string) bool {
+	switch strings.ToLower(s) {
+	case "..", ".%2e", "%2e.", "%2e%2e":
+		return true
+	}
+	return false
+}
+
+// isWindowsDriveLetter returns whether the code points are a letter followed by ':' or '|', such as C|
+func isWindowsDriveLetter(s []rune) bool {
+	return len(s) == 2 && isASCIIAlpha(s[0]) && (s[1] == ':' || s[1] == '|')
+}
+
+func isNormalizedWindowsDriveLetter(s string) bool {
+	return len(s) == 2 && isASCIIAlpha(rune(s[0])) && s[1] == ':'
+}
+
+// startsWithWindowsDriveLetter returns whether the code points start with a drive letter, followed by the end or a
+// path, query or fragment delimiter
+func startsWithWindowsDriveLetter(s []rune) bool {
+	return len(s) >= 2 && isWindowsDriveLetter(s[:2]) &&
+		(len(s) == 2 || s[2] == '/' || s[2] == '\\' || s[2] == '?' || s[2] == '#')
+}
+
+func isASCIIAlpha(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func toLower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
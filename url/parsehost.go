@@ -0,0 +1,162 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/detectify/n5/ip"
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile is the UTS #46 profile of the domain to ASCII algorithm of the WHATWG URL Standard, which allows
+// characters not allowed by STD3 (beStrict is false)
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.CheckJoiners(true),
+	idna.CheckHyphens(false),
+	idna.StrictDomainName(false),
+	idna.Transitional(false),
+	idna.VerifyDNSLength(false),
+)
+
+// parseHost parses the host of a URL, returning the serialized host and the IP address, if the host is one
+//
+// Hosts of URLs with special schemes are domain names and IPv4 addresses, which may be percent-encoded or in any
+// notation of ip.ParseLenient, and other hosts are opaque. Both may be IPv6 addresses in brackets.
+func parseHost(s string, opaque bool) (string, netip.Addr, error) {
+	if strings.HasPrefix(s, "[") {
+		if !strings.HasSuffix(s, "]") {
+			return "", netip.Addr{}, fmt.Errorf("invalid IPv6 address %s", s)
+		}
+		addr, err := netip.ParseAddr(s[1 : len(s)-1])
+		if err != nil || !addr.Is6() || len(addr.Zone()) > 0 {
+			return "", netip.Addr{}, fmt.Errorf("invalid IPv6 address %s", s)
+		}
+		return "[" + serializeIPv6(addr) + "]", addr, nil
+	}
+
+	if opaque {
+		if i := strings.IndexFunc(s, isForbiddenHostCodePoint); i >= 0 {
+			return "", netip.Addr{}, fmt.Errorf("invalid host %s: forbidden character %q", s, s[i])
+		}
+		var buf []rune
+		for _, r := range s {
+			buf = appendEncoded(buf, r, inC0ControlSet)
+		}
+		return string(buf), netip.Addr{}, nil
+	}
+
+	name, err := domainToASCII(strings.ToValidUTF8(string(percentDecode(s)), "�"))
+	if err != nil {
+		return "", netip.Addr{}, fmt.Errorf("invalid host %s: %w", s, err)
+	}
+	if endsInNumber(name) {
+		addr, _, err := ip.ParseLenient(name)
+		if err != nil || !addr.Is4() {
+			return "", netip.Addr{}, fmt.Errorf("invalid IPv4 address %s", s)
+		}
+		return addr.String(), addr, nil
+	}
+	return name, netip.Addr{}, nil
+}
+
+// domainToASCII converts the domain name to lower case ASCII (punycode), as specified by UTS #46 with the options of
+// the WHATWG URL Standard
+func domainToASCII(s string) (string, error) {
+	name := strings.ToLower(s)
+	if !isASCII(s) || strings.HasPrefix(name, "xn--") || strings.Contains(name, ".xn--") {
+		var err error
+		if name, err = idnaProfile.ToASCII(s); err != nil {
+			return "", err
+		}
+	}
+	if len(name) == 0 {
+		return "", errors.New("empty host")
+	}
+	if i := strings.IndexFunc(name, isForbiddenDomainCodePoint); i >= 0 {
+		return "", fmt.Errorf("forbidden character %q", name[i])
+	}
+	return name, nil
+}
+
+// endsInNumber returns whether the last label of the domain name, ignoring a trailing dot, is a number, in which case
+// the name is parsed as an IPv4 address
+func endsInNumber(name string) bool {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	last := labels[len(labels)-1]
+	if len(last) > 0 && strings.Trim(last, "0123456789") == "" {
+		return true
+	}
+	if len(last) >= 2 && (last[:2] == "0x" || last[:2] == "0X") {
+		return strings.Trim(last[2:], "0123456789abcdefABCDEF") == ""
+	}
+	return false
+}
+
+// serializeIPv6 returns the IPv6 address with the longest run of zero pieces compressed, but unlike
+// netip.Addr.String without an IPv4 address in dotted notation
+func serializeIPv6(addr netip.Addr) string {
+	b := addr.As16()
+	var pieces [8]uint16
+	for i := range pieces {
+		pieces[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+
+	compress, longest := -1, 1
+	for i := 0; i < len(pieces); {
+		if pieces[i] != 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(pieces) && pieces[j] == 0 {
+			j++
+		}
+		if j-i > longest {
+			compress, longest = i, j-i
+		}
+		i = j
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(pieces); i++ {
+		if i == compress {
+			if i == 0 {
+				sb.WriteByte(':')
+			}
+			sb.WriteByte(':')
+			i += longest - 1
+			continue
+		}
+		sb.WriteString(strconv.FormatUint(uint64(pieces[i]), 16))
+		if i < len(pieces)-1 {
+			sb.WriteByte(':')
+		}
+	}
+	return sb.String()
+}
+
+func isForbiddenHostCodePoint(r rune) bool {
+	switch r {
+	case 0, '\t', '\n', '\r', ' ', '#', '/', ':', '<', '>', '?', '@', '[', '\\', ']', '^', '|':
+		return true
+	}
+	return false
+}
+
+func isForbiddenDomainCodePoint(r rune) bool {
+	return isForbiddenHostCodePoint(r) || r <= 0x1f || r == '%' || r == 0x7f
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package url
+
+import (
+	"unicode/utf8"
+)
+
+const upperHex = "0123456789ABCDEF"
+
+// percent-encode sets of the WHATWG URL Standard, returning whether the code point is percent-encoded
+
+func inC0ControlSet(r rune) bool {
+	return r < 0x20 || r > 0x7e
+}
+
+func inFragmentSet(r rune) bool {
+	return inC0ControlSet(r) || r == ' ' || r == '"' || r == '<' || r == '>' || r == '`'
+}
+
+func inQuerySet(r rune) bool {
+	return inC0ControlSet(r) || r == ' ' || r == '"' || r == '#' || r == '<' || r == '>'
+}
+
+func inSpecialQuerySet(r rune) bool {
+	return inQuerySet(r) || r == '\''
+}
+
+func inPathSet(r rune) bool {
+	return inQuerySet(r) || r == '?' || r == '`' || r == '{' || r == '}'
+}
+
+func inUserinfoSet(r rune) bool {
+	return inPathSet(r) || r == '/' || r == ':' || r == ';' || r == '=' || r == '@' || r >= '[' && r <= '^' ||
+		r == '|'
+}
+
+// appendEncoded appends the code point to the buffer, percent-encoding its UTF-8 bytes if it is in the set
+func appendEncoded(buf []rune, r rune, set func(rune) bool) []rune {
+	if !set(r) {
+		return append(buf, r)
+	}
+	var b [utf8.UTFMax]byte
+	for _, c := range b[:utf8.EncodeRune(b[:], r)] {
+		buf = append(buf, '%', rune(upperHex[c>>4]), rune(upperHex[c&0x0f]))
+	}
+	return buf
+}
+
+// percentDecode decodes the percent-encoded bytes of the string, leaving invalid sequences as is
+func percentDecode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			b = append(b, unhex(s[i+1])<<4|unhex(s[i+2]))
+			i += 2
+			continue
+		}
+		b = append(b, s[i])
+	}
+	return b
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
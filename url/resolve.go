@@ -0,0 +1,210 @@
+package url
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ReferenceType is the type of a URI reference, as defined in RFC 3986 section 4
+type ReferenceType int
+
+// Types of URI references
+const (
+	// ReferenceAbsolute is a URI with a scheme, such as https://example.com/a
+	ReferenceAbsolute ReferenceType = iota
+	// ReferenceSchemeRelative is a relative reference with an authority (network-path reference), such as
+	// //example.com/a
+	ReferenceSchemeRelative
+	// ReferencePathAbsolute is a relative reference with a path starting with a slash, such as /a/b
+	ReferencePathAbsolute
+	// ReferencePathRelative is a relative reference with a path not starting with a slash, such as a/b, ../a, ?q or
+	// an empty reference
+	ReferencePathRelative
+)
+
+// String returns the name of the reference type
+func (t ReferenceType) String() string {
+	switch t {
+	case ReferenceAbsolute:
+		return "absolute"
+	case ReferenceSchemeRelative:
+		return "scheme-relative"
+	case ReferencePathAbsolute:
+		return "path-absolute"
+	default:
+		return "path-relative"
+	}
+}
+
+// uriRegex splits a URI reference into its components, as given in RFC 3986 appendix B
+var uriRegex = regexp.MustCompile(`^(([^:/?#]+):)?(//([^/?#]*))?([^?#]*)(\?([^#]*))?(#(.*))?$`)
+
+var schemeRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*$`)
+
+// reference holds the components of a URI reference, as specified in RFC 3986
+type reference struct {
+	scheme       string
+	hasScheme    bool
+	authority    string
+	hasAuthority bool
+	path         string
+	query        string
+	hasQuery     bool
+	fragment     string
+	hasFragment  bool
+}
+
+// splitReference splits the URI reference into its components
+//
+// Returns an error if the scheme is invalid.
+func splitReference(s string) (reference, error) {
+	m := uriRegex.FindStringSubmatch(s)
+	r := reference{
+		scheme:       m[2],
+		hasScheme:    len(m[1]) > 0,
+		authority:    m[4],
+		hasAuthority: len(m[3]) > 0,
+		path:         m[5],
+		query:        m[7],
+		hasQuery:     len(m[6]) > 0,
+		fragment:     m[9],
+		hasFragment:  len(m[8]) > 0,
+	}
+	if r.hasScheme && !schemeRegex.MatchString(r.scheme) {
+		return reference{}, fmt.Errorf("invalid scheme %s", r.scheme)
+	}
+	return r, nil
+}
+
+// String returns the URI reference recomposed from its components, as specified in RFC 3986 section 5.3
+func (r reference) String() string {
+	var sb strings.Builder
+	if r.hasScheme {
+		sb.WriteString(r.scheme + ":")
+	}
+	if r.hasAuthority {
+		sb.WriteString("//" + r.authority)
+	}
+	sb.WriteString(r.path)
+	if r.hasQuery {
+		sb.WriteString("?" + r.query)
+	}
+	if r.hasFragment {
+		sb.WriteString("#" + r.fragment)
+	}
+	return sb.String()
+}
+
+// ClassifyReference returns the type of the URI reference, such as a link found in a page
+//
+// A reference with an invalid scheme, such as 1a:b, is path-relative.
+func ClassifyReference(s string) ReferenceType {
+	r, err := splitReference(s)
+	switch {
+	case err == nil && r.hasScheme:
+		return ReferenceAbsolute
+	case r.hasAuthority:
+		return ReferenceSchemeRelative
+	case strings.HasPrefix(r.path, "/"):
+		return ReferencePathAbsolute
+	default:
+		return ReferencePathRelative
+	}
+}
+
+// Resolve resolves the URI reference against the absolute base URI, as specified in RFC 3986 section 5.2, such as
+// ../c?q against http://example.com/a/b/ to http://example.com/a/c?q
+//
+// The URIs are not parsed or encoded as by browsers, unlike URL.Parse, i.e. backslashes are not slashes.
+// Returns an error if the base URI is not absolute, or a scheme is invalid.
+func Resolve(base, ref string) (string, error) {
+	b, err := splitReference(base)
+	if err != nil || !b.hasScheme {
+		return "", fmt.Errorf("invalid base URI %s", base)
+	}
+	r, err := splitReference(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid URI reference %s: %w", ref, err)
+	}
+
+	t := reference{fragment: r.fragment, hasFragment: r.hasFragment}
+	switch {
+	case r.hasScheme:
+		t.scheme, t.hasScheme = r.scheme, true
+		t.authority, t.hasAuthority = r.authority, r.hasAuthority
+		t.path = removeDotSegments(r.path)
+		t.query, t.hasQuery = r.query, r.hasQuery
+		return t.String(), nil
+	case r.hasAuthority:
+		t.authority, t.hasAuthority = r.authority, true
+		t.path = removeDotSegments(r.path)
+		t.query, t.hasQuery = r.query, r.hasQuery
+	default:
+		t.authority, t.hasAuthority = b.authority, b.hasAuthority
+		switch {
+		case len(r.path) == 0:
+			t.path = b.path
+			t.query, t.hasQuery = b.query, b.hasQuery
+			if r.hasQuery {
+				t.query, t.hasQuery = r.query, true
+			}
+		case strings.HasPrefix(r.path, "/"):
+			t.path = removeDotSegments(r.path)
+			t.query, t.hasQuery = r.query, r.hasQuery
+		default:
+			t.path = removeDotSegments(mergePaths(b, r.path))
+			t.query, t.hasQuery = r.query, r.hasQuery
+		}
+	}
+	t.scheme, t.hasScheme = b.scheme, true
+	return t.String(), nil
+}
+
+// mergePaths merges the relative path with the path of the base, as specified in RFC 3986 section 5.2.3
+func mergePaths(base reference, path string) string {
+	if base.hasAuthority && len(base.path) == 0 {
+		return "/" + path
+	}
+	return base.path[:strings.LastIndex(base.path, "/")+1] + path
+}
+
+// removeDotSegments removes the "." and ".." segments of the path, as specified in RFC 3986 section 5.2.4
+func removeDotSegments(path string) string {
+	var output []string
+	for len(path) > 0 {
+		switch {
+		case strings.HasPrefix(path, "../"):
+			path = path[3:]
+		case strings.HasPrefix(path, "./"):
+			path = path[2:]
+		case strings.HasPrefix(path, "/./"):
+			path = path[2:]
+		case path == "/.":
+			path = "/"
+		case strings.HasPrefix(path, "/../"):
+			path = path[3:]
+			output = removeLastSegment(output)
+		case path == "/..":
+			path = "/"
+			output = removeLastSegment(output)
+		case path == "." || path == "..":
+			path = ""
+		default:
+			i := strings.Index(path[1:], "/") + 1
+			if i == 0 {
+				i = len(path)
+			}
+			output = append(output, path[:i])
+			path = path[i:]
+		}
+	}
+	return strings.Join(output, "")
+}
+
+func removeLastSegment(segments []string) []string {
+	if len(segments) == 0 {
+		return segments
+	}
+	return segments[:len(segments)-1]
+}
@@ -0,0 +1,91 @@
+package url_test
+
+import (
+	"testing"
+
+	"github.com/detectify/n5/url"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve_WithRFC3986Examples_ShouldResolveReferences(t *testing.T) {
+	// RFC 3986 section 5.4
+	for ref, expected := range map[string]string{
+		"g:h":           "g:h",
+		"g":             "http://a/b/c/g",
+		"./g":           "http://a/b/c/g",
+		"g/":            "http://a/b/c/g/",
+		"/g":            "http://a/g",
+		"//g":           "http://g",
+		"?y":            "http://a/b/c/d;p?y",
+		"g?y":           "http://a/b/c/g?y",
+		"#s":            "http://a/b/c/d;p?q#s",
+		"g#s":           "http://a/b/c/g#s",
+		"g?y#s":         "http://a/b/c/g?y#s",
+		";x":            "http://a/b/c/;x",
+		"g;x":           "http://a/b/c/g;x",
+		"g;x?y#s":       "http://a/b/c/g;x?y#s",
+		"":              "http://a/b/c/d;p?q",
+		".":             "http://a/b/c/",
+		"./":            "http://a/b/c/",
+		"..":            "http://a/b/",
+		"../":           "http://a/b/",
+		"../g":          "http://a/b/g",
+		"../..":         "http://a/",
+		"../../":        "http://a/",
+		"../../g":       "http://a/g",
+		"../../../g":    "http://a/g",
+		"../../../../g": "http://a/g",
+		"/./g":          "http://a/g",
+		"/../g":         "http://a/g",
+		"g.":            "http://a/b/c/g.",
+		".g":            "http://a/b/c/.g",
+		"g..":           "http://a/b/c/g..",
+		"..g":           "http://a/b/c/..g",
+		"./../g":        "http://a/b/g",
+		"./g/.":         "http://a/b/c/g/",
+		"g/./h":         "http://a/b/c/g/h",
+		"g/../h":        "http://a/b/c/h",
+		"g;x=1/./y":     "http://a/b/c/g;x=1/y",
+		"g;x=1/../y":    "http://a/b/c/y",
+		"g?y/./x":       "http://a/b/c/g?y/./x",
+		"g?y/../x":      "http://a/b/c/g?y/../x",
+		"g#s/./x":       "http://a/b/c/g#s/./x",
+		"g#s/../x":      "http://a/b/c/g#s/../x",
+		"http:g":        "http:g",
+	} {
+		resolved, err := url.Resolve("http://a/b/c/d;p?q", ref)
+		require.NoError(t, err, ref)
+		require.Equal(t, expected, resolved, ref)
+	}
+}
+
+func TestResolve_WithBaseWithoutPath_ShouldMergeWithSlash(t *testing.T) {
+	resolved, err := url.Resolve("https://example.com", "a/b")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/a/b", resolved)
+}
+
+func TestResolve_WithRelativeBase_ShouldReturnError(t *testing.T) {
+	for _, base := range []string{"/a/b", "//example.com/a", "1a:b"} {
+		_, err := url.Resolve(base, "c")
+		require.Error(t, err, base)
+	}
+}
+
+func TestClassifyReference_WithReferences_ShouldReturnType(t *testing.T) {
+	for s, expected := range map[string]url.ReferenceType{
+		"https://example.com/a":   url.ReferenceAbsolute,
+		"mailto:user@example.com": url.ReferenceAbsolute,
+		"//example.com/a":         url.ReferenceSchemeRelative,
+		"/a/b":                    url.ReferencePathAbsolute,
+		"a/b":                     url.ReferencePathRelative,
+		"../a":                    url.ReferencePathRelative,
+		"?q":                      url.ReferencePathRelative,
+		"#top":                    url.ReferencePathRelative,
+		"":                        url.ReferencePathRelative,
+		"1a:b":                    url.ReferencePathRelative,
+	} {
+		require.Equal(t, expected, url.ClassifyReference(s), s)
+	}
+	require.Equal(t, "scheme-relative", url.ReferenceSchemeRelative.String())
+}
@@ -0,0 +1,304 @@
+package url
+
+import (
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/detectify/n5/domain"
+)
+
+// MatchType is the type of a match found by Scan
+type MatchType int
+
+// Types of matches found by Scan
+const (
+	// MatchURL is an absolute URL with a host, such as https://example.com/a
+	MatchURL MatchType = iota
+	// MatchEmail is an email address, such as user@example.com
+	MatchEmail
+	// MatchIP is an IPv4 or IPv6 address, such as 192.0.2.1 or 2001:db8::1
+	MatchIP
+	// MatchHostname is a domain name under a public suffix, such as www.example.com
+	MatchHostname
+)
+
+// String returns the name of the match type
+func (t MatchType) String() string {
+	switch t {
+	case MatchURL:
+		return "URL"
+	case MatchEmail:
+		return "email"
+	case MatchIP:
+		return "IP"
+	default:
+		return "hostname"
+	}
+}
+
+// Match is a URL, email address, IP address or hostname found by Scan
+type Match struct {
+	Type MatchType
+	// Start and End are the byte offsets of the match in the scanned string, including escape sequences
+	Start, End int
+	// Text is the match with escape sequences decoded, such as https://example.com/a for https:\/\/example.com\/a
+	Text string
+	// URL is the parsed URL of a URL match
+	URL *URL
+	// Name is the domain name of a hostname or email address, or of the host of a URL if a valid domain name
+	Name domain.Name
+	// Addr is the address of an IP address, or of the host of a URL if an IP address
+	Addr netip.Addr
+}
+
+var (
+	scanURLRegex   = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>\x60\\{}|^]+`)
+	scanEmailRegex = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@((?:[\p{L}\p{N}_](?:[\p{L}\p{N}_-]{0,61}[\p{L}\p{N}_])?\.)+` +
+		`[\p{L}\p{N}](?:[\p{L}\p{N}-]{0,61}[\p{L}\p{N}])?)`)
+	scanIPv4Regex     = regexp.MustCompile(`[0-9]{1,3}(?:\.[0-9]{1,3}){3}`)
+	scanIPv6Regex     = regexp.MustCompile(`(?:[0-9a-fA-F]{1,4})?(?::(?:[0-9a-fA-F]{1,4})?){2,7}(?:\.[0-9]{1,3}){0,3}`)
+	scanHostnameRegex = regexp.MustCompile(`(?:[\p{L}\p{N}_](?:[\p{L}\p{N}_-]{0,61}[\p{L}\p{N}_])?\.)+` +
+		`[\p{L}\p{N}](?:[\p{L}\p{N}-]{0,61}[\p{L}\p{N}])?`)
+)
+
+// Scan finds the absolute URLs, email addresses, IP addresses and hostnames in the string, which may be free text,
+// HTML, JavaScript, JSON or certificate fields, in order of offset
+//
+// JavaScript and JSON escape sequences, such as \/ and \u002F, and HTML character references, such as &amp; and
+// &#x2F;, are decoded before scanning. Hostnames and the domains of email addresses must be under a public suffix,
+// hence file names such as file.js are ignored, while the hosts of URLs are not filtered, such as localhost. Hostnames
+// of two labels ending with a common file extension which is also a top-level domain, such as README.md or libfoo.so,
+// are ignored unless preceded by *. as in *.app.zip. IPv6 addresses without digits must have at least three groups,
+// and groups mixing upper and lower case are identifiers, such as Face::Add. Matches do not overlap, e.g. the host of a
+// URL or the domain of an email address is not a separate hostname match.
+func Scan(s string) []Match {
+	sc := scanner{}
+	sc.text, sc.offsets = unescape(s)
+
+	for _, loc := range scanURLRegex.FindAllStringIndex(sc.text, -1) {
+		sc.addURL(loc[0], trimURL(sc.text[loc[0]:loc[1]]))
+	}
+	for _, loc := range scanEmailRegex.FindAllStringSubmatchIndex(sc.text, -1) {
+		if name, err := domain.Parse(sc.text[loc[2]:loc[3]]); err == nil && name.HasPublicSuffix() &&
+			sc.isBoundary(loc[0], loc[1]) {
+			sc.add(Match{Type: MatchEmail, Name: name}, loc[0], loc[1])
+		}
+	}
+	for _, re := range []*regexp.Regexp{scanIPv4Regex, scanIPv6Regex} {
+		for _, loc := range re.FindAllStringIndex(sc.text, -1) {
+			if addr, err := netip.ParseAddr(sc.text[loc[0]:loc[1]]); err == nil && !addr.IsUnspecified() &&
+				(addr.Is4() || isIPv6Text(sc.text[loc[0]:loc[1]])) && sc.isBoundary(loc[0], loc[1]) {
+				sc.add(Match{Type: MatchIP, Addr: addr}, loc[0], loc[1])
+			}
+		}
+	}
+	for _, loc := range scanHostnameRegex.FindAllStringIndex(sc.text, -1) {
+		if sc.isFileName(loc[0], loc[1]) {
+			continue
+		}
+		if name, err := domain.Parse(sc.text[loc[0]:loc[1]]); err == nil && name.HasPublicSuffix() &&
+			sc.isBoundary(loc[0], loc[1]) {
+			sc.add(Match{Type: MatchHostname, Name: name}, loc[0], loc[1])
+		}
+	}
+
+	sort.Slice(sc.matches, func(i, j int) bool {
+		return sc.matches[i].Start < sc.matches[j].Start
+	})
+	return sc.matches
+}
+
+// scanner holds the decoded string being scanned, the offsets in the scanned string of each byte of the decoded
+// string, and the matches found
+type scanner struct {
+	text    string
+	offsets []int
+	matches []Match
+	covered [][2]int
+}
+
+func (sc *scanner) addURL(start int, s string) {
+	u, err := Parse(s)
+	if err != nil || !u.HasHost() || len(u.Hostname()) == 0 {
+		return
+	}
+	m := Match{Type: MatchURL, URL: u}
+	if h, ok := u.Host(); ok {
+		m.Name, m.Addr = h.Name(), h.Addr()
+	}
+	sc.add(m, start, start+len(s))
+}
+
+// add adds the match of the decoded string from start to end, unless it overlaps a previous match
+func (sc *scanner) add(m Match, start, end int) {
+	for _, c := range sc.covered {
+		if start < c[1] && end > c[0] {
+			return
+		}
+	}
+	sc.covered = append(sc.covered, [2]int{start, end})
+	m.Start, m.End, m.Text = sc.offsets[start], sc.offsets[end], sc.text[start:end]
+	sc.matches = append(sc.matches, m)
+}
+
+// isBoundary returns whether the match of the decoded string from start to end is not part of a longer word, such as
+// a version number or an identifier, allowing a wildcard prefix such as *.example.com
+func (sc *scanner) isBoundary(start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(sc.text[:start])
+	after, size := utf8.DecodeRuneInString(sc.text[end:])
+	if before == '.' && strings.HasSuffix(sc.text[:start], "*.") {
+		before = '*'
+	}
+	if isWordRune(before) || before == '.' || before == '@' || isWordRune(after) {
+		return false
+	}
+	// a trailing period may end a sentence, but not precede another label or number
+	next, _ := utf8.DecodeRuneInString(sc.text[end+size:])
+	return after != '.' || !isWordRune(next)
+}
+
+// fileExtensions are the common file extensions which are also top-level domains, such as md for Moldova, of source
+// code (such as pl, py and rs), build files (am, in and mk), libraries (so), documents, archives and media
+var fileExtensions = map[string]bool{
+	"ai": true, "am": true, "as": true, "cab": true, "cc": true, "gs": true, "in": true, "md": true, "mk": true,
+	"ml": true, "mm": true, "mo": true, "mov": true, "ms": true, "pl": true, "pm": true, "ps": true, "py": true,
+	"rs": true, "sc": true, "sh": true, "so": true, "st": true, "tf": true, "tk": true, "zip": true,
+}
+
+// isFileName returns whether the match of the decoded string from start to end is likely a file name rather than a
+// hostname, i.e. two labels ending with a file extension, such as main.py, unless it is a wildcard such as *.main.py
+func (sc *scanner) isFileName(start, end int) bool {
+	_, extension, _ := strings.Cut(sc.text[start:end], ".")
+	return !strings.Contains(extension, ".") && fileExtensions[strings.ToLower(extension)] &&
+		!strings.HasSuffix(sc.text[:start], "*.")
+}
+
+// isIPv6Text returns whether the IPv6 address is likely an address rather than an identifier, such as Face::Add in C++,
+// Ruby or Rust, i.e. it has a digit or at least three groups, and no group mixes upper and lower case letters
+func isIPv6Text(s string) bool {
+	groups, digit := 0, false
+	for _, g := range strings.Split(s, ":") {
+		if len(g) == 0 {
+			continue
+		}
+		if strings.ToLower(g) != g && strings.ToUpper(g) != g {
+			return false
+		}
+		groups++
+		digit = digit || strings.ContainsAny(g, "0123456789")
+	}
+	return digit || groups >= 3
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// trimURL removes trailing punctuation which is unlikely to be part of the URL, such as the period ending a sentence
+// or a closing parenthesis without an opening one
+func trimURL(s string) string {
+	for len(s) > 0 {
+		switch c := s[len(s)-1]; c {
+		case '.', ',', ';', ':', '!', '?', '*':
+		case ')':
+			if strings.Count(s, "(") >= strings.Count(s, ")") {
+				return s
+			}
+		case ']':
+			if strings.Count(s, "[") >= strings.Count(s, "]") {
+				return s
+			}
+		default:
+			return s
+		}
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// htmlEntities are the named HTML character references decoded by unescape
+var htmlEntities = map[string]string{
+	"amp":    "&",
+	"quot":   `"`,
+	"apos":   "'",
+	"lt":     "<",
+	"gt":     ">",
+	"sol":    "/",
+	"colon":  ":",
+	"period": ".",
+	"commat": "@",
+	"nbsp":   " ",
+}
+
+// jsEscapes are the single character JavaScript escape sequences decoded by unescape
+var jsEscapes = map[byte]string{
+	'/': "/", '\\': `\`, '"': `"`, '\'': "'", 'n': "\n", 'r': "\r", 't': "\t", 'b': "\b", 'f': "\f", 'v': "\v",
+}
+
+// unescape decodes the JavaScript escape sequences and HTML character references of the string, and returns the
+// offset in the string of each byte of the decoded string, followed by the length of the string
+func unescape(s string) (string, []int) {
+	var sb strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	for i := 0; i < len(s); {
+		decoded, n := decodeEscape(s[i:])
+		if n == 0 {
+			decoded, n = s[i:i+1], 1
+		}
+		sb.WriteString(decoded)
+		for j := 0; j < len(decoded); j++ {
+			offsets = append(offsets, i)
+		}
+		i += n
+	}
+	return sb.String(), append(offsets, len(s))
+}
+
+// decodeEscape decodes the escape sequence or character reference at the start of the string, returning the decoded
+// string and the length of the sequence, or 0 if none
+func decodeEscape(s string) (string, int) {
+	switch {
+	case len(s) >= 2 && s[0] == '\\':
+		if decoded, ok := jsEscapes[s[1]]; ok {
+			return decoded, 2
+		}
+		if len(s) >= 4 && s[1] == 'x' {
+			if c, err := strconv.ParseUint(s[2:4], 16, 8); err == nil {
+				return string(rune(c)), 4
+			}
+		}
+		if len(s) >= 6 && s[1] == 'u' {
+			if r, err := strconv.ParseUint(s[2:6], 16, 16); err == nil && !(r >= 0xd800 && r < 0xe000) {
+				return string(rune(r)), 6
+			}
+		}
+	case len(s) >= 3 && s[0] == '&':
+		end := strings.IndexByte(s, ';')
+		if end < 0 || end > 10 {
+			return "", 0
+		}
+		ref := s[1:end]
+		if decoded, ok := htmlEntities[ref]; ok {
+			return decoded, end + 1
+		}
+		var r uint64
+		var err error
+		switch {
+		case strings.HasPrefix(ref, "#x") || strings.HasPrefix(ref, "#X"):
+			r, err = strconv.ParseUint(ref[2:], 16, 21)
+		case strings.HasPrefix(ref, "#"):
+			r, err = strconv.ParseUint(ref[1:], 10, 21)
+		default:
+			return "", 0
+		}
+		if err == nil && utf8.ValidRune(rune(r)) {
+			return string(rune(r)), end + 1
+		}
+	}
+	return "", 0
+}
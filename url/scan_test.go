@@ -0,0 +1,114 @@
+package url_test
+
+import (
+	"testing"
+
+	"github.com/detectify/n5/url"
+	"github.com/stretchr/testify/require"
+)
+
+type scanned struct {
+	typ  url.MatchType
+	text string
+	host string
+}
+
+func scan(s string) []scanned {
+	var result []scanned
+	for _, m := range url.Scan(s) {
+		host := m.Name.String()
+		if m.Addr.IsValid() {
+			host = m.Addr.String()
+		}
+		result = append(result, scanned{m.Type, m.Text, host})
+	}
+	return result
+}
+
+func TestScan_WithFreeText_ShouldFindAllTypes(t *testing.T) {
+	s := "Visit https://www.example.com/a?b=1 (or www.example.org). Mail admin@example.co.uk, " +
+		"ping 192.0.2.1 or 2001:db8::1, but not file.js, v1.2.3.4.5, std::vector or 12:30:45."
+	require.Equal(t, []scanned{
+		{url.MatchURL, "https://www.example.com/a?b=1", "www.example.com"},
+		{url.MatchHostname, "www.example.org", "www.example.org"},
+		{url.MatchEmail, "admin@example.co.uk", "example.co.uk"},
+		{url.MatchIP, "192.0.2.1", "192.0.2.1"},
+		{url.MatchIP, "2001:db8::1", "2001:db8::1"},
+	}, scan(s))
+}
+
+func TestScan_WithEscapedJavaScriptAndJSON_ShouldDecodeEscapes(t *testing.T) {
+	s := `{"url":"https:\/\/api.example.com\/v1","cdn":"//cdn.example.net/app.js"}`
+	matches := url.Scan(s)
+	require.Len(t, matches, 2)
+
+	require.Equal(t, url.MatchURL, matches[0].Type)
+	require.Equal(t, "https://api.example.com/v1", matches[0].Text)
+	require.Equal(t, `https:\/\/api.example.com\/v1`, s[matches[0].Start:matches[0].End])
+	require.Equal(t, "https://api.example.com/v1", matches[0].URL.String())
+
+	require.Equal(t, url.MatchHostname, matches[1].Type)
+	require.Equal(t, "cdn.example.net", matches[1].Name.String())
+	require.Equal(t, "cdn.example.net", s[matches[1].Start:matches[1].End])
+}
+
+func TestScan_WithHTML_ShouldDecodeCharacterReferences(t *testing.T) {
+	s := `<a href="https://example.com/?a=1&amp;b=2">x</a><script src="/static/main.js"></script>` +
+		`<img src="https&#x3A;&#x2F;&#x2F;img.example.com/a.png">`
+	require.Equal(t, []scanned{
+		{url.MatchURL, "https://example.com/?a=1&b=2", "example.com"},
+		{url.MatchURL, "https://img.example.com/a.png", "img.example.com"},
+	}, scan(s))
+}
+
+func TestScan_WithCertificateFields_ShouldFindNamesAndAddresses(t *testing.T) {
+	s := "CN=www.example.com, O=Example; DNS:*.example.com, DNS:a.user.github.io, IP Address:10.0.0.1"
+	require.Equal(t, []scanned{
+		{url.MatchHostname, "www.example.com", "www.example.com"},
+		{url.MatchHostname, "example.com", "example.com"},
+		{url.MatchHostname, "a.user.github.io", "a.user.github.io"},
+		{url.MatchIP, "10.0.0.1", "10.0.0.1"},
+	}, scan(s))
+}
+
+func TestScan_WithURLsInProse_ShouldTrimPunctuation(t *testing.T) {
+	s := "See (https://en.wikipedia.org/wiki/Foo_(bar)), http://localhost:8080/a. and http://[::1]/!"
+	require.Equal(t, []scanned{
+		{url.MatchURL, "https://en.wikipedia.org/wiki/Foo_(bar)", "en.wikipedia.org"},
+		{url.MatchURL, "http://localhost:8080/a", "localhost"},
+		{url.MatchURL, "http://[::1]/", "::1"},
+	}, scan(s))
+}
+
+func TestScan_WithUnicodeHostname_ShouldReturnPunycode(t *testing.T) {
+	require.Equal(t, []scanned{
+		{url.MatchHostname, "пример.мкд", "xn--e1afmkfd.xn--d1alf"},
+	}, scan("Сајт: пример.мкд"))
+}
+
+func TestScan_WithFileNames_ShouldIgnoreThem(t *testing.T) {
+	require.Nil(t, url.Scan("README.md, main.py, run.sh, app.zip"))
+
+	require.Equal(t, []scanned{
+		{url.MatchHostname, "docs.example.md", "docs.example.md"},
+		{url.MatchHostname, "app.zip", "app.zip"},
+		{url.MatchURL, "https://example.sh/", "example.sh"},
+		{url.MatchEmail, "admin@example.py", "example.py"},
+	}, scan("See docs.example.md, *.app.zip, https://example.sh/ and admin@example.py"))
+}
+
+func TestScan_WithSourceCode_ShouldIgnoreIdentifiers(t *testing.T) {
+	require.Nil(t, url.Scan("see libfoo.so, run.pl, Makefile.in and model.ai, "+
+		"or call Face::Add, Beef::Cafe and dead::beef"))
+
+	require.Equal(t, []scanned{
+		{url.MatchIP, "fe80::1", "fe80::1"},
+		{url.MatchIP, "dead:beef:cafe::", "dead:beef:cafe::"},
+		{url.MatchIP, "2001:DB8::ABCD", "2001:db8::abcd"},
+		{url.MatchHostname, "www.example.so", "www.example.so"},
+	}, scan("Listen on fe80::1, dead:beef:cafe:: or 2001:DB8::ABCD at www.example.so"))
+}
+
+func TestScan_WithoutMatches_ShouldReturnNil(t *testing.T) {
+	require.Nil(t, url.Scan("import x from './file.js'; a.b = c.d; version 1.2.3"))
+}
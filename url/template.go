@@ -0,0 +1,191 @@
+package url
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variable is the placeholder of a variable path segment or query value of a URL template
+type Variable string
+
+// Variables of URL templates
+const (
+	// VariableNumber is a numeric ID, such as 123
+	VariableNumber Variable = "{int}"
+	// VariableUUID is a UUID, such as 123e4567-e89b-12d3-a456-426614174000
+	VariableUUID Variable = "{uuid}"
+	// VariableHash is a hexadecimal hash or ID of at least 16 digits, such as an MD5 or SHA-1 hash, or a MongoDB ID
+	VariableHash Variable = "{hash}"
+	// VariableDate is a date, such as 2023-01-31 or 20230131
+	VariableDate Variable = "{date}"
+	// VariableLocale is a locale code of a common language, optionally with a region, such as en, en-US or pt_br
+	VariableLocale Variable = "{locale}"
+	// VariableValue is a query value
+	VariableValue Variable = "{value}"
+)
+
+var (
+	uuidRegex   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hashRegex   = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	numberRegex = regexp.MustCompile(`^[0-9]+$`)
+	localeRegex = regexp.MustCompile(`^([a-zA-Z]{2})(?:[-_][a-zA-Z]{2})?$`)
+)
+
+// languages are the ISO 639-1 codes of common languages of locale codes in URLs
+var languages = map[string]bool{
+	"ar": true, "bg": true, "ca": true, "cs": true, "da": true, "de": true, "el": true, "en": true, "es": true,
+	"et": true, "fa": true, "fi": true, "fr": true, "he": true, "hi": true, "hr": true, "hu": true, "id": true,
+	"it": true, "ja": true, "ko": true, "lt": true, "lv": true, "ms": true, "nb": true, "nl": true, "nn": true,
+	"no": true, "pl": true, "pt": true, "ro": true, "ru": true, "sk": true, "sl": true, "sr": true, "sv": true,
+	"th": true, "tr": true, "uk": true, "vi": true, "zh": true,
+}
+
+// ClassifySegment returns the variable of the path segment, and whether it is variable
+//
+// A language code without region, such as en, is not a locale, as it may be a word such as id or it, while NewTemplate
+// classifies it as a locale when it is the first segment of a path, such as /en/about.
+func ClassifySegment(s string) (Variable, bool) {
+	return classifySegment(s, false)
+}
+
+// classifySegment returns the variable of the path segment, where a language code without region is a locale if it is
+// the first segment
+func classifySegment(s string, first bool) (Variable, bool) {
+	switch {
+	case uuidRegex.MatchString(s):
+		return VariableUUID, true
+	case isDate(s):
+		return VariableDate, true
+	case numberRegex.MatchString(s):
+		return VariableNumber, true
+	case hashRegex.MatchString(s) && strings.ContainsAny(s, "0123456789"):
+		return VariableHash, true
+	}
+	if m := localeRegex.FindStringSubmatch(s); m != nil && languages[strings.ToLower(m[1])] && (first || len(s) > 2) {
+		return VariableLocale, true
+	}
+	return "", false
+}
+
+func isDate(s string) bool {
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Template is the template of URLs which differ only by variable path segments and query values, such as
+// https://example.com/users/{int}?page={value} for https://example.com/users/123?page=2
+type Template struct {
+	// Scheme is the scheme of the URLs
+	Scheme string
+	// Host is the host of the URLs, with the port if not the default port
+	Host string
+	// Path are the segments of the path, with variable segments replaced by their variable
+	Path []string
+	// Opaque is the opaque path of URLs such as mailto:user@example.com, which is not templated
+	Opaque string
+	// Query are the query parameters as name={value}, sorted by name
+	Query []string
+}
+
+// NewTemplate returns the template of the absolute URL, which is parsed with Parse
+//
+// Path segments are replaced by their variable, if any, as by ClassifySegment, except that a language code without
+// region is a locale as the first segment, and all query values by VariableValue, hence URLs with the same query
+// parameters share a template. The fragment is ignored. Returns an error if the URL is invalid.
+func NewTemplate(s string) (Template, error) {
+	u, err := Parse(s)
+	if err != nil {
+		return Template{}, err
+	}
+
+	t := Template{Scheme: u.Scheme(), Host: u.Hostname()}
+	if port, ok := u.Port(); ok {
+		t.Host += ":" + strconv.Itoa(int(port))
+	}
+	if u.HasOpaquePath() {
+		t.Opaque = u.Path()
+	}
+	for i, segment := range u.PathSegments() {
+		if v, ok := classifySegment(segment, i == 0); ok {
+			segment = string(v)
+		}
+		t.Path = append(t.Path, segment)
+	}
+
+	query, _ := u.Query()
+	for _, param := range strings.Split(query, "&") {
+		if len(param) == 0 {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		t.Query = append(t.Query, name+"="+string(VariableValue))
+	}
+	sort.Strings(t.Query)
+	return t, nil
+}
+
+// String returns the template as a URL with the variables, such as
+// https://example.com/users/{int}?page={value}
+func (t Template) String() string {
+	var sb strings.Builder
+	sb.WriteString(t.Scheme + ":")
+	if len(t.Host) > 0 || t.Scheme == "file" {
+		sb.WriteString("//" + t.Host)
+	}
+	for _, segment := range t.Path {
+		sb.WriteString("/" + segment)
+	}
+	sb.WriteString(t.Opaque)
+	if len(t.Query) > 0 {
+		sb.WriteString("?" + strings.Join(t.Query, "&"))
+	}
+	return sb.String()
+}
+
+// Fingerprint returns a hash of the template in hexadecimal, which is the same for all URLs of the template, and is
+// suitable as deduplication key across scans
+//
+// The fingerprints of URLs change when the classification of path segments changes between versions of this package.
+func (t Template) Fingerprint() string {
+	sum := sha256.Sum256([]byte(t.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// TemplateGroup is a group of URLs of the same template
+type TemplateGroup struct {
+	Template Template
+	// URLs are the URLs of the template, in order
+	URLs []string
+}
+
+// GroupByTemplate groups the absolute URLs by template, in the order of the first URL of each template
+//
+// Returns an error if any URL is invalid.
+func GroupByTemplate(urls []string) ([]TemplateGroup, error) {
+	var groups []TemplateGroup
+	indexes := map[string]int{}
+	for _, s := range urls {
+		t, err := NewTemplate(s)
+		if err != nil {
+			return nil, err
+		}
+		key := t.String()
+		i, ok := indexes[key]
+		if !ok {
+			i = len(groups)
+			indexes[key] = i
+			groups = append(groups, TemplateGroup{Template: t})
+		}
+		groups[i].URLs = append(groups[i].URLs, s)
+	}
+	return groups, nil
+}
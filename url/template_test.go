@@ -0,0 +1,86 @@
+package url_test
+
+import (
+	"testing"
+
+	"github.com/detectify/n5/url"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifySegment_WithSegment_ShouldReturnVariable(t *testing.T) {
+	for s, expected := range map[string]url.Variable{
+		"123":                                      url.VariableNumber,
+		"123e4567-e89b-12d3-a456-426614174000":     url.VariableUUID,
+		"D41D8CD98F00B204E9800998ECF8427E":         url.VariableHash,
+		"507f1f77bcf86cd799439011":                 url.VariableHash,
+		"da39a3ee5e6b4b0d3255bfef95601890afd80709": url.VariableHash,
+		"2023-01-31":                               url.VariableDate,
+		"20230131":                                 url.VariableDate,
+		"en-US":                                    url.VariableLocale,
+		"pt_br":                                    url.VariableLocale,
+	} {
+		v, ok := url.ClassifySegment(s)
+		require.True(t, ok, s)
+		require.Equal(t, expected, v, s)
+	}
+	for _, s := range []string{"users", "", "api", "v2", "deadbeefdeadbeef", "2023-13-01", "xx-YY", "index.html", "en", "id"} {
+		_, ok := url.ClassifySegment(s)
+		require.False(t, ok, s)
+	}
+}
+
+func TestNewTemplate_WithURL_ShouldReplaceVariables(t *testing.T) {
+	tmpl, err := url.NewTemplate("https://Example.com:8443/en-us/users/123/posts/2023-01-31?sort=asc&id=5#top")
+	require.NoError(t, err)
+	require.Equal(t, url.Template{
+		Scheme: "https",
+		Host:   "example.com:8443",
+		Path:   []string{"{locale}", "users", "{int}", "posts", "{date}"},
+		Query:  []string{"id={value}", "sort={value}"},
+	}, tmpl)
+	require.Equal(t, "https://example.com:8443/{locale}/users/{int}/posts/{date}?id={value}&sort={value}", tmpl.String())
+
+	tmpl, err = url.NewTemplate("https://example.com/en/users/id/123/it")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/{locale}/users/id/{int}/it", tmpl.String())
+
+	tmpl, err = url.NewTemplate("mailto:user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "mailto:user@example.com", tmpl.String())
+
+	_, err = url.NewTemplate("/users/123")
+	require.Error(t, err)
+}
+
+func TestTemplate_Fingerprint_ShouldBeStable(t *testing.T) {
+	a, err := url.NewTemplate("https://example.com/users/123?b=1&a=2")
+	require.NoError(t, err)
+	b, err := url.NewTemplate("https://example.com/users/456?a=x&b=9#fragment")
+	require.NoError(t, err)
+	c, err := url.NewTemplate("https://example.com/groups/123")
+	require.NoError(t, err)
+
+	require.Len(t, a.Fingerprint(), 32)
+	require.Equal(t, a.Fingerprint(), b.Fingerprint())
+	require.NotEqual(t, a.Fingerprint(), c.Fingerprint())
+	require.Equal(t, "df9290873b1025e7c7e9831218af44ff", c.Fingerprint())
+}
+
+func TestGroupByTemplate_WithURLs_ShouldGroupInOrder(t *testing.T) {
+	groups, err := url.GroupByTemplate([]string{
+		"https://example.com/users/123",
+		"https://example.com/users/456",
+		"https://example.com/about",
+		"https://example.com/users/789",
+	})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	require.Equal(t, "https://example.com/users/{int}", groups[0].Template.String())
+	require.Equal(t, []string{
+		"https://example.com/users/123", "https://example.com/users/456", "https://example.com/users/789",
+	}, groups[0].URLs)
+	require.Equal(t, []string{"https://example.com/about"}, groups[1].URLs)
+
+	_, err = url.GroupByTemplate([]string{"https://example.com/", "not a URL"})
+	require.Error(t, err)
+}
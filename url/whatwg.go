@@ -0,0 +1,194 @@
+package url
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/detectify/n5/domain"
+)
+
+// specialSchemes are the special schemes of the WHATWG URL Standard, with their default ports (0 if none)
+var specialSchemes = map[string]uint16{
+	"ftp":   21,
+	"file":  0,
+	"http":  80,
+	"https": 443,
+	"ws":    80,
+	"wss":   443,
+}
+
+// URL holds a URL parsed as specified by the WHATWG URL Standard (https://url.spec.whatwg.org), i.e. the way browsers
+// parse URLs
+//
+// Unlike net/url, backslashes, tabs and newlines, IPv4 shorthands and percent-encoded hosts are handled as browsers
+// do, and the components are percent-encoded and normalized, e.g. default ports are removed. A URL is immutable, and
+// created with Parse or URL.Parse.
+type URL struct {
+	scheme        string
+	username      string
+	password      string
+	host          string
+	hasHost       bool
+	addr          netip.Addr
+	port          uint16
+	hasPort       bool
+	path          []string
+	opaquePath    string
+	hasOpaquePath bool
+	query         string
+	hasQuery      bool
+	fragment      string
+	hasFragment   bool
+}
+
+// Parse parses the absolute URL as specified by the WHATWG URL Standard
+//
+// Leading and trailing spaces and control characters, as well as tabs and newlines, are removed.
+// Returns an error if the string is not a valid absolute URL.
+func Parse(s string) (*URL, error) {
+	return parse(s, nil)
+}
+
+// Parse parses the URL, which may be relative, with the URL as base, as specified by the WHATWG URL Standard
+//
+// Returns an error if the string is not a valid URL.
+func (u *URL) Parse(ref string) (*URL, error) {
+	return parse(ref, u)
+}
+
+// Scheme returns the scheme of the URL in lower case, such as "https"
+func (u *URL) Scheme() string {
+	return u.scheme
+}
+
+// IsSpecial returns whether the scheme of the URL is special, i.e. ftp, file, http, https, ws or wss, which changes
+// how the URL is parsed
+func (u *URL) IsSpecial() bool {
+	_, ok := specialSchemes[u.scheme]
+	return ok
+}
+
+// Username returns the percent-encoded username of the URL
+func (u *URL) Username() string {
+	return u.username
+}
+
+// Password returns the percent-encoded password of the URL
+func (u *URL) Password() string {
+	return u.password
+}
+
+// Host returns the domain name or IP address of the URL with the port, if any, and whether the URL has one
+//
+// The host of a URL with a special scheme is a domain name or IP address, which is not returned for an empty host
+// (file URLs) or a domain name rejected by domain.Parse. The host of other URLs is opaque, except IPv6 addresses.
+func (u *URL) Host() (domain.Host, bool) {
+	var h domain.Host
+	switch {
+	case u.addr.IsValid():
+		h = domain.HostFromAddr(u.addr)
+	case u.IsSpecial() && len(u.host) > 0:
+		name, err := domain.Parse(u.host)
+		if err != nil {
+			return domain.Host{}, false
+		}
+		h = domain.HostFromName(name)
+	default:
+		return domain.Host{}, false
+	}
+	if u.hasPort {
+		h = h.WithPort(u.port)
+	}
+	return h, true
+}
+
+// Hostname returns the serialized host of the URL, without port, which is a domain name in ASCII (punycode), an IPv4
+// address, an IPv6 address in brackets, an opaque host, or empty if the URL has no host
+func (u *URL) Hostname() string {
+	return u.host
+}
+
+// HasHost returns whether the URL has a host, which may be empty, e.g. for file:///etc/passwd
+func (u *URL) HasHost() bool {
+	return u.hasHost
+}
+
+// Port returns the port of the URL, and whether the URL has a port, which is not the case for the default port of
+// the scheme
+func (u *URL) Port() (uint16, bool) {
+	return u.port, u.hasPort
+}
+
+// Path returns the serialized, percent-encoded path of the URL, such as "/a/b"
+func (u *URL) Path() string {
+	if u.hasOpaquePath {
+		return u.opaquePath
+	}
+	var sb strings.Builder
+	for _, segment := range u.path {
+		sb.WriteByte('/')
+		sb.WriteString(segment)
+	}
+	return sb.String()
+}
+
+// PathSegments returns the percent-encoded segments of the path of the URL, or nil if the path is opaque
+func (u *URL) PathSegments() []string {
+	if u.hasOpaquePath {
+		return nil
+	}
+	return append([]string{}, u.path...)
+}
+
+// HasOpaquePath returns whether the path of the URL is opaque, i.e. not a list of segments, such as "text/plain,x"
+// of data:text/plain,x
+func (u *URL) HasOpaquePath() bool {
+	return u.hasOpaquePath
+}
+
+// Query returns the percent-encoded query of the URL, without '?', and whether the URL has a query
+func (u *URL) Query() (string, bool) {
+	return u.query, u.hasQuery
+}
+
+// Fragment returns the percent-encoded fragment of the URL, without '#', and whether the URL has a fragment
+func (u *URL) Fragment() (string, bool) {
+	return u.fragment, u.hasFragment
+}
+
+// String returns the serialized URL, i.e. the href of the URL
+func (u *URL) String() string {
+	var sb strings.Builder
+	sb.WriteString(u.scheme)
+	sb.WriteByte(':')
+	if u.hasHost {
+		sb.WriteString("//")
+		if len(u.username) > 0 || len(u.password) > 0 {
+			sb.WriteString(u.username)
+			if len(u.password) > 0 {
+				sb.WriteByte(':')
+				sb.WriteString(u.password)
+			}
+			sb.WriteByte('@')
+		}
+		sb.WriteString(u.host)
+		if u.hasPort {
+			sb.WriteByte(':')
+			sb.WriteString(strconv.Itoa(int(u.port)))
+		}
+	} else if !u.hasOpaquePath && len(u.path) > 1 && len(u.path[0]) == 0 {
+		// prevents the path from being parsed as a host, e.g. web+demo:/.//not-a-host/
+		sb.WriteString("/.")
+	}
+	sb.WriteString(u.Path())
+	if u.hasQuery {
+		sb.WriteByte('?')
+		sb.WriteString(u.query)
+	}
+	if u.hasFragment {
+		sb.WriteByte('#')
+		sb.WriteString(u.fragment)
+	}
+	return sb.String()
+}
@@ -1,6 +1,10 @@
 package validate
 
-import "github.com/detectify/n5/ip"
+import (
+	"net/netip"
+
+	"github.com/detectify/n5/ip"
+)
 
 // IP determines whether a string is a valid IP address
 func IP(s string) error {
@@ -11,3 +15,13 @@ func IP(s string) error {
 func NonReservedIP(s string) error {
 	return ip.ValidateNonReserved(s)
 }
+
+// Addr determines whether an IP address is valid
+func Addr(addr netip.Addr) error {
+	return ip.ValidateAddr(addr)
+}
+
+// NonReservedAddr determines whether an IP address is valid and non-reserved
+func NonReservedAddr(addr netip.Addr) error {
+	return ip.ValidateNonReservedAddr(addr)
+}